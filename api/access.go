@@ -0,0 +1,112 @@
+package api
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Access policies that can be declared for a route
+const (
+	AccessPublic        = "public"
+	AccessAuthenticated = "authenticated"
+	accessRolePrefix    = "role:"
+)
+
+// routePolicies records the policy declared for every registered route, keyed by "METHOD /full/path".
+// Routes registered through Any are stored under the method "*".
+var routePolicies = make(map[string]string)
+
+// AccessGroup is a router group whose routes all share one access policy.
+// Sub-groups inherit the policy, so every route registered through it has one.
+type AccessGroup struct {
+	*gin.RouterGroup
+	policy string
+}
+
+// PublicGroup creates a group whose routes need no authentication
+func PublicGroup(parent *gin.RouterGroup, relativePath string) *AccessGroup {
+	return &AccessGroup{RouterGroup: parent.Group(relativePath), policy: AccessPublic}
+}
+
+// AuthenticatedGroup creates a group whose routes require a valid login token
+func AuthenticatedGroup(parent *gin.RouterGroup, relativePath string, auth gin.HandlerFunc) *AccessGroup {
+	return &AccessGroup{RouterGroup: parent.Group(relativePath, auth), policy: AccessAuthenticated}
+}
+
+// RoleGroup creates a group whose routes require a login token with one of the roles
+func RoleGroup(parent *gin.RouterGroup, relativePath string, auth gin.HandlerFunc, roles ...string) *AccessGroup {
+	return &AccessGroup{
+		RouterGroup: parent.Group(relativePath, auth, RequireRole(roles...)),
+		policy:      accessRolePrefix + strings.Join(roles, ","),
+	}
+}
+
+// RoutePolicy returns the access policy declared for a route
+func RoutePolicy(method, fullPath string) (string, bool) {
+	if policy, ok := routePolicies[method+" "+fullPath]; ok {
+		return policy, true
+	}
+	policy, ok := routePolicies["* "+fullPath]
+	return policy, ok
+}
+
+// record stores the group's policy for a route
+func (g *AccessGroup) record(method, relativePath string) {
+	fullPath := g.BasePath()
+	if relativePath != "" {
+		fullPath = path.Join(fullPath, relativePath)
+	}
+	routePolicies[method+" "+fullPath] = g.policy
+}
+
+// Group creates a sub-group with the same access policy
+func (g *AccessGroup) Group(relativePath string, handlers ...gin.HandlerFunc) *AccessGroup {
+	return &AccessGroup{RouterGroup: g.RouterGroup.Group(relativePath, handlers...), policy: g.policy}
+}
+
+// GET registers a GET route
+func (g *AccessGroup) GET(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	g.record(http.MethodGet, relativePath)
+	return g.RouterGroup.GET(relativePath, handlers...)
+}
+
+// POST registers a POST route
+func (g *AccessGroup) POST(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	g.record(http.MethodPost, relativePath)
+	return g.RouterGroup.POST(relativePath, handlers...)
+}
+
+// PUT registers a PUT route
+func (g *AccessGroup) PUT(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	g.record(http.MethodPut, relativePath)
+	return g.RouterGroup.PUT(relativePath, handlers...)
+}
+
+// PATCH registers a PATCH route
+func (g *AccessGroup) PATCH(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	g.record(http.MethodPatch, relativePath)
+	return g.RouterGroup.PATCH(relativePath, handlers...)
+}
+
+// DELETE registers a DELETE route
+func (g *AccessGroup) DELETE(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	g.record(http.MethodDelete, relativePath)
+	return g.RouterGroup.DELETE(relativePath, handlers...)
+}
+
+// Any registers a route for all HTTP methods
+func (g *AccessGroup) Any(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	g.record("*", relativePath)
+	return g.RouterGroup.Any(relativePath, handlers...)
+}
+
+// Static serves files from a directory (GET and HEAD)
+func (g *AccessGroup) Static(relativePath, root string) gin.IRoutes {
+	filePath := path.Join(relativePath, "/*filepath")
+	g.record(http.MethodGet, filePath)
+	g.record(http.MethodHead, filePath)
+	return g.RouterGroup.Static(relativePath, root)
+}
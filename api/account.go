@@ -0,0 +1,368 @@
+package api
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"hope_backend/dao"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	accountDeletionGracePeriod = 7 * 24 * time.Hour
+	// AccountDeletionInterval is how often the scheduler looks for accounts due for deletion
+	AccountDeletionInterval = 10 * time.Minute
+)
+
+// DeleteAccountRequest represents the request body for deleting an account
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// ExportUserDataHandler streams a ZIP archive with all data stored for the current user
+func ExportUserDataHandler(accountDAO *dao.AccountDAO, auditDAO *dao.AuditDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get authenticated user ID
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, Response{
+				Success: false,
+				Message: "Authentication required",
+			})
+			return
+		}
+
+		export, err := accountDAO.Export(userID.(int64))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to export data: " + err.Error(),
+			})
+			return
+		}
+		recordAudit(c, auditDAO, AuditDataExport, userID.(int64), nil)
+
+		filename := fmt.Sprintf("hope-export-%d-%s.zip", userID.(int64), time.Now().Format("20060102"))
+		c.Header("Content-Type", "application/zip")
+		c.Header("Content-Disposition", "attachment; filename="+filename)
+		c.Status(http.StatusOK)
+
+		if err := writeExportArchive(c.Writer, export); err != nil {
+			// Headers are already sent, so the client only sees a truncated archive
+			fmt.Printf("Warning: Failed to write export for user %d: %v\n", userID.(int64), err)
+		}
+	}
+}
+
+// writeExportArchive writes the export as JSON files, a Markdown journal and the uploaded images
+func writeExportArchive(w io.Writer, export *dao.UserDataExport) error {
+	zw := zip.NewWriter(w)
+
+	jsonFiles := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"notes.json", export.Notes},
+		{"note_revisions.json", export.NoteRevisions},
+		{"note_reflections.json", export.Reflections},
+		{"note_templates.json", export.NoteTemplates},
+		{"messages.json", export.Messages},
+		{"posts.json", export.Posts},
+		{"comments.json", export.Comments},
+	}
+	for _, file := range jsonFiles {
+		f, err := zw.Create(file.name)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(file.data); err != nil {
+			return err
+		}
+	}
+
+	// Human-readable journal
+	f, err := zw.Create("notes.md")
+	if err != nil {
+		return err
+	}
+	for _, note := range export.Notes {
+		if note.DeletedAt > 0 {
+			continue
+		}
+		if _, err := fmt.Fprintf(f, "## %s\n\n%s%s\n\n", note.NoteDate, noteCheckInMarkdown(note), note.Content); err != nil {
+			return err
+		}
+	}
+
+	// Uploaded images
+	images := []string{export.Profile.UserAvatar, export.Profile.ChatBackground}
+	for _, post := range export.Posts {
+		for _, image := range post.Images {
+			images = append(images, image.ImagePath)
+		}
+	}
+	for _, image := range images {
+		localPath := uploadFilePath(image)
+		if localPath == "" {
+			continue
+		}
+		if err := addFileToArchive(zw, localPath, "images/"+filepath.Base(localPath)); err != nil {
+			fmt.Printf("Info: Skipping %s in export: %v\n", localPath, err)
+		}
+	}
+
+	// Note photos, named after the attachment_id in notes.json
+	for _, note := range export.Notes {
+		for _, attachment := range note.Attachments {
+			localPath := uploadFilePath(attachment.FilePath)
+			name := fmt.Sprintf("note_photos/%d%s", attachment.AttachmentID, filepath.Ext(localPath))
+			if err := addFileToArchive(zw, localPath, name); err != nil {
+				fmt.Printf("Info: Skipping %s in export: %v\n", localPath, err)
+			}
+		}
+	}
+
+	return zw.Close()
+}
+
+// addFileToArchive copies a file from disk into the archive
+func addFileToArchive(zw *zip.Writer, srcPath, name string) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+// uploadFilePath converts a stored image path or public URL to its location on disk.
+// It returns "" for empty values and files hosted elsewhere.
+func uploadFilePath(stored string) string {
+	if stored == "" {
+		return ""
+	}
+
+	if strings.Contains(stored, "://") {
+		if !strings.HasPrefix(stored, PublicFileBaseURL+"/") {
+			return ""
+		}
+		stored = strings.TrimPrefix(stored, PublicFileBaseURL+"/")
+	}
+
+	return filepath.Join(UploadsBasePath, filepath.Clean("/"+stored))
+}
+
+// removeUploadedFile deletes an uploaded file and its thumbnail
+func removeUploadedFile(stored string) {
+	filePath := uploadFilePath(stored)
+	if filePath == "" {
+		return
+	}
+
+	if err := os.Remove(filePath); err != nil {
+		fmt.Printf("Info: Could not remove file %s: %v\n", filePath, err)
+	}
+
+	thumbnailPath := filepath.Join(filepath.Dir(filePath), ThumbnailPrefix+filepath.Base(filePath))
+	if err := os.Remove(thumbnailPath); err != nil {
+		fmt.Printf("Info: Could not remove thumbnail %s: %v\n", thumbnailPath, err)
+	}
+}
+
+// DeleteAccountHandler re-checks the password and schedules the account for deletion
+func DeleteAccountHandler(profileDAO *dao.UserProfileDAO, accountDAO *dao.AccountDAO, sessionDAO *dao.SessionDAO, auditDAO *dao.AuditDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get authenticated user ID
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, Response{
+				Success: false,
+				Message: "Authentication required",
+			})
+			return
+		}
+
+		var req DeleteAccountRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+
+		profile, err := profileDAO.GetByID(userID.(int64))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to retrieve user profile",
+			})
+			return
+		}
+
+		isValid, _, err := profileDAO.VerifyPassword(profile.MobileNumber, req.Password)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Error verifying credentials: " + err.Error(),
+			})
+			return
+		}
+		if !isValid {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Password is incorrect",
+			})
+			return
+		}
+
+		job, err := accountDAO.ScheduleDeletion(userID.(int64), accountDeletionGracePeriod)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to schedule account deletion: " + err.Error(),
+			})
+			return
+		}
+		recordAudit(c, auditDAO, AuditAccountDeletionScheduled, userID.(int64), gin.H{"job_id": job.ID})
+
+		// Sign out every other device; logging in again during the grace period allows cancelling
+		if _, err := sessionDAO.RevokeAllExcept(userID.(int64), currentSessionID(c)); err != nil {
+			fmt.Printf("Warning: Failed to revoke sessions for user %d: %v\n", userID.(int64), err)
+		}
+
+		c.JSON(http.StatusAccepted, Response{
+			Success: true,
+			Message: "Account scheduled for deletion",
+			Data:    job,
+		})
+	}
+}
+
+// GetAccountDeletionHandler returns the pending deletion job of the current user
+func GetAccountDeletionHandler(accountDAO *dao.AccountDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get authenticated user ID
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, Response{
+				Success: false,
+				Message: "Authentication required",
+			})
+			return
+		}
+
+		job, err := accountDAO.GetPendingDeletion(userID.(int64))
+		if err != nil {
+			status := http.StatusInternalServerError
+			if err.Error() == "deletion job not found" {
+				status = http.StatusNotFound
+			}
+			c.JSON(status, Response{
+				Success: false,
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, Response{
+			Success: true,
+			Data:    job,
+		})
+	}
+}
+
+// CancelAccountDeletionHandler cancels a pending deletion during the grace period
+func CancelAccountDeletionHandler(accountDAO *dao.AccountDAO, auditDAO *dao.AuditDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get authenticated user ID
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, Response{
+				Success: false,
+				Message: "Authentication required",
+			})
+			return
+		}
+
+		if err := accountDAO.CancelDeletion(userID.(int64)); err != nil {
+			status := http.StatusInternalServerError
+			if err.Error() == "deletion job not found" {
+				status = http.StatusNotFound
+			}
+			c.JSON(status, Response{
+				Success: false,
+				Message: err.Error(),
+			})
+			return
+		}
+
+		recordAudit(c, auditDAO, AuditAccountDeletionCancelled, userID.(int64), nil)
+
+		c.JSON(http.StatusOK, Response{
+			Success: true,
+			Message: "Account deletion cancelled",
+		})
+	}
+}
+
+// AccountDeletionJob returns the scheduled job that deletes accounts whose grace period has ended.
+// Audit entries are kept after deletion until the audit retention period expires.
+func AccountDeletionJob(accountDAO *dao.AccountDAO, auditDAO *dao.AuditDAO) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return runAccountDeletions(ctx, accountDAO, auditDAO)
+	}
+}
+
+// runAccountDeletions processes every due deletion job once
+func runAccountDeletions(ctx context.Context, accountDAO *dao.AccountDAO, auditDAO *dao.AuditDAO) error {
+	jobs, err := accountDAO.ListDueDeletions(time.Now().UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to list jobs: %w", err)
+	}
+
+	for _, job := range jobs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		files, err := accountDAO.DeleteUserData(job.UserID)
+		if err == nil {
+			for _, file := range files {
+				removeUploadedFile(file)
+			}
+		}
+
+		if err := accountDAO.FinishDeletion(job.ID, err); err != nil {
+			fmt.Printf("[Account Deletion] Failed to update job %d: %v\n", job.ID, err)
+		}
+		fmt.Printf("[Account Deletion] Job %d for user %d finished, err: %v\n", job.ID, job.UserID, err)
+
+		if err == nil {
+			writeAudit(auditDAO, &dao.AuditLog{
+				Action:       AuditAccountDeleted,
+				TargetUserID: job.UserID,
+			}, gin.H{"job_id": job.ID, "files": len(files)})
+		}
+	}
+	return nil
+}
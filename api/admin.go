@@ -0,0 +1,99 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"hope_backend/dao"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateRoleRequest represents the request body for changing a user's role
+type UpdateRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// UpdateUserRoleHandler lets admins grant or remove the moderator and admin roles.
+// The role is carried in the login token, so the user's sessions are signed
+// out and the new role takes effect when they log in again.
+func UpdateUserRoleHandler(profileDAO *dao.UserProfileDAO, sessionDAO *dao.SessionDAO, auditDAO *dao.AuditDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get user ID from URL parameter
+		targetID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid user ID format",
+			})
+			return
+		}
+
+		var req UpdateRoleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+
+		if !isValidRole(req.Role) {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid role. Supported roles: user, moderator, admin",
+			})
+			return
+		}
+
+		if !canAccess(c, ActionManageUsers, targetID) {
+			c.JSON(http.StatusForbidden, Response{
+				Success: false,
+				Message: "You do not have permission to change roles",
+			})
+			return
+		}
+
+		// Remember the previous role for the audit log
+		var oldRole string
+		if profile, err := profileDAO.GetByID(targetID); err == nil {
+			oldRole = profile.Role
+		}
+
+		if err := profileDAO.UpdateRole(targetID, req.Role); err != nil {
+			status := http.StatusInternalServerError
+			if err.Error() == "user profile not found" {
+				status = http.StatusNotFound
+			}
+			c.JSON(status, Response{
+				Success: false,
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Tokens issued with the old role must not keep its access
+		var revoked int64
+		if oldRole != req.Role {
+			revoked, err = sessionDAO.RevokeAllExcept(targetID, 0)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, Response{
+					Success: false,
+					Message: "Role updated but failed to sign out sessions: " + err.Error(),
+				})
+				return
+			}
+		}
+
+		recordAudit(c, auditDAO, AuditAdminRoleChange, targetID, gin.H{
+			"old_role":         oldRole,
+			"new_role":         req.Role,
+			"revoked_sessions": revoked,
+		})
+
+		c.JSON(http.StatusOK, Response{
+			Success: true,
+			Message: "Role updated successfully",
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"hope_backend/dao"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Audit log actions
+const (
+	AuditLogin                    = "auth.login"
+	AuditLoginFailed              = "auth.login_failed"
+	AuditTwoFactorFailed          = "auth.2fa_failed"
+	AuditRegister                 = "auth.register"
+	AuditPasswordChange           = "user.password_change"
+	AuditPasswordChangeFailed     = "user.password_change_failed"
+	AuditMobileChange             = "user.mobile_change"
+	AuditTwoFactorEnable          = "user.2fa_enable"
+	AuditTwoFactorDisable         = "user.2fa_disable"
+	AuditRecoveryCodesRegenerate  = "user.recovery_codes_regenerate"
+	AuditSessionRevoke            = "session.revoke"
+	AuditSessionRevokeOthers      = "session.revoke_others"
+	AuditDataExport               = "account.export"
+	AuditAccountDeletionScheduled = "account.deletion_scheduled"
+	AuditAccountDeletionCancelled = "account.deletion_cancelled"
+	AuditAccountDeleted           = "account.deleted"
+	AuditAdminRoleChange          = "admin.role_change"
+	AuditModeratorDeletePost      = "admin.post_delete"
+	AuditModeratorDeleteComment   = "admin.comment_delete"
+	AuditModeratorBanUser         = "admin.community_ban"
+	AuditAdminAuditQuery          = "admin.audit_query"
+	AuditNoteShareCreate          = "note.share_create"
+	AuditNoteShareRevoke          = "note.share_revoke"
+	AuditSharedNoteAccess         = "note.shared_access" // Someone other than the owner read shared notes
+)
+
+const (
+	// defaultAuditRetentionDays applies when AUDIT_LOG_RETENTION_DAYS is not set
+	defaultAuditRetentionDays = 365
+	// AuditRetentionInterval is how often expired audit entries are removed
+	AuditRetentionInterval = 24 * time.Hour
+)
+
+// recordAudit appends an audit entry for the current request.
+// The actor is the authenticated user if there is one. Failures are logged
+// and never fail the request. Detail must not contain secrets.
+func recordAudit(c *gin.Context, auditDAO *dao.AuditDAO, action string, targetUserID int64, detail gin.H) {
+	var actorID int64
+	if userID, exists := c.Get("userID"); exists {
+		actorID = userID.(int64)
+	}
+
+	entry := &dao.AuditLog{
+		ActorID:      actorID,
+		Action:       action,
+		TargetUserID: targetUserID,
+		IP:           c.ClientIP(),
+		UserAgent:    c.Request.UserAgent(),
+		RequestID:    c.GetString("requestID"),
+	}
+	writeAudit(auditDAO, entry, detail)
+}
+
+// writeAudit serialises the detail and stores the entry
+func writeAudit(auditDAO *dao.AuditDAO, entry *dao.AuditLog, detail gin.H) {
+	if len(detail) > 0 {
+		if data, err := json.Marshal(detail); err == nil {
+			entry.Detail = string(data)
+		}
+	}
+
+	if err := auditDAO.Create(entry); err != nil {
+		fmt.Printf("Warning: Failed to write audit entry %s for user %d: %v\n", entry.Action, entry.TargetUserID, err)
+	}
+}
+
+// maskMobile hides the middle digits of a mobile number, e.g. 138****5678
+func maskMobile(mobile string) string {
+	if len(mobile) < 7 {
+		return "****"
+	}
+	return mobile[:3] + "****" + mobile[len(mobile)-4:]
+}
+
+// ListAuditLogsHandler lets admins search the audit log.
+// Supported filters: actor_id, target_user_id, action, ip, request_id, and from/to as milliseconds since epoch.
+func ListAuditLogsHandler(auditDAO *dao.AuditDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Parse pagination parameters
+		page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+		if err != nil || page < 1 {
+			page = 1
+		}
+
+		pageSize, err := strconv.Atoi(c.DefaultQuery("size", strconv.Itoa(defaultPageSize)))
+		if err != nil || pageSize < 1 {
+			pageSize = defaultPageSize
+		}
+		if pageSize > maxPageSize {
+			pageSize = maxPageSize
+		}
+
+		filter := dao.AuditLogFilter{
+			Action:    c.Query("action"),
+			IP:        c.Query("ip"),
+			RequestID: c.Query("request_id"),
+		}
+		filter.ActorID, _ = strconv.ParseInt(c.Query("actor_id"), 10, 64)
+		filter.TargetUserID, _ = strconv.ParseInt(c.Query("target_user_id"), 10, 64)
+		filter.From, _ = strconv.ParseInt(c.Query("from"), 10, 64)
+		filter.To, _ = strconv.ParseInt(c.Query("to"), 10, 64)
+
+		entries, total, err := auditDAO.List(filter, page, pageSize)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to retrieve audit log: " + err.Error(),
+			})
+			return
+		}
+
+		// Reading the audit log is itself a data access worth recording
+		recordAudit(c, auditDAO, AuditAdminAuditQuery, filter.TargetUserID, gin.H{
+			"query": c.Request.URL.RawQuery,
+		})
+
+		c.JSON(http.StatusOK, Response{
+			Success: true,
+			Data:    entries,
+			Total:   total,
+			Page:    page,
+			Size:    pageSize,
+		})
+	}
+}
+
+// auditRetention returns how long audit entries are kept, configured with AUDIT_LOG_RETENTION_DAYS
+func auditRetention() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("AUDIT_LOG_RETENTION_DAYS"))
+	if err != nil || days < 1 {
+		days = defaultAuditRetentionDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// AuditRetentionJob returns the scheduled job that removes audit entries older than the retention period
+func AuditRetentionJob(auditDAO *dao.AuditDAO) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		cutoff := time.Now().Add(-auditRetention()).UnixMilli()
+		removed, err := auditDAO.DeleteOlderThan(cutoff)
+		if err != nil {
+			return fmt.Errorf("failed to remove expired entries: %w", err)
+		}
+		fmt.Printf("[Audit Retention] Removed %d expired entries\n", removed)
+		return nil
+	}
+}
@@ -22,14 +22,20 @@ type RegisterUserRequest struct {
 	VerificationCode      string `json:"verification_code" binding:"required"`
 	PatientName           string `json:"patient_name" binding:"required"`
 	RelationshipToPatient string `json:"relationship_to_patient" binding:"required"`
-	IllnessCause          string `json:"illness_cause"`
+	IllnessCause          string `json:"illness_cause" binding:"max=200"`
 	UserNickname          string `json:"user_nickname" binding:"required"`
+	DeviceName            string `json:"device_name"`
+	Platform              string `json:"platform"`
+	LinkToken             string `json:"link_token"` // From a third-party login that had no linked account
+	Timezone              string `json:"timezone"`   // IANA name, defaults to Asia/Shanghai
 }
 
 // LoginRequest represents the request body for user login
 type LoginRequest struct {
 	MobileNumber string `json:"mobile_number" binding:"required"`
 	Password     string `json:"password" binding:"required"`
+	DeviceName   string `json:"device_name"`
+	Platform     string `json:"platform"`
 }
 
 // VerificationCodeRequest represents the request for sending verification codes
@@ -45,33 +51,17 @@ type VerifyMobileRequest struct {
 
 // Claims structure for JWT payload
 type Claims struct {
-	UserID int64 `json:"user_id"`
+	UserID int64  `json:"user_id"`
+	Role   string `json:"role,omitempty"`
+	MFA    bool   `json:"mfa,omitempty"` // Issued after a completed two-factor step
 	jwt.RegisteredClaims
 }
 
 // AuthMiddleware checks for a valid JWT token in Authorization header
-func AuthMiddleware() gin.HandlerFunc {
+// and that the session it was issued for has not been signed out.
+// It is attached to route groups through AuthenticatedGroup and RoleGroup.
+func AuthMiddleware(sessionDAO *dao.SessionDAO, twoFactorDAO *dao.TwoFactorDAO) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Public paths that don't need authentication
-		publicPaths := []string{
-			"/hope/ping",
-			"/hope/auth/register",
-			"/hope/auth/login",
-			"/hope/auth/verification-code",
-			"/hope/auth/verify-mobile",
-			// "/hope/user",
-			"/hope/file/posts",
-		}
-
-		// Skip authentication for public paths
-		requestPath := c.Request.URL.Path
-		for _, path := range publicPaths {
-			if strings.HasPrefix(requestPath, path) {
-				c.Next()
-				return
-			}
-		}
-
 		// Get the Authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -115,19 +105,58 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Set user ID in context
+		// Tokens issued before session tracking carry no ID and are
+		// accepted until they expire
+		if claims.ID != "" {
+			session, err := sessionDAO.GetByTokenID(claims.ID)
+			if err != nil || session.UserID != claims.UserID {
+				c.JSON(http.StatusUnauthorized, Response{
+					Success: false,
+					Message: "Session has been signed out",
+				})
+				c.Abort()
+				return
+			}
+
+			if err := sessionDAO.Touch(session, c.ClientIP()); err != nil {
+				fmt.Printf("Warning: Failed to refresh session %d: %v\n", session.ID, err)
+			}
+			c.Set("sessionID", session.ID)
+			c.Set("tokenID", claims.ID)
+		}
+
+		// Once 2FA is enabled, tokens issued without the second step are no longer accepted
+		if !claims.MFA {
+			enabled, err := twoFactorDAO.IsEnabled(claims.UserID)
+			if err != nil || enabled {
+				c.JSON(http.StatusUnauthorized, Response{
+					Success: false,
+					Message: "Two-factor authentication required",
+				})
+				c.Abort()
+				return
+			}
+		}
+
+		// Set user ID and role in context
 		c.Set("userID", claims.UserID)
+		c.Set("role", claims.Role)
 		c.Next()
 	}
 }
 
-// GenerateToken creates a new JWT token for a user
-func GenerateToken(userID int64) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour) // Token valid for 24 hours
+// tokenLifetime is how long a login token, and the session it belongs to, stays valid
+const tokenLifetime = 24 * time.Hour
 
+// GenerateToken creates a new JWT token for a user, tagged with the user's role, session token ID
+// and whether the login completed a two-factor step
+func GenerateToken(userID int64, role string, tokenID string, mfa bool, expirationTime time.Time) (string, error) {
 	claims := &Claims{
 		UserID: userID,
+		Role:   role,
+		MFA:    mfa,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
@@ -140,7 +169,7 @@ func GenerateToken(userID int64) (string, error) {
 }
 
 // RegisterUserHandler handles user registration
-func RegisterUserHandler(profileDAO *dao.UserProfileDAO) gin.HandlerFunc {
+func RegisterUserHandler(profileDAO *dao.UserProfileDAO, sessionDAO *dao.SessionDAO, identityDAO *dao.IdentityDAO, auditDAO *dao.AuditDAO) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req RegisterUserRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -181,6 +210,31 @@ func RegisterUserHandler(profileDAO *dao.UserProfileDAO) gin.HandlerFunc {
 			return
 		}
 
+		timezone := req.Timezone
+		if timezone == "" {
+			timezone = defaultTimezone
+		}
+		if _, err := loadTimezone(timezone); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid timezone: " + timezone,
+			})
+			return
+		}
+
+		// Validate the link token before creating anything
+		var link *linkClaims
+		if req.LinkToken != "" {
+			link, err = parseLinkToken(req.LinkToken)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, Response{
+					Success: false,
+					Message: err.Error(),
+				})
+				return
+			}
+		}
+
 		// Create new user profile
 		profile := &dao.UserProfile{
 			PatientName:           req.PatientName,
@@ -188,6 +242,8 @@ func RegisterUserHandler(profileDAO *dao.UserProfileDAO) gin.HandlerFunc {
 			IllnessCause:          req.IllnessCause,
 			UserNickname:          req.UserNickname,
 			MobileNumber:          req.MobileNumber,
+			Role:                  RoleUser,
+			Timezone:              timezone,
 			// Default values for other fields
 			ChatBackground: "",
 			UserAvatar:     "",
@@ -202,9 +258,27 @@ func RegisterUserHandler(profileDAO *dao.UserProfileDAO) gin.HandlerFunc {
 			})
 			return
 		}
+		recordAudit(c, auditDAO, AuditRegister, userID, gin.H{"mobile": maskMobile(req.MobileNumber)})
+
+		// Notes shared with the number before it was registered
+		if err := dao.ClaimPendingNoteShares(userID, req.MobileNumber); err != nil {
+			fmt.Printf("Warning: Failed to claim note shares for user %d: %v\n", userID, err)
+		}
+
+		// Link the third-party identity the user signed in with
+		if link != nil {
+			if err := identityDAO.Link(&dao.UserIdentity{
+				UserID:   userID,
+				Provider: link.Provider,
+				Subject:  link.Subject,
+				Nickname: link.Nickname,
+			}); err != nil {
+				fmt.Printf("Warning: Failed to link %s identity for user %d: %v\n", link.Provider, userID, err)
+			}
+		}
 
-		// Generate JWT token
-		token, err := GenerateToken(userID)
+		// Generate JWT token and record the session it belongs to
+		token, err := issueSessionToken(c, sessionDAO, userID, profile.Role, false, req.DeviceName, req.Platform)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, Response{
 				Success: false,
@@ -226,7 +300,7 @@ func RegisterUserHandler(profileDAO *dao.UserProfileDAO) gin.HandlerFunc {
 }
 
 // LoginHandler handles user login
-func LoginHandler(profileDAO *dao.UserProfileDAO) gin.HandlerFunc {
+func LoginHandler(profileDAO *dao.UserProfileDAO, sessionDAO *dao.SessionDAO, twoFactorDAO *dao.TwoFactorDAO, auditDAO *dao.AuditDAO) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req LoginRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -241,6 +315,12 @@ func LoginHandler(profileDAO *dao.UserProfileDAO) gin.HandlerFunc {
 		isValid, userID, err := profileDAO.VerifyPassword(req.MobileNumber, req.Password)
 		fmt.Printf("login: %+v\n", req)
 		if err != nil {
+			if err.Error() == "user profile not found" {
+				recordAudit(c, auditDAO, AuditLoginFailed, 0, gin.H{
+					"mobile": maskMobile(req.MobileNumber),
+					"reason": "unknown account",
+				})
+			}
 			c.JSON(http.StatusInternalServerError, Response{
 				Success: false,
 				Message: "Error verifying credentials: " + err.Error(),
@@ -249,6 +329,10 @@ func LoginHandler(profileDAO *dao.UserProfileDAO) gin.HandlerFunc {
 		}
 
 		if !isValid {
+			recordAudit(c, auditDAO, AuditLoginFailed, 0, gin.H{
+				"mobile": maskMobile(req.MobileNumber),
+				"reason": "wrong password",
+			})
 			c.JSON(http.StatusUnauthorized, Response{
 				Success: false,
 				Message: "Invalid credentials",
@@ -266,25 +350,8 @@ func LoginHandler(profileDAO *dao.UserProfileDAO) gin.HandlerFunc {
 			return
 		}
 
-		// Generate JWT token
-		token, err := GenerateToken(userID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, Response{
-				Success: false,
-				Message: "Failed to generate token",
-			})
-			return
-		}
-
-		// Return success with token and user profile
-		c.JSON(http.StatusOK, Response{
-			Success: true,
-			Message: "Login successful",
-			Data: gin.H{
-				"token":   token,
-				"profile": profile,
-			},
-		})
+		// Issue a token, or a challenge if the user has 2FA enabled
+		completeLogin(c, twoFactorDAO, sessionDAO, auditDAO, profile, req.DeviceName, req.Platform, "password")
 	}
 }
 
@@ -10,7 +10,7 @@ import (
 )
 
 // CreateCommentHandler handles POST requests to create a new comment
-func CreateCommentHandler(commentDAO *dao.CommentDAO) gin.HandlerFunc {
+func CreateCommentHandler(commentDAO *dao.CommentDAO, postDAO *dao.PostDAO) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get authenticated user ID
 		userID, exists := c.Get("userID")
@@ -42,16 +42,32 @@ func CreateCommentHandler(commentDAO *dao.CommentDAO) gin.HandlerFunc {
 			return
 		}
 
+		// Hidden posts and comments cannot be commented on, except by those who can still see them
+		if findVisiblePost(c, postDAO, postID, userID.(int64)) == nil {
+			return
+		}
+		if req.ParentID != nil && findVisibleComment(c, commentDAO, postDAO, *req.ParentID, userID.(int64)) == nil {
+			return
+		}
+
 		// Create the comment
 		comment := &dao.Comment{
-			PostID:   postID,
-			UserID:   userID.(int64),
-			ParentID: req.ParentID,
-			Content:  req.Content,
+			PostID:    postID,
+			UserID:    userID.(int64),
+			ParentID:  req.ParentID,
+			Content:   req.Content,
+			Anonymous: req.Anonymous,
 		}
 
 		commentID, err := commentDAO.Create(comment)
 		if err != nil {
+			if err.Error() == "banned from the community" {
+				c.JSON(http.StatusForbidden, Response{
+					Success: false,
+					Message: "You are banned from commenting in the community",
+				})
+				return
+			}
 			c.JSON(http.StatusInternalServerError, Response{
 				Success: false,
 				Message: "Failed to create comment: " + err.Error(),
@@ -77,8 +93,9 @@ func CreateCommentHandler(commentDAO *dao.CommentDAO) gin.HandlerFunc {
 	}
 }
 
-// ListCommentsHandler handles GET requests to list comments for a post
-func ListCommentsHandler(commentDAO *dao.CommentDAO) gin.HandlerFunc {
+// ListCommentsHandler handles GET requests to list comments for a post.
+// Like ListPostsHandler it supports ?cursor= as well as ?page=.
+func ListCommentsHandler(commentDAO *dao.CommentDAO, postDAO *dao.PostDAO) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get authenticated user ID
 		userID, exists := c.Get("userID")
@@ -100,6 +117,10 @@ func ListCommentsHandler(commentDAO *dao.CommentDAO) gin.HandlerFunc {
 			return
 		}
 
+		if findVisiblePost(c, postDAO, postID, userID.(int64)) == nil {
+			return
+		}
+
 		// Parse pagination parameters
 		page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
 		if err != nil || page < 1 {
@@ -114,6 +135,28 @@ func ListCommentsHandler(commentDAO *dao.CommentDAO) gin.HandlerFunc {
 			pageSize = maxPageSize
 		}
 
+		// Cursor pagination, used when the cursor parameter is present
+		if cursor, useCursor, ok := pageCursorParam(c); !ok {
+			return
+		} else if useCursor {
+			comments, next, err := commentDAO.ListCommentsAfter(postID, cursor, pageSize, userID.(int64))
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, Response{
+					Success: false,
+					Message: "Failed to retrieve comments: " + err.Error(),
+				})
+				return
+			}
+
+			c.JSON(http.StatusOK, Response{
+				Success:    true,
+				Data:       comments,
+				Size:       pageSize,
+				NextCursor: cursorString(next),
+			})
+			return
+		}
+
 		// Get comments with pagination
 		comments, total, err := commentDAO.ListComments(postID, page, pageSize, userID.(int64))
 		if err != nil {
@@ -134,8 +177,38 @@ func ListCommentsHandler(commentDAO *dao.CommentDAO) gin.HandlerFunc {
 	}
 }
 
+// findVisibleComment loads a comment for the current user. It responds with
+// 404 and returns nil if the comment does not exist or it or its post is
+// hidden from the user, like findVisiblePost.
+func findVisibleComment(c *gin.Context, commentDAO *dao.CommentDAO, postDAO *dao.PostDAO, commentID, userID int64) *dao.Comment {
+	comment, err := commentDAO.GetByID(commentID, userID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err.Error() == "comment not found" {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, Response{
+			Success: false,
+			Message: err.Error(),
+		})
+		return nil
+	}
+
+	if comment.Hidden && !canAccess(c, ActionViewHidden, comment.UserID) {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Message: "comment not found",
+		})
+		return nil
+	}
+	if findVisiblePost(c, postDAO, comment.PostID, userID) == nil {
+		return nil
+	}
+	return comment
+}
+
 // DeleteCommentHandler handles DELETE requests to delete a comment
-func DeleteCommentHandler(commentDAO *dao.CommentDAO) gin.HandlerFunc {
+func DeleteCommentHandler(commentDAO *dao.CommentDAO, auditDAO *dao.AuditDAO) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get authenticated user ID
 		userID, exists := c.Get("userID")
@@ -157,7 +230,7 @@ func DeleteCommentHandler(commentDAO *dao.CommentDAO) gin.HandlerFunc {
 			return
 		}
 
-		// Get the comment to check ownership
+		// Get the comment to check permissions
 		comment, err := commentDAO.GetByID(commentID, userID.(int64))
 		if err != nil {
 			status := http.StatusInternalServerError
@@ -171,8 +244,8 @@ func DeleteCommentHandler(commentDAO *dao.CommentDAO) gin.HandlerFunc {
 			return
 		}
 
-		// Check if user is the owner of the comment
-		if comment.UserID != userID.(int64) {
+		// Owners and moderators may delete the comment
+		if !canAccess(c, ActionDeleteComment, comment.UserID) {
 			c.JSON(http.StatusForbidden, Response{
 				Success: false,
 				Message: "You do not have permission to delete this comment",
@@ -189,6 +262,11 @@ func DeleteCommentHandler(commentDAO *dao.CommentDAO) gin.HandlerFunc {
 			return
 		}
 
+		// Removing someone else's comment is a moderation action
+		if comment.UserID != userID.(int64) {
+			recordAudit(c, auditDAO, AuditModeratorDeleteComment, comment.UserID, gin.H{"comment_id": commentID})
+		}
+
 		c.JSON(http.StatusOK, Response{
 			Success: true,
 			Message: "Comment deleted successfully",
@@ -197,7 +275,7 @@ func DeleteCommentHandler(commentDAO *dao.CommentDAO) gin.HandlerFunc {
 }
 
 // LikeCommentHandler handles POST requests to like a comment
-func LikeCommentHandler(commentDAO *dao.CommentDAO) gin.HandlerFunc {
+func LikeCommentHandler(commentDAO *dao.CommentDAO, postDAO *dao.PostDAO) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get authenticated user ID
 		userID, exists := c.Get("userID")
@@ -219,6 +297,10 @@ func LikeCommentHandler(commentDAO *dao.CommentDAO) gin.HandlerFunc {
 			return
 		}
 
+		if findVisibleComment(c, commentDAO, postDAO, commentID, userID.(int64)) == nil {
+			return
+		}
+
 		// Add like
 		err = commentDAO.LikeComment(commentID, userID.(int64))
 		if err != nil {
@@ -246,7 +328,7 @@ func LikeCommentHandler(commentDAO *dao.CommentDAO) gin.HandlerFunc {
 }
 
 // UnlikeCommentHandler handles POST requests to unlike a comment
-func UnlikeCommentHandler(commentDAO *dao.CommentDAO) gin.HandlerFunc {
+func UnlikeCommentHandler(commentDAO *dao.CommentDAO, postDAO *dao.PostDAO) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get authenticated user ID
 		userID, exists := c.Get("userID")
@@ -268,6 +350,10 @@ func UnlikeCommentHandler(commentDAO *dao.CommentDAO) gin.HandlerFunc {
 			return
 		}
 
+		if findVisibleComment(c, commentDAO, postDAO, commentID, userID.(int64)) == nil {
+			return
+		}
+
 		// Remove like
 		err = commentDAO.UnlikeComment(commentID, userID.(int64))
 		if err != nil {
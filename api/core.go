@@ -15,4 +15,6 @@ type Response struct {
 	Total   int64       `json:"total,omitempty"`
 	Page    int         `json:"page,omitempty"`
 	Size    int         `json:"size,omitempty"`
+	// Cursor of the next page in cursor pagination, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
 }
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"strings"
+)
+
+// crisisPhrases are signs that someone may be thinking of suicide or self-harm.
+// Caregivers often write about the patient, so the phrases are matched no
+// matter who they are about. A bare "想死" is left out because it is also
+// how people say they miss someone ("想死你了").
+var crisisPhrases = []string{
+	"自杀", "轻生", "不想活", "活不下去", "活着没意思", "想去死", "一死了之", "死了算了", "寻死",
+	"结束生命", "结束自己", "自残", "自伤", "割腕", "跳楼", "遗书",
+	"suicide", "suicidal", "kill myself", "end my life", "self-harm", "self harm",
+}
+
+// crisisResponse is sent instead of an AI reply when screenCrisis matches
+const crisisResponse = "听起来您或您身边的人正处在非常艰难的时刻，谢谢您愿意说出来。" +
+	"如果有人有伤害自己的想法或已经处于危险之中，请立即拨打 120 或 110，或尽快前往最近的医院急诊。" +
+	"您也可以拨打 24 小时心理危机干预热线：希望24热线 400-161-9995，北京心理危机研究与干预中心 010-82951332。" +
+	"请不要一个人扛着，尽快联系一位可以信任的人陪在身边。"
+
+// screenCrisis reports whether a text needs the crisis response rather than
+// an AI reply. It is a plain phrase match: it errs on the side of showing
+// help too often, and the text never reaches a provider when it matches.
+func screenCrisis(text string) bool {
+	text = strings.ToLower(text)
+	for _, phrase := range crisisPhrases {
+		if strings.Contains(text, phrase) {
+			return true
+		}
+	}
+	return false
+}
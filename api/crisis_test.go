@@ -0,0 +1,34 @@
+package api
+
+import "testing"
+
+func TestScreenCrisis(t *testing.T) {
+	matches := []string{
+		"我最近总觉得活不下去了",
+		"他说他想去死",
+		"妈妈昨天说不如一死了之",
+		"她一直在说死了算了",
+		"I think he is suicidal",
+		"She said she wants to END MY LIFE",
+		"我发现孩子手上有自残的痕迹",
+	}
+	for _, text := range matches {
+		if !screenCrisis(text) {
+			t.Errorf("%q: not screened as a crisis", text)
+		}
+	}
+
+	misses := []string{
+		"想死你了，周末回家吃饭吧",
+		"好久不见，想死我了",
+		"今天天气很好，我们去公园散步了",
+		"他终于愿意吃药了",
+		"热死了，空调坏了",
+		"",
+	}
+	for _, text := range misses {
+		if screenCrisis(text) {
+			t.Errorf("%q: screened as a crisis", text)
+		}
+	}
+}
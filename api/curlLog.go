@@ -7,6 +7,7 @@ import (
 	"log"
 	"math/rand"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
@@ -22,25 +23,37 @@ func CurlLoggingMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Generate unique request ID
+		// Generate unique request ID; handlers read it for audit entries
 		requestID := generateRequestID()
-
-		// Read the request body
-		bodyBytes, err := io.ReadAll(c.Request.Body)
-		if err != nil {
-			log.Printf("[%s] Error reading request body: %v", requestID, err)
-			c.Next()
-			return
+		c.Set("requestID", requestID)
+		c.Header("X-Request-ID", requestID)
+
+		// Bodies of sensitive routes are neither read nor logged
+		logBody := !hasSensitiveBody(c.Request.URL.Path)
+
+		// Read the request body. File uploads are left to the handler, which
+		// limits their size while it streams them.
+		var bodyBytes []byte
+		if logBody && c.ContentType() != "multipart/form-data" {
+			var err error
+			bodyBytes, err = io.ReadAll(c.Request.Body)
+			if err != nil {
+				log.Printf("[%s] Error reading request body: %v", requestID, err)
+				c.Next()
+				return
+			}
+
+			// Restore the request body for the next handler
+			c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+		} else if c.Request.ContentLength != 0 {
+			bodyBytes = []byte(redactedBody)
 		}
 
-		// Restore the request body for the next handler
-		c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
-
 		// Generate the curl command
 		curlCommand := generateCurlCommand(c.Request, bodyBytes)
 
 		// Create a response writer to capture response data
-		writer := &responseWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		writer := &responseWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}, capture: logBody}
 		c.Writer = writer
 
 		// Record start time
@@ -52,16 +65,66 @@ func CurlLoggingMiddleware() gin.HandlerFunc {
 		// Calculate duration
 		duration := time.Since(startTime)
 
+		response := writer.body.String()
+		switch {
+		case !logBody:
+			response = redactedBody
+		case writer.Size() > 0 && writer.body.Len() == 0:
+			response = fmt.Sprintf("[%s, %d bytes]", writer.Header().Get("Content-Type"), writer.Size())
+		}
+
 		// Log everything in one line
 		log.Printf("[%s] %s | Status: %d | Duration: %v | Response: %s",
 			requestID,
 			curlCommand,
 			writer.status,
 			duration,
-			writer.body.String())
+			response)
 	}
 }
 
+// redactedBody replaces request and response bodies that are not logged
+const redactedBody = "[redacted]"
+
+// sensitiveBodyPaths are route prefixes whose request or response bodies carry
+// credentials (passwords, TOTP secrets and codes, recovery codes, MFA challenge
+// tokens, third-party login codes and link tokens) or content that is encrypted
+// at rest (notes with their revisions and reflections, shared notes, messages)
+var sensitiveBodyPaths = []string{
+	"/hope/auth/login",
+	"/hope/auth/register",
+	"/hope/auth/oauth/",
+	"/hope/user/2fa",
+	"/hope/user/identities",
+	"/hope/notes",
+	sharedLinkPath,
+	"/hope/send",
+	"/hope/messages",
+}
+
+// hasSensitiveBody reports whether the bodies of a request path must be kept out of the log
+func hasSensitiveBody(path string) bool {
+	for _, prefix := range sensitiveBodyPaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// sharedLinkPath is the prefix of share link routes, followed by the link's bearer token
+const sharedLinkPath = "/hope/shared/"
+
+// redactURL returns the request URL with the token of share links masked
+func redactURL(u *url.URL) string {
+	path := u.EscapedPath()
+	if !strings.HasPrefix(path, sharedLinkPath) {
+		return u.String()
+	}
+	token, _, _ := strings.Cut(strings.TrimPrefix(path, sharedLinkPath), "/")
+	return strings.Replace(u.String(), sharedLinkPath+token, sharedLinkPath+redactedBody, 1)
+}
+
 // isStaticFileRequest checks if the request is for static files
 func isStaticFileRequest(path string) bool {
 	staticPaths := []string{
@@ -105,12 +168,17 @@ func generateRequestID() string {
 // responseWriter wraps gin.ResponseWriter to capture response data
 type responseWriter struct {
 	gin.ResponseWriter
-	body   *bytes.Buffer
-	status int
+	body    *bytes.Buffer
+	status  int
+	capture bool // whether the body is kept for the log
 }
 
+// Write passes the body on, keeping a copy of JSON bodies for the log; files
+// such as exports and attachments are not buffered
 func (w *responseWriter) Write(b []byte) (int, error) {
-	w.body.Write(b)
+	if w.capture && strings.HasPrefix(w.Header().Get("Content-Type"), gin.MIMEJSON) {
+		w.body.Write(b)
+	}
 	return w.ResponseWriter.Write(b)
 }
 
@@ -132,7 +200,7 @@ func generateCurlCommand(req *http.Request, body []byte) string {
 	if req.TLS != nil {
 		scheme = "https"
 	}
-	curlCmd.WriteString(fmt.Sprintf(" '%s://%s%s'", scheme, req.Host, req.URL.String()))
+	curlCmd.WriteString(fmt.Sprintf(" '%s://%s%s'", scheme, req.Host, redactURL(req.URL)))
 
 	// Add headers (no masking)
 	for name, values := range req.Header {
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// logRequest sends a request through CurlLoggingMiddleware to a handler that
+// echoes a response and returns the log line
+func logRequest(t *testing.T, method, path, contentType, body string, respond gin.HandlerFunc) string {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	var out bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&out)
+
+	r := gin.New()
+	r.Use(CurlLoggingMiddleware())
+	r.Handle(method, path, respond)
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	return out.String()
+}
+
+func TestCurlLoggingRedactsSecrets(t *testing.T) {
+	respond := func(c *gin.Context) {
+		c.JSON(http.StatusOK, Response{Success: true, Data: gin.H{"secret": "JBSWY3DPEHPK3PXP"}})
+	}
+
+	line := logRequest(t, http.MethodPost, "/hope/user/2fa/enable", "application/json", `{"code":"123456"}`, respond)
+	for _, secret := range []string{"123456", "JBSWY3DPEHPK3PXP"} {
+		if strings.Contains(line, secret) {
+			t.Errorf("2FA log line contains %q: %s", secret, line)
+		}
+	}
+	if !strings.Contains(line, "Status: 200") || !strings.Contains(line, "/hope/user/2fa/enable") {
+		t.Errorf("2FA log line lacks the request metadata: %s", line)
+	}
+
+	line = logRequest(t, http.MethodPut, "/hope/notes/1", "application/json", `{"content":"a private note"}`, respond)
+	if strings.Contains(line, "a private note") || strings.Contains(line, "JBSWY3DPEHPK3PXP") {
+		t.Errorf("note log line contains the note: %s", line)
+	}
+
+	// Uploads reach the handler unread
+	upload := func(c *gin.Context) {
+		if n, _ := io.Copy(io.Discard, c.Request.Body); n != int64(len("file data")) {
+			t.Errorf("handler read %d bytes of the upload", n)
+		}
+		c.JSON(http.StatusOK, Response{Success: true})
+	}
+	line = logRequest(t, http.MethodPost, "/hope/user/upload", "multipart/form-data; boundary=x", "file data", upload)
+	if strings.Contains(line, "file data") || !strings.Contains(line, `"success":true`) {
+		t.Errorf("upload log line: %s", line)
+	}
+
+	// Files are logged by type and size only
+	download := func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/zip", []byte("PK archive"))
+	}
+	line = logRequest(t, http.MethodGet, "/hope/user/export", "", "", download)
+	if strings.Contains(line, "PK archive") || !strings.Contains(line, "[application/zip, 10 bytes]") {
+		t.Errorf("export log line: %s", line)
+	}
+
+	// Share link tokens are masked in the URL
+	line = logRequest(t, http.MethodGet, "/hope/shared/s3cr3tt0ken/attachments/3", "", "", respond)
+	if strings.Contains(line, "s3cr3tt0ken") || !strings.Contains(line, "/hope/shared/[redacted]/attachments/3") {
+		t.Errorf("share link log line: %s", line)
+	}
+
+	line = logRequest(t, http.MethodPost, "/hope/ping", "application/json", `{"hello":"world"}`, respond)
+	if !strings.Contains(line, `{"hello":"world"}`) || !strings.Contains(line, "JBSWY3DPEHPK3PXP") {
+		t.Errorf("other routes are logged with their bodies: %s", line)
+	}
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"hope_backend/dao"
+	"hope_backend/fieldcrypt"
+)
+
+const (
+	// FieldReencryptionInterval is how often stored values are checked for an outdated key
+	FieldReencryptionInterval = 10 * time.Minute
+	// fieldReencryptionBatch is the number of rows rewritten per query
+	fieldReencryptionBatch = 200
+)
+
+// FieldReencryptionJob returns a scheduled job that rewrites encrypted columns
+// stored in plaintext or under an older master key with the current key. After
+// rotating FIELD_ENCRYPTION_KEY_VERSION, the old key can be removed from
+// FIELD_ENCRYPTION_KEYS once a run reports no failed values. The job does
+// nothing while encryption is disabled, so a missing key never turns stored
+// values back into plaintext.
+func FieldReencryptionJob() func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if fieldcrypt.CurrentVersion() == 0 {
+			return nil
+		}
+
+		for _, column := range dao.EncryptedColumns {
+			var after int64
+			rewritten, failed := 0, 0
+			for {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+
+				result, err := dao.ReencryptBatch(column, after, fieldReencryptionBatch)
+				if err != nil {
+					return fmt.Errorf("%s.%s: %w", column.Table, column.Column, err)
+				}
+				rewritten += result.Rewritten
+				failed += result.Failed
+				if result.LastID == 0 {
+					break
+				}
+				after = result.LastID
+			}
+
+			if rewritten > 0 || failed > 0 {
+				fmt.Printf("[Field Encryption] %s.%s: re-encrypted %d values, %d could not be decrypted\n",
+					column.Table, column.Column, rewritten, failed)
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,391 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"hope_backend/dao"
+	"hope_backend/notify"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// reportClaimTimeout is how long a claim holds before another moderator can take the report over
+	reportClaimTimeout = 30 * time.Minute
+	// defaultBanDays and maxBanDays bound community bans
+	defaultBanDays = 7
+	maxBanDays     = 3650
+	// maxModerationNoteLength limits the note a moderator adds to a decision
+	maxModerationNoteLength = 1000
+)
+
+// ResolveReportRequest represents the request body for resolving a report
+type ResolveReportRequest struct {
+	Action  string `json:"action" binding:"required"` // dismiss, hide, delete, warn or ban
+	Note    string `json:"note"`                      // Shown to the author with warnings and bans
+	BanDays int    `json:"ban_days"`                  // For bans, defaults to defaultBanDays
+}
+
+// ListReportsHandler handles GET requests for the moderation queue. By
+// default it lists reports that are open or claimed, oldest first;
+// ?status= selects open, claimed or resolved reports.
+func ListReportsHandler(reportDAO *dao.ReportDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		statuses := []string{dao.ReportStatusOpen, dao.ReportStatusClaimed}
+		switch status := c.Query("status"); status {
+		case "":
+		case dao.ReportStatusOpen, dao.ReportStatusClaimed, dao.ReportStatusResolved:
+			statuses = []string{status}
+		default:
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid status. Supported statuses: open, claimed, resolved",
+			})
+			return
+		}
+
+		// Parse pagination parameters
+		page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+		if err != nil || page < 1 {
+			page = 1
+		}
+
+		pageSize, err := strconv.Atoi(c.DefaultQuery("size", strconv.Itoa(defaultPageSize)))
+		if err != nil || pageSize < 1 {
+			pageSize = defaultPageSize
+		}
+		if pageSize > maxPageSize {
+			pageSize = maxPageSize
+		}
+
+		reports, total, err := reportDAO.List(statuses, page, pageSize)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to retrieve reports: " + err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, Response{
+			Success: true,
+			Data:    reports,
+			Total:   total,
+			Page:    page,
+			Size:    pageSize,
+		})
+	}
+}
+
+// ClaimReportHandler handles POST requests to claim a report, so that two
+// moderators do not work on it at once
+func ClaimReportHandler(reportDAO *dao.ReportDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, Response{
+				Success: false,
+				Message: "Authentication required",
+			})
+			return
+		}
+
+		reportID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid report ID format",
+			})
+			return
+		}
+
+		staleBefore := time.Now().Add(-reportClaimTimeout).UnixMilli()
+		report, err := reportDAO.Claim(reportID, userID.(int64), staleBefore)
+		if err != nil {
+			status := http.StatusInternalServerError
+			switch err.Error() {
+			case "report not found":
+				status = http.StatusNotFound
+			case "report already resolved", "report claimed by another moderator":
+				status = http.StatusConflict
+			}
+			c.JSON(status, Response{
+				Success: false,
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, Response{
+			Success: true,
+			Message: "Report claimed",
+			Data:    report,
+		})
+	}
+}
+
+// ResolveReportHandler handles POST requests to resolve a claimed report.
+// The action is applied to the reported content and its author, every open
+// report on the same content is resolved with it, and the decision is recorded.
+func ResolveReportHandler(reportDAO *dao.ReportDAO, postDAO *dao.PostDAO, commentDAO *dao.CommentDAO,
+	profileDAO *dao.UserProfileDAO, auditDAO *dao.AuditDAO, notifier notify.Notifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, Response{
+				Success: false,
+				Message: "Authentication required",
+			})
+			return
+		}
+		moderatorID := userID.(int64)
+
+		reportID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid report ID format",
+			})
+			return
+		}
+
+		var req ResolveReportRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+
+		switch req.Action {
+		case dao.ModerationDismiss, dao.ModerationHide, dao.ModerationDelete, dao.ModerationWarn, dao.ModerationBan:
+		default:
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid action. Supported actions: dismiss, hide, delete, warn, ban",
+			})
+			return
+		}
+
+		note := strings.TrimSpace(req.Note)
+		if len([]rune(note)) > maxModerationNoteLength {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Note is too long. Maximum length is " + strconv.Itoa(maxModerationNoteLength) + " characters",
+			})
+			return
+		}
+
+		banDays := req.BanDays
+		if banDays == 0 {
+			banDays = defaultBanDays
+		}
+		if banDays < 1 || banDays > maxBanDays {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid ban_days. It must be between 1 and " + strconv.Itoa(maxBanDays),
+			})
+			return
+		}
+
+		report, err := reportDAO.GetByID(reportID)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if err.Error() == "report not found" {
+				status = http.StatusNotFound
+			}
+			c.JSON(status, Response{
+				Success: false,
+				Message: err.Error(),
+			})
+			return
+		}
+
+		if report.Status == dao.ReportStatusResolved {
+			c.JSON(http.StatusConflict, Response{
+				Success: false,
+				Message: "report already resolved",
+			})
+			return
+		}
+		if report.Status != dao.ReportStatusClaimed || report.ClaimedBy != moderatorID {
+			c.JSON(http.StatusConflict, Response{
+				Success: false,
+				Message: "Claim the report before resolving it",
+			})
+			return
+		}
+
+		// Accounts deleted since the report leave no one to warn or ban
+		if (req.Action == dao.ModerationWarn || req.Action == dao.ModerationBan) && report.TargetUserID == 0 {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "The author of this " + report.TargetType + " no longer exists",
+			})
+			return
+		}
+
+		decision := &dao.ModerationDecision{
+			ModeratorID: moderatorID,
+			Action:      req.Action,
+			Note:        note,
+		}
+		if req.Action == dao.ModerationBan {
+			decision.BanUntil = time.Now().AddDate(0, 0, banDays).UnixMilli()
+		}
+
+		if err := applyModerationAction(c, report, decision, postDAO, commentDAO, reportDAO, profileDAO, auditDAO); err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to apply decision: " + err.Error(),
+			})
+			return
+		}
+
+		if err := reportDAO.Resolve(report, decision); err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to record decision: " + err.Error(),
+			})
+			return
+		}
+
+		if req.Action == dao.ModerationWarn || req.Action == dao.ModerationBan {
+			notifyModerationDecision(c, notifier, report, decision)
+		}
+
+		c.JSON(http.StatusOK, Response{
+			Success: true,
+			Message: "Report resolved",
+			Data:    decision,
+		})
+	}
+}
+
+// applyModerationAction carries out a decision on the reported content and its author
+func applyModerationAction(c *gin.Context, report *dao.Report, decision *dao.ModerationDecision, postDAO *dao.PostDAO,
+	commentDAO *dao.CommentDAO, reportDAO *dao.ReportDAO, profileDAO *dao.UserProfileDAO, auditDAO *dao.AuditDAO) error {
+	switch decision.Action {
+	case dao.ModerationDismiss:
+		return reportDAO.SetHidden(report.TargetType, report.TargetID, false)
+
+	case dao.ModerationDelete:
+		if report.TargetType == dao.ReportTargetComment {
+			if err := commentDAO.Delete(report.TargetID); err != nil {
+				if err.Error() == "comment not found" {
+					return nil // Already deleted by its author
+				}
+				return err
+			}
+			recordAudit(c, auditDAO, AuditModeratorDeleteComment, report.TargetUserID, gin.H{"comment_id": report.TargetID})
+			return nil
+		}
+
+		post, err := postDAO.GetByID(report.TargetID, decision.ModeratorID)
+		if err != nil {
+			if err.Error() == "post not found" {
+				return nil // Already deleted by its author
+			}
+			return err
+		}
+		removePostImageFiles(post.Images)
+		if err := postDAO.Delete(report.TargetID); err != nil {
+			return err
+		}
+		recordAudit(c, auditDAO, AuditModeratorDeletePost, report.TargetUserID, gin.H{"post_id": report.TargetID})
+		return nil
+
+	case dao.ModerationBan:
+		if err := profileDAO.BanFromCommunity(report.TargetUserID, decision.BanUntil); err != nil {
+			return err
+		}
+		recordAudit(c, auditDAO, AuditModeratorBanUser, report.TargetUserID, gin.H{
+			"report_id": report.ID,
+			"ban_until": decision.BanUntil,
+		})
+	}
+
+	// Hide, warn and ban keep the content out of the community
+	return reportDAO.SetHidden(report.TargetType, report.TargetID, true)
+}
+
+// notifyModerationDecision tells an author about a warning or ban. Failures
+// are logged and never fail the request.
+func notifyModerationDecision(c *gin.Context, notifier notify.Notifier, report *dao.Report, decision *dao.ModerationDecision) {
+	if notifier == nil {
+		return
+	}
+
+	content := "帖子"
+	if report.TargetType == dao.ReportTargetComment {
+		content = "评论"
+	}
+	n := notify.Notification{
+		UserID:   report.TargetUserID,
+		Category: "moderation_" + decision.Action,
+		Title:    "社区提醒",
+		Body:     "你的一条" + content + "违反了社区规范，已被隐藏。",
+		Data: map[string]string{
+			"target_type": report.TargetType,
+			"target_id":   strconv.FormatInt(report.TargetID, 10),
+		},
+	}
+	if decision.Action == dao.ModerationBan {
+		until := time.UnixMilli(decision.BanUntil).Format("2006-01-02")
+		n.Title = "社区禁言通知"
+		n.Body += "在 " + until + " 之前你将无法在社区发帖和评论。"
+		n.Data["ban_until"] = strconv.FormatInt(decision.BanUntil, 10)
+	}
+	if decision.Note != "" {
+		n.Body += "\n" + decision.Note
+	}
+
+	if err := notifier.Notify(c.Request.Context(), n); err != nil {
+		fmt.Printf("[Moderation] Failed to notify user %d: %v\n", report.TargetUserID, err)
+	}
+}
+
+// ListModerationDecisionsHandler handles GET requests for the record of
+// moderation decisions, newest first. ?user_id= limits it to decisions about
+// one user's content.
+func ListModerationDecisionsHandler(reportDAO *dao.ReportDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		targetUserID, _ := strconv.ParseInt(c.Query("user_id"), 10, 64)
+
+		// Parse pagination parameters
+		page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+		if err != nil || page < 1 {
+			page = 1
+		}
+
+		pageSize, err := strconv.Atoi(c.DefaultQuery("size", strconv.Itoa(defaultPageSize)))
+		if err != nil || pageSize < 1 {
+			pageSize = defaultPageSize
+		}
+		if pageSize > maxPageSize {
+			pageSize = maxPageSize
+		}
+
+		decisions, total, err := reportDAO.ListDecisions(targetUserID, page, pageSize)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to retrieve decisions: " + err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, Response{
+			Success: true,
+			Data:    decisions,
+			Total:   total,
+			Page:    page,
+			Size:    pageSize,
+		})
+	}
+}
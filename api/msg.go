@@ -37,7 +37,7 @@ var (
 type SendMsg struct {
 	UserID  int64  `json:"user_id"`
 	ChatID  string `json:"chat_id"`
-	Content string `json:"content"`
+	Content string `json:"content" binding:"max=2000"` // Counted in characters, the column holds the encrypted value
 }
 
 // Claude API structures
@@ -174,73 +174,82 @@ func SendMessageHandler(profileDAO *dao.UserProfileDAO) func(c *gin.Context) {
 	}
 }
 
+// Models the providers are called with
+const (
+	deepSeekModel = "deepseek-chat"             // DeepSeek's main model
+	claudeModel   = "claude-3-5-haiku-20241022" // Using cheaper Haiku model
+	openAIModel   = "gpt-4o-mini"
+)
+
+// caregiverSystemPrompt sets the tone of every chat reply
+const caregiverSystemPrompt = "你是一位富有同情心的助手，帮助那些正在照顾抑郁症亲人的人。提供支持性、有同理心的回应，并在适当的时候提供实用的指导。请用温暖、理解的语调回复，避免过于技术性的建议。"
+
+// aiFallbackResponse is sent when every provider failed
+const aiFallbackResponse = "抱歉，我现在暂时无法回复。请稍后再试，或者告诉我更多关于您当前情况的信息，我会尽力帮助您。"
+
+// aiProviderChain lists the providers in the order they are tried:
+// DeepSeek (cheapest) -> Claude Haiku -> OpenAI
+var aiProviderChain = []struct {
+	Provider AIProvider
+	Model    string
+	complete func(systemPrompt, userPrompt string) (string, error)
+}{
+	{ProviderDeepSeek, deepSeekModel, getDeepSeekResponse},
+	{ProviderClaude, claudeModel, getClaudeResponse},
+	{ProviderOpenAI, openAIModel, getChatGPTResponseEnhance},
+}
+
 // getAIResponse tries multiple providers with fallback logic
 func getAIResponse(userInput, patientName, relationshipToPatient, illnessCause string) string {
-	// Priority order: DeepSeek (cheapest) -> Claude Haiku -> OpenAI -> fallback
-
-	// Try DeepSeek first (cheapest and good quality)
-	start := time.Now()
-	if response, err := getDeepSeekResponse(userInput, patientName, relationshipToPatient, illnessCause); err == nil {
-		duration := time.Since(start)
-		fmt.Printf("[AI Response] DeepSeek success in %v\n", duration)
-		return response
-	} else {
-		duration := time.Since(start)
-		fmt.Printf("[AI Response] DeepSeek failed in %v: %v\n", duration, err)
-	}
+	contextualPrompt := fmt.Sprintf(
+		"你正在回复一位照顾抑郁症患者的人。"+
+			"患者姓名：%s。照顾者与患者的关系：%s。"+
+			"关于患者病情的背景：%s。"+
+			"请提供一个富有同情心和支持性的回复，同时认可他们所处的情况。"+
+			"原始消息：%s",
+		patientName, relationshipToPatient, illnessCause, userInput)
 
-	// Try Claude Haiku (good balance of cost/quality)
-	start = time.Now()
-	if response, err := getClaudeResponse(userInput, patientName, relationshipToPatient, illnessCause); err == nil {
-		duration := time.Since(start)
-		fmt.Printf("[AI Response] Claude success in %v\n", duration)
-		return response
-	} else {
-		duration := time.Since(start)
-		fmt.Printf("[AI Response] Claude failed in %v: %v\n", duration, err)
+	response, _, _, err := completeWithFallback(caregiverSystemPrompt, contextualPrompt)
+	if err != nil {
+		// All providers failed - return default response
+		return aiFallbackResponse
 	}
+	return response
+}
 
-	// Try OpenAI as fallback
-	start = time.Now()
-	if response, err := getChatGPTResponseEnhance(userInput, patientName, relationshipToPatient, illnessCause); err == nil {
+// completeWithFallback sends a prompt to each provider of aiProviderChain
+// until one answers, and reports which provider and model did
+func completeWithFallback(systemPrompt, userPrompt string) (string, AIProvider, string, error) {
+	for _, p := range aiProviderChain {
+		start := time.Now()
+		response, err := p.complete(systemPrompt, userPrompt)
 		duration := time.Since(start)
-		fmt.Printf("[AI Response] OpenAI success in %v\n", duration)
-		return response
-	} else {
-		duration := time.Since(start)
-		fmt.Printf("[AI Response] OpenAI failed in %v: %v\n", duration, err)
+		if err == nil {
+			fmt.Printf("[AI Response] %s success in %v\n", p.Provider, duration)
+			return response, p.Provider, p.Model, nil
+		}
+		fmt.Printf("[AI Response] %s failed in %v: %v\n", p.Provider, duration, err)
 	}
 
-	// All providers failed - return default response
 	fmt.Printf("[AI Response] All providers failed, using fallback\n")
-	return "抱歉，我现在暂时无法回复。请稍后再试，或者告诉我更多关于您当前情况的信息，我会尽力帮助您。"
+	return "", "", "", fmt.Errorf("all AI providers failed")
 }
 
 // getClaudeResponse uses Claude API
-func getClaudeResponse(userInput, patientName, relationshipToPatient, illnessCause string) (string, error) {
+func getClaudeResponse(systemPrompt, userPrompt string) (string, error) {
 	apiKey := os.Getenv("CLAUDE_API_KEY")
 	if apiKey == "" {
 		return "", fmt.Errorf("Claude API key not configured")
 	}
 
-	systemPrompt := "你是一位富有同情心的助手，帮助那些正在照顾抑郁症亲人的人。提供支持性、有同理心的回应，并在适当的时候提供实用的指导。请用温暖、理解的语调回复，避免过于技术性的建议。"
-
-	contextualPrompt := fmt.Sprintf(
-		"你正在回复一位照顾抑郁症患者的人。"+
-			"患者姓名：%s。照顾者与患者的关系：%s。"+
-			"关于患者病情的背景：%s。"+
-			"请提供一个富有同情心和支持性的回复，同时认可他们所处的情况。"+
-			"原始消息：%s",
-		patientName, relationshipToPatient, illnessCause, userInput)
-
 	request := ClaudeRequest{
-		Model:     "claude-3-5-haiku-20241022", // Using cheaper Haiku model
+		Model:     claudeModel,
 		MaxTokens: 800,
 		System:    systemPrompt,
 		Messages: []ClaudeMessage{
 			{
 				Role:    "user",
-				Content: contextualPrompt,
+				Content: userPrompt,
 			},
 		},
 	}
@@ -295,7 +304,7 @@ func getClaudeResponse(userInput, patientName, relationshipToPatient, illnessCau
 }
 
 // getDeepSeekResponse uses DeepSeek API (compatible with OpenAI client)
-func getDeepSeekResponse(userInput, patientName, relationshipToPatient, illnessCause string) (string, error) {
+func getDeepSeekResponse(systemPrompt, userPrompt string) (string, error) {
 	apiKey := os.Getenv("DEEPSEEK_API_KEY")
 	if apiKey == "" {
 		return "", fmt.Errorf("DeepSeek API key not configured")
@@ -306,32 +315,23 @@ func getDeepSeekResponse(userInput, patientName, relationshipToPatient, illnessC
 	config.BaseURL = "https://api.deepseek.com"
 	client := openai.NewClientWithConfig(config)
 
-	// Create contextual prompt
-	contextualPrompt := fmt.Sprintf(
-		"你正在回复一位照顾抑郁症患者的人。"+
-			"患者姓名：%s。照顾者与患者的关系：%s。"+
-			"关于患者病情的背景：%s。"+
-			"请提供一个富有同情心和支持性的回复，同时认可他们所处的情况。"+
-			"原始消息：%s",
-		patientName, relationshipToPatient, illnessCause, userInput)
-
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	resp, err := client.CreateChatCompletion(
 		ctx,
 		openai.ChatCompletionRequest{
-			Model:       "deepseek-chat", // DeepSeek's main model
+			Model:       deepSeekModel,
 			MaxTokens:   800,
 			Temperature: 0.7,
 			Messages: []openai.ChatCompletionMessage{
 				{
 					Role:    "system",
-					Content: "你是一位富有同情心的助手，帮助那些正在照顾抑郁症亲人的人。提供支持性、有同理心的回应，并在适当的时候提供实用的指导。请用温暖、理解的语调回复，避免过于技术性的建议。",
+					Content: systemPrompt,
 				},
 				{
 					Role:    "user",
-					Content: contextualPrompt,
+					Content: userPrompt,
 				},
 			},
 		},
@@ -362,7 +362,7 @@ func getChatGPTResponse(userInput string) (string, error) {
 }
 
 // getChatGPTResponseEnhance - enhanced OpenAI function with context
-func getChatGPTResponseEnhance(userInput, patientName, relationshipToPatient, illnessCause string) (string, error) {
+func getChatGPTResponseEnhance(systemPrompt, userPrompt string) (string, error) {
 	apiKey := os.Getenv("OPENAI_API_KEY")
 	if apiKey == "" {
 		return "", fmt.Errorf("OpenAI API key not configured")
@@ -370,27 +370,18 @@ func getChatGPTResponseEnhance(userInput, patientName, relationshipToPatient, il
 
 	client := openai.NewClient(apiKey)
 
-	// Create contextual prompt with patient information in Chinese
-	contextualPrompt := fmt.Sprintf(
-		"你正在回复一位照顾抑郁症患者的人。"+
-			"患者姓名：%s。照顾者与患者的关系：%s。"+
-			"关于患者病情的背景：%s。"+
-			"请提供一个富有同情心和支持性的回复，同时认可他们所处的情况。"+
-			"原始消息：%s",
-		patientName, relationshipToPatient, illnessCause, userInput)
-
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	resp, err := client.CreateChatCompletion(
 		ctx,
 		openai.ChatCompletionRequest{
-			Model:       "gpt-4o-mini",
+			Model:       openAIModel,
 			MaxTokens:   500,
 			Temperature: 0.7,
 			Messages: []openai.ChatCompletionMessage{
-				{Role: "system", Content: "你是一位富有同情心的助手，帮助那些正在照顾抑郁症亲人的人。提供支持性、有同理心的回应，并在适当的时候提供实用的指导。"},
-				{Role: "user", Content: contextualPrompt},
+				{Role: "system", Content: systemPrompt},
+				{Role: "user", Content: userPrompt},
 			},
 		},
 	)
@@ -2,25 +2,144 @@ package api
 
 import (
 	"errors"
+	"fmt"
 	"hope_backend/dao"
 	"hope_backend/models"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-// CreateNoteRequest is the expected request body for creating a note
+// Limits for the structured check-in fields of a note
+const (
+	minMoodScore       = 1
+	maxMoodScore       = 10
+	maxSleepHours      = 24
+	clearSleepHours    = -1 // sleep_hours value that clears the field, as 0 hours is a valid entry
+	maxEmotionTags     = 10
+	maxEmotionTagRunes = 20
+)
+
+// NoteCheckIn holds the optional structured fields of a note
+type NoteCheckIn struct {
+	PatientMood   *int     `json:"patient_mood"`   // 1-10
+	CaregiverMood *int     `json:"caregiver_mood"` // 1-10
+	SleepHours    *float64 `json:"sleep_hours"`    // 0-24, or -1 to clear
+	EmotionTags   []string `json:"emotion_tags"`
+}
+
+// maxNoteContentRunes limits note content. The column holds the encrypted
+// value, which is longer, so the limit is enforced here rather than by the schema.
+const maxNoteContentRunes = 10000
+
+// CreateNoteRequest is the expected request body for creating a note.
+// NoteDate is "YYYY.M.D" or "YYYY-MM-DD"; it defaults to today in the user's timezone.
 type CreateNoteRequest struct {
-	NoteDate string `json:"note_date" binding:"required"`
-	Content  string `json:"content" binding:"required"`
+	NoteDate string `json:"note_date"`
+	Content  string `json:"content" binding:"required,max=10000"`
+	PromptID *int   `json:"prompt_id"` // Journaling prompt the note answers
+	NoteCheckIn
 }
 
-// UpdateNoteRequest is the expected request body for updating a note
+// UpdateNoteRequest is the expected request body for updating a note.
+// Check-in fields and prompt_id that are left out keep their value; a mood
+// or prompt_id of 0, a sleep_hours of -1 or an empty emotion_tags array
+// clears the field.
 type UpdateNoteRequest struct {
-	Content string `json:"content" binding:"required"`
+	Content  string `json:"content" binding:"required,max=10000"`
+	PromptID *int   `json:"prompt_id"`
+	NoteCheckIn
+}
+
+// validate checks the ranges of the check-in fields and normalises the emotion tags
+func (r *NoteCheckIn) validate() error {
+	if !validMood(r.PatientMood) {
+		return fmt.Errorf("patient_mood must be between %d and %d", minMoodScore, maxMoodScore)
+	}
+	if !validMood(r.CaregiverMood) {
+		return fmt.Errorf("caregiver_mood must be between %d and %d", minMoodScore, maxMoodScore)
+	}
+
+	if r.SleepHours != nil && *r.SleepHours != clearSleepHours && (*r.SleepHours < 0 || *r.SleepHours > maxSleepHours) {
+		return fmt.Errorf("sleep_hours must be between 0 and %d, or %d to clear", maxSleepHours, clearSleepHours)
+	}
+
+	if r.EmotionTags != nil {
+		tags := make([]string, 0, len(r.EmotionTags))
+		seen := make(map[string]bool)
+		for _, tag := range r.EmotionTags {
+			tag = strings.ToLower(strings.TrimSpace(tag))
+			if tag == "" || seen[tag] {
+				continue
+			}
+			if len([]rune(tag)) > maxEmotionTagRunes {
+				return fmt.Errorf("emotion tags can be at most %d characters", maxEmotionTagRunes)
+			}
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+		if len(tags) > maxEmotionTags {
+			return fmt.Errorf("at most %d emotion tags are allowed", maxEmotionTags)
+		}
+		r.EmotionTags = tags
+	}
+
+	return nil
+}
+
+// validMood reports whether a mood is unset, 0 (clear) or within the score range
+func validMood(mood *int) bool {
+	return mood == nil || *mood == 0 || (*mood >= minMoodScore && *mood <= maxMoodScore)
+}
+
+// noteCheckInMarkdown renders the logged check-in fields of a note as Markdown lines
+func noteCheckInMarkdown(note models.Note) string {
+	var b strings.Builder
+	if note.PatientMood != nil {
+		fmt.Fprintf(&b, "- 心情: %d/10\n", *note.PatientMood)
+	}
+	if note.CaregiverMood != nil {
+		fmt.Fprintf(&b, "- 照护者心情: %d/10\n", *note.CaregiverMood)
+	}
+	if note.SleepHours != nil {
+		fmt.Fprintf(&b, "- 睡眠: %.1f 小时\n", *note.SleepHours)
+	}
+	if len(note.EmotionTags) > 0 {
+		fmt.Fprintf(&b, "- 情绪: %s\n", strings.Join(note.EmotionTags, ", "))
+	}
+	if b.Len() > 0 {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// apply copies the provided check-in fields onto a note
+func (r *NoteCheckIn) apply(note *models.Note) {
+	if r.PatientMood != nil {
+		note.PatientMood = r.PatientMood
+		if *r.PatientMood == 0 {
+			note.PatientMood = nil
+		}
+	}
+	if r.CaregiverMood != nil {
+		note.CaregiverMood = r.CaregiverMood
+		if *r.CaregiverMood == 0 {
+			note.CaregiverMood = nil
+		}
+	}
+	if r.SleepHours != nil {
+		note.SleepHours = r.SleepHours
+		if *r.SleepHours == clearSleepHours {
+			note.SleepHours = nil
+		}
+	}
+	if r.EmotionTags != nil {
+		note.EmotionTags = r.EmotionTags
+	}
 }
 
 // CreateNoteHandler handles creating a new note
@@ -45,16 +164,37 @@ func CreateNoteHandler(c *gin.Context) {
 		return
 	}
 
-	if req.NoteDate == "" {
-		// If no date provided, use today's date in the format "YYYY.M.D"
-		now := time.Now()
-		req.NoteDate = strconv.Itoa(now.Year()) + "." +
-			strconv.Itoa(int(now.Month())) + "." +
-			strconv.Itoa(now.Day())
+	if err := req.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	if err := validPromptID(req.PromptID); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	// If no date provided, use today's date where the user lives
+	noteDate := userToday(userID.(int64))
+	if req.NoteDate != "" {
+		parsed, err := models.ParseDate(req.NoteDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: err.Error(),
+			})
+			return
+		}
+		noteDate = parsed
 	}
 
 	// Check if a note already exists for this date
-	existingNote, err := dao.GetNoteByUserAndDate(userID.(int64), req.NoteDate)
+	existingNote, err := dao.GetNoteByUserAndDate(userID.(int64), noteDate)
 	if err != nil {
 		if !errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusInternalServerError, Response{
@@ -77,11 +217,13 @@ func CreateNoteHandler(c *gin.Context) {
 	now := time.Now().Unix()
 	note := &models.Note{
 		UserID:    userID.(int64),
-		NoteDate:  req.NoteDate,
+		NoteDate:  noteDate,
 		Content:   req.Content,
+		PromptID:  promptIDValue(req.PromptID),
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
+	req.apply(note)
 
 	if err := dao.CreateNote(note); err != nil {
 		c.JSON(http.StatusInternalServerError, Response{
@@ -120,7 +262,7 @@ func GetNoteHandler(c *gin.Context) {
 	}
 
 	note, err := dao.GetNoteByID(noteID)
-	if err != nil {
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		c.JSON(http.StatusInternalServerError, Response{
 			Success: false,
 			Message: "Failed to retrieve note: " + err.Error(),
@@ -136,13 +278,24 @@ func GetNoteHandler(c *gin.Context) {
 		return
 	}
 
-	// Check if the note belongs to the user
+	// Notes of other users are readable only through an active share
 	if note.UserID != userID.(int64) {
-		c.JSON(http.StatusForbidden, Response{
-			Success: false,
-			Message: "You don't have permission to access this note",
-		})
-		return
+		share, err := noteShareFor(userID.(int64), note)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to check access: " + err.Error(),
+			})
+			return
+		}
+		if share == nil {
+			c.JSON(http.StatusForbidden, Response{
+				Success: false,
+				Message: "You don't have permission to access this note",
+			})
+			return
+		}
+		recordSharedNoteAccess(c, share, []int{note.NoteID})
 	}
 
 	c.JSON(http.StatusOK, Response{
@@ -182,9 +335,24 @@ func UpdateNoteHandler(c *gin.Context) {
 		return
 	}
 
+	if err := req.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	if err := validPromptID(req.PromptID); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
 	// Check if the note exists and belongs to the user
 	note, err := dao.GetNoteByID(noteID)
-	if err != nil {
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		c.JSON(http.StatusInternalServerError, Response{
 			Success: false,
 			Message: "Failed to retrieve note: " + err.Error(),
@@ -209,6 +377,11 @@ func UpdateNoteHandler(c *gin.Context) {
 	}
 
 	note.Content = req.Content
+	if req.PromptID != nil {
+		note.PromptID = promptIDValue(req.PromptID)
+	}
+	req.apply(note)
+	note.UpdatedAt = time.Now().Unix()
 	if err := dao.UpdateNote(note); err != nil {
 		c.JSON(http.StatusInternalServerError, Response{
 			Success: false,
@@ -224,7 +397,7 @@ func UpdateNoteHandler(c *gin.Context) {
 	})
 }
 
-// DeleteNoteHandler handles deleting a note
+// DeleteNoteHandler moves a note to the trash
 func DeleteNoteHandler(c *gin.Context) {
 	// Get user ID from context or session
 	userID, exists := c.Get("userID")
@@ -246,16 +419,21 @@ func DeleteNoteHandler(c *gin.Context) {
 	}
 
 	if err := dao.DeleteNote(noteID, userID.(int64)); err != nil {
-		c.JSON(http.StatusInternalServerError, Response{
+		status := http.StatusInternalServerError
+		message := "Failed to delete note: " + err.Error()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			status, message = http.StatusNotFound, "Note not found"
+		}
+		c.JSON(status, Response{
 			Success: false,
-			Message: "Failed to delete note: " + err.Error(),
+			Message: message,
 		})
 		return
 	}
 
 	c.JSON(http.StatusOK, Response{
 		Success: true,
-		Message: "Note deleted successfully",
+		Message: "Note moved to trash, it can be restored for 30 days",
 	})
 }
 
@@ -299,8 +477,7 @@ func GetNoteByDateHandler(c *gin.Context) {
 		return
 	}
 
-	date := c.Param("date")
-	if date == "" {
+	if c.Param("date") == "" {
 		c.JSON(http.StatusBadRequest, Response{
 			Success: false,
 			Message: "Date parameter is required",
@@ -308,6 +485,15 @@ func GetNoteByDateHandler(c *gin.Context) {
 		return
 	}
 
+	date, err := models.ParseDate(c.Param("date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
 	note, err := dao.GetNoteByUserAndDate(userID.(int64), date)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, Response{
@@ -332,7 +518,8 @@ func GetNoteByDateHandler(c *gin.Context) {
 	})
 }
 
-// GetNotesByDateRangeHandler handles retrieving notes within a date range
+// GetNotesByDateRangeHandler handles retrieving notes within a date range.
+// With ?owner_id= it returns the notes of another user that are shared with the current user.
 func GetNotesByDateRangeHandler(c *gin.Context) {
 	// Get user ID from context or session
 	userID, exists := c.Get("userID")
@@ -344,10 +531,7 @@ func GetNotesByDateRangeHandler(c *gin.Context) {
 		return
 	}
 
-	startDate := c.Query("start")
-	endDate := c.Query("end")
-
-	if startDate == "" || endDate == "" {
+	if c.Query("start") == "" || c.Query("end") == "" {
 		c.JSON(http.StatusBadRequest, Response{
 			Success: false,
 			Message: "Both start and end date parameters are required",
@@ -355,7 +539,41 @@ func GetNotesByDateRangeHandler(c *gin.Context) {
 		return
 	}
 
-	notes, err := dao.GetNotesByDateRange(userID.(int64), startDate, endDate)
+	startDate, err := models.ParseDate(c.Query("start"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+	endDate, err := models.ParseDate(c.Query("end"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+	if startDate.After(endDate.Time) {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "start must not be after end",
+		})
+		return
+	}
+
+	ownerID, ok := noteOwnerParam(c, userID.(int64))
+	if !ok {
+		return
+	}
+
+	var notes []models.Note
+	if ownerID == userID.(int64) {
+		notes, err = dao.GetNotesByDateRange(ownerID, startDate, endDate)
+	} else {
+		notes, err = sharedNotesInRange(c, userID.(int64), ownerID, startDate, endDate)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, Response{
 			Success: false,
@@ -371,7 +589,8 @@ func GetNotesByDateRangeHandler(c *gin.Context) {
 	})
 }
 
-// GetNotesByMonthHandler handles retrieving all notes for a user for a specific month
+// GetNotesByMonthHandler handles retrieving all notes for a user for a specific month.
+// With ?owner_id= it returns the notes of another user that are shared with the current user.
 func GetNotesByMonthHandler(c *gin.Context) {
 	// Get user ID from context or session
 	userID, exists := c.Get("userID")
@@ -383,18 +602,30 @@ func GetNotesByMonthHandler(c *gin.Context) {
 		return
 	}
 
-	year := c.Param("year")
-	month := c.Param("month")
+	year, yearErr := strconv.Atoi(c.Param("year"))
+	month, monthErr := strconv.Atoi(c.Param("month"))
 
-	if year == "" || month == "" {
+	if yearErr != nil || monthErr != nil || month < 1 || month > 12 {
 		c.JSON(http.StatusBadRequest, Response{
 			Success: false,
-			Message: "Both year and month parameters are required",
+			Message: "Both year and month parameters are required, month must be 1-12",
 		})
 		return
 	}
 
-	notes, err := dao.GetNotesByMonth(userID.(int64), year, month)
+	ownerID, ok := noteOwnerParam(c, userID.(int64))
+	if !ok {
+		return
+	}
+
+	var notes []models.Note
+	var err error
+	if ownerID == userID.(int64) {
+		notes, err = dao.GetNotesByMonth(ownerID, year, time.Month(month))
+	} else {
+		first := models.NewDate(year, time.Month(month), 1)
+		notes, err = sharedNotesInRange(c, userID.(int64), ownerID, first, models.Date{Time: first.AddDate(0, 1, -1)})
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, Response{
 			Success: false,
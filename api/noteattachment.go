@@ -0,0 +1,219 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"hope_backend/dao"
+	"hope_backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxNoteAttachments is the number of photos a note can have
+	maxNoteAttachments = 4
+	// noteAttachmentDir holds note photos under UploadsBasePath. It is not
+	// served by the static file server; see GetNoteAttachmentFileHandler.
+	noteAttachmentDir = "notes"
+)
+
+// AddNoteAttachmentHandler attaches a photo to a note. The photo is sent as
+// the multipart field "file" and goes through the same resize and thumbnail
+// steps as post images.
+func AddNoteAttachmentHandler(c *gin.Context) {
+	// Get user ID from context or session
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Message: "Unauthorized: User not authenticated",
+		})
+		return
+	}
+
+	noteID, ok := noteIDParam(c)
+	if !ok {
+		return
+	}
+	if ownedNote(c, noteID, userID.(int64)) == nil {
+		return
+	}
+
+	// Limit the upload size
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxUploadSize)
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "Error retrieving file: " + err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	fileExt := strings.ToLower(filepath.Ext(header.Filename))
+	if !isValidImageExt(fileExt) {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "Invalid file type. Allowed types: .jpg, .jpeg, .png, .gif",
+		})
+		return
+	}
+
+	filename, err := storeImage(file, fileExt, filepath.Join(UploadsBasePath, noteAttachmentDir), userID.(int64))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to save file: " + err.Error(),
+		})
+		return
+	}
+
+	attachment := &models.NoteAttachment{
+		NoteID:   noteID,
+		UserID:   userID.(int64),
+		FilePath: noteAttachmentDir + "/" + filename,
+	}
+	if err := dao.CreateNoteAttachment(attachment, maxNoteAttachments); err != nil {
+		removeUploadedFile(attachment.FilePath)
+
+		status := http.StatusInternalServerError
+		message := "Failed to attach photo: " + err.Error()
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			status, message = http.StatusNotFound, "Note not found"
+		case err.Error() == "too many attachments":
+			status, message = http.StatusBadRequest, "A note can have at most "+strconv.Itoa(maxNoteAttachments)+" photos"
+		}
+		c.JSON(status, Response{
+			Success: false,
+			Message: message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Message: "Photo attached successfully",
+		Data:    attachment,
+	})
+}
+
+// DeleteNoteAttachmentHandler removes a photo from a note and deletes its files
+func DeleteNoteAttachmentHandler(c *gin.Context) {
+	// Get user ID from context or session
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Message: "Unauthorized: User not authenticated",
+		})
+		return
+	}
+
+	noteID, ok := noteIDParam(c)
+	if !ok {
+		return
+	}
+	attachmentID, err := strconv.ParseInt(c.Param("attachmentId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "Invalid attachment ID",
+		})
+		return
+	}
+
+	attachment, err := dao.DeleteNoteAttachment(attachmentID, noteID, userID.(int64))
+	if err != nil {
+		status := http.StatusInternalServerError
+		message := "Failed to remove photo: " + err.Error()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			status, message = http.StatusNotFound, "Photo not found"
+		}
+		c.JSON(status, Response{
+			Success: false,
+			Message: message,
+		})
+		return
+	}
+	removeUploadedFile(attachment.FilePath)
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Photo removed successfully",
+	})
+}
+
+// GetNoteAttachmentFileHandler serves the file of a note photo to its owner
+// or to a user the note is shared with. ?size=thumbnail serves the thumbnail
+// instead of the full image.
+func GetNoteAttachmentFileHandler(c *gin.Context) {
+	// Get user ID from context or session
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Message: "Unauthorized: User not authenticated",
+		})
+		return
+	}
+
+	attachmentID, err := strconv.ParseInt(c.Param("attachmentId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "Invalid attachment ID",
+		})
+		return
+	}
+
+	attachment, err := dao.GetNoteAttachment(attachmentID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to retrieve photo: " + err.Error(),
+		})
+		return
+	}
+
+	// Photos the user may not see are reported as missing so IDs cannot be probed
+	notFound := func() {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Message: "Photo not found",
+		})
+	}
+	if attachment == nil {
+		notFound()
+		return
+	}
+
+	if attachment.UserID != userID.(int64) {
+		note, err := attachmentNote(attachment)
+		var share *models.NoteShare
+		if err == nil && note != nil {
+			share, err = noteShareFor(userID.(int64), note)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to check access: " + err.Error(),
+			})
+			return
+		}
+		if share == nil {
+			notFound()
+			return
+		}
+		recordSharedNoteAccess(c, share, []int{note.NoteID})
+	}
+
+	serveNoteAttachment(c, attachment)
+}
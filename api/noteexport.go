@@ -0,0 +1,359 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"hope_backend/config"
+	"hope_backend/dao"
+	"hope_backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jung-kurt/gofpdf"
+)
+
+const (
+	// maxNoteExportDays limits how much history a single export may cover
+	maxNoteExportDays = 366
+	// noteExportFontFamily is the name the CJK font is registered under in the PDF
+	noteExportFontFamily = "cjk"
+)
+
+// Supported export formats
+const (
+	ExportFormatMarkdown = "md"
+	ExportFormatPDF      = "pdf"
+	ExportFormatCSV      = "csv"
+)
+
+// noteExportFontPaths are tried in order when NOTE_EXPORT_FONT is not set.
+// The PDF library embeds TrueType outlines only, so OpenType/CFF fonts and
+// font collections (.otf, .ttc) cannot be used. No font ships with the
+// repository; see README.md for how to install one.
+var noteExportFontPaths = []string{
+	"fonts/NotoSansSC-Regular.ttf",
+	"/usr/share/fonts/truetype/droid/DroidSansFallbackFull.ttf",
+}
+
+// noteExportFont caches the font file, which is read on the first PDF export
+var noteExportFont struct {
+	once sync.Once
+	data []byte
+	err  error
+}
+
+// loadNoteExportFont returns the TrueType font used for PDF exports
+func loadNoteExportFont() ([]byte, error) {
+	noteExportFont.once.Do(func() {
+		paths := noteExportFontPaths
+		if path := os.Getenv("NOTE_EXPORT_FONT"); path != "" {
+			paths = []string{path}
+		}
+
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if err == nil {
+				noteExportFont.data = data
+				return
+			}
+		}
+		noteExportFont.err = errors.New("no CJK TrueType font found, set NOTE_EXPORT_FONT to a .ttf file")
+	})
+	return noteExportFont.data, noteExportFont.err
+}
+
+// MustLoadNoteExportFont loads the PDF export font and panics if none is
+// installed, so a missing font stops startup instead of failing exports
+func MustLoadNoteExportFont() {
+	if _, err := loadNoteExportFont(); err != nil {
+		panic("note export: " + err.Error())
+	}
+}
+
+// noteExport is what the renderers need to produce a document
+type noteExport struct {
+	PatientName string
+	From        models.Date
+	To          models.Date
+	Notes       []models.Note
+}
+
+// ExportNotesHandler returns the user's notes in a date range as a downloadable document.
+// Query parameters: from and to as "YYYY.M.D" or "YYYY-MM-DD" (default: the month up to
+// today in the user's timezone) and format as md, pdf or csv (default: md).
+func ExportNotesHandler(c *gin.Context) {
+	// Get user ID from context or session
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Message: "Unauthorized: User not authenticated",
+		})
+		return
+	}
+
+	format := c.DefaultQuery("format", ExportFormatMarkdown)
+	if format != ExportFormatMarkdown && format != ExportFormatPDF && format != ExportFormatCSV {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "Invalid format. Supported values: md, pdf, csv",
+		})
+		return
+	}
+
+	to := userToday(userID.(int64))
+	if value := c.Query("to"); value != "" {
+		parsed, err := models.ParseDate(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid to date: " + err.Error(),
+			})
+			return
+		}
+		to = parsed
+	}
+
+	from := models.Date{Time: to.AddDate(0, -1, 1)}
+	if value := c.Query("from"); value != "" {
+		parsed, err := models.ParseDate(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid from date: " + err.Error(),
+			})
+			return
+		}
+		from = parsed
+	}
+
+	if from.After(to.Time) {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "from must not be after to",
+		})
+		return
+	}
+	if to.Sub(from.Time) >= maxNoteExportDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: fmt.Sprintf("The export can cover at most %d days", maxNoteExportDays),
+		})
+		return
+	}
+
+	notes, err := dao.GetNotesByDateRange(userID.(int64), from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to retrieve notes: " + err.Error(),
+		})
+		return
+	}
+
+	export := noteExport{From: from, To: to, Notes: notes}
+	if profile, err := dao.NewUserProfileDAO(config.DB).GetByID(userID.(int64)); err == nil {
+		export.PatientName = profile.PatientName
+		if export.PatientName == "" {
+			export.PatientName = profile.UserNickname
+		}
+	}
+
+	var buf bytes.Buffer
+	var contentType string
+	switch format {
+	case ExportFormatPDF:
+		contentType = "application/pdf"
+		err = renderNotesPDF(&buf, export)
+	case ExportFormatCSV:
+		contentType = "text/csv; charset=utf-8"
+		err = renderNotesCSV(&buf, export)
+	default:
+		contentType = "text/markdown; charset=utf-8"
+		err = renderNotesMarkdown(&buf, export)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to export notes: " + err.Error(),
+		})
+		return
+	}
+
+	filename := fmt.Sprintf("hope-notes-%s-%s.%s",
+		from.Format("20060102"), to.Format("20060102"), format)
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// noteExportTitle is the document title shown in every format
+func noteExportTitle(export noteExport) string {
+	if export.PatientName == "" {
+		return "日记"
+	}
+	return export.PatientName + " 的日记"
+}
+
+// noteExportRange formats the exported date range
+func noteExportRange(export noteExport) string {
+	return export.From.String() + " – " + export.To.String()
+}
+
+// renderNotesMarkdown writes the notes as a Markdown document
+func renderNotesMarkdown(buf *bytes.Buffer, export noteExport) error {
+	fmt.Fprintf(buf, "# %s\n\n%s，共 %d 篇\n\n", noteExportTitle(export), noteExportRange(export), len(export.Notes))
+	for _, note := range export.Notes {
+		fmt.Fprintf(buf, "## %s\n\n%s%s\n\n", note.NoteDate, noteCheckInMarkdown(note), note.Content)
+	}
+	return nil
+}
+
+// renderNotesCSV writes one row per note, with a BOM so spreadsheet apps detect UTF-8
+func renderNotesCSV(buf *bytes.Buffer, export noteExport) error {
+	buf.WriteString("\ufeff")
+
+	w := csv.NewWriter(buf)
+	w.Write([]string{"note_date", "patient_mood", "caregiver_mood", "sleep_hours", "emotion_tags", "content", "created_at", "updated_at"})
+	for _, note := range export.Notes {
+		w.Write([]string{
+			note.NoteDate.Format(models.ISODateLayout),
+			optionalInt(note.PatientMood),
+			optionalInt(note.CaregiverMood),
+			optionalFloat(note.SleepHours),
+			strings.Join(note.EmotionTags, ";"),
+			note.Content,
+			time.Unix(note.CreatedAt, 0).UTC().Format(time.RFC3339),
+			time.Unix(note.UpdatedAt, 0).UTC().Format(time.RFC3339),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// renderNotesPDF writes a printable report: a check-in table followed by the notes.
+// The CJK font is embedded as a subset holding only the characters used.
+func renderNotesPDF(buf *bytes.Buffer, export noteExport) error {
+	font, err := loadNoteExportFont()
+	if err != nil {
+		return err
+	}
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8FontFromBytes(noteExportFontFamily, "", font)
+	pdf.SetTitle(pdfText(noteExportTitle(export)), true)
+	pdf.SetCreator("Hope", true)
+	pdf.SetMargins(15, 20, 15)
+	pdf.SetAutoPageBreak(true, 18)
+	pdf.AliasNbPages("{nb}")
+
+	pageWidth, _ := pdf.GetPageSize()
+	left, _, right, _ := pdf.GetMargins()
+	width := pageWidth - left - right
+
+	pdf.SetHeaderFunc(func() {
+		pdf.SetFont(noteExportFontFamily, "", 10)
+		pdf.SetTextColor(90, 90, 90)
+		pdf.CellFormat(width/2, 6, pdfText(noteExportTitle(export)), "", 0, "L", false, 0, "")
+		pdf.CellFormat(width/2, 6, noteExportRange(export), "", 1, "R", false, 0, "")
+		pdf.Line(left, pdf.GetY(), left+width, pdf.GetY())
+		pdf.Ln(4)
+		pdf.SetTextColor(0, 0, 0)
+	})
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont(noteExportFontFamily, "", 8)
+		pdf.SetTextColor(120, 120, 120)
+		pdf.CellFormat(0, 5, fmt.Sprintf("%d / {nb}", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+
+	pdf.AddPage()
+	pdf.SetFont(noteExportFontFamily, "", 16)
+	pdf.CellFormat(width, 9, pdfText(noteExportTitle(export)), "", 1, "L", false, 0, "")
+	pdf.SetFont(noteExportFontFamily, "", 10)
+	pdf.CellFormat(width, 6, fmt.Sprintf("%s，共 %d 篇", noteExportRange(export), len(export.Notes)), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	if len(export.Notes) > 0 {
+		// Check-in overview
+		columns := []struct {
+			title string
+			width float64
+		}{
+			{"日期", 28}, {"心情", 20}, {"照护者心情", 26}, {"睡眠(小时)", 26}, {"情绪", width - 100},
+		}
+		pdf.SetFillColor(235, 235, 235)
+		for _, col := range columns {
+			pdf.CellFormat(col.width, 7, col.title, "1", 0, "C", true, 0, "")
+		}
+		pdf.Ln(-1)
+		for _, note := range export.Notes {
+			tags := pdfText(strings.Join(note.EmotionTags, ", "))
+			for pdf.GetStringWidth(tags) > columns[4].width-2 {
+				runes := []rune(tags)
+				if len(runes) < 2 {
+					break
+				}
+				tags = string(runes[:len(runes)-2]) + "…"
+			}
+			pdf.CellFormat(columns[0].width, 7, note.NoteDate.String(), "1", 0, "C", false, 0, "")
+			pdf.CellFormat(columns[1].width, 7, optionalInt(note.PatientMood), "1", 0, "C", false, 0, "")
+			pdf.CellFormat(columns[2].width, 7, optionalInt(note.CaregiverMood), "1", 0, "C", false, 0, "")
+			pdf.CellFormat(columns[3].width, 7, optionalFloat(note.SleepHours), "1", 0, "C", false, 0, "")
+			pdf.CellFormat(columns[4].width, 7, tags, "1", 1, "L", false, 0, "")
+		}
+		pdf.Ln(6)
+	}
+
+	for _, note := range export.Notes {
+		pdf.SetFont(noteExportFontFamily, "", 12)
+		pdf.CellFormat(width, 8, note.NoteDate.String(), "B", 1, "L", false, 0, "")
+		pdf.Ln(1)
+
+		pdf.SetFont(noteExportFontFamily, "", 10)
+		if checkIn := strings.TrimSpace(noteCheckInMarkdown(note)); checkIn != "" {
+			pdf.SetTextColor(90, 90, 90)
+			pdf.MultiCell(width, 5, pdfText(strings.ReplaceAll(checkIn, "- ", "")), "", "L", false)
+			pdf.SetTextColor(0, 0, 0)
+			pdf.Ln(1)
+		}
+		pdf.MultiCell(width, 6, pdfText(note.Content), "", "L", false)
+		pdf.Ln(5)
+	}
+
+	return pdf.Output(buf)
+}
+
+// pdfText drops characters outside the Basic Multilingual Plane, such as
+// emoji, which the embedded font subset cannot represent
+func pdfText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r > 0xFFFF {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// optionalInt formats an optional value, empty when not set
+func optionalInt(value *int) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.Itoa(*value)
+}
+
+// optionalFloat formats an optional value with one decimal, empty when not set
+func optionalFloat(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*value, 'f', 1, 64)
+}
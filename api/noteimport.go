@@ -0,0 +1,422 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"hope_backend/dao"
+	"hope_backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// NoteImportInterval is how often the scheduler looks for new imports
+	NoteImportInterval = 5 * time.Second
+	// noteImportStaleAfter is how long a running import may go without progress
+	// before it is taken to be cut off by a restart and resumed
+	noteImportStaleAfter = 5 * time.Minute
+	// noteImportDir holds uploaded import files under UploadsBasePath until their job ends
+	noteImportDir = "imports"
+	// maxImportUploadSize limits the uploaded file; ZIP archives are unpacked up to maxImportBytes
+	maxImportUploadSize = 50 << 20
+	// maxImportErrors is how many failed entries a job lists; the rest are only counted
+	maxImportErrors = 100
+	// noteImportListLimit is how many past imports are listed
+	noteImportListLimit = 20
+)
+
+// What happened to an imported entry
+const (
+	importCreated = "created"
+	importMerged  = "merged"
+	importSkipped = "skipped"
+)
+
+// ImportNotesHandler starts importing journal entries from another app. The
+// file is sent as the multipart field "file": a Day One JSON export, a list of
+// notes as in a Hope data export, a CSV file, dated Markdown or text files, or
+// a ZIP of those. The form field on_conflict says what happens to entries whose
+// date already has a note: "skip" (default) keeps the note, "merge" appends the entry.
+// The import runs in the background; the response holds the job to poll for progress.
+func ImportNotesHandler(c *gin.Context) {
+	// Get user ID from context or session
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Message: "Unauthorized: User not authenticated",
+		})
+		return
+	}
+
+	// Limit the upload size
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportUploadSize)
+
+	onConflict := c.DefaultPostForm("on_conflict", models.ImportConflictSkip)
+	if onConflict != models.ImportConflictSkip && onConflict != models.ImportConflictMerge {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "Invalid on_conflict. Supported values: skip, merge",
+		})
+		return
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "Error retrieving file: " + err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	fileExt := strings.ToLower(filepath.Ext(header.Filename))
+	if !importFileExts[fileExt] {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "Invalid file type. Allowed types: .zip, .json, .csv, .md, .markdown, .txt",
+		})
+		return
+	}
+
+	active, err := dao.HasActiveNoteImport(userID.(int64))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to check imports: " + err.Error(),
+		})
+		return
+	}
+	if active {
+		c.JSON(http.StatusConflict, Response{
+			Success: false,
+			Message: "An import is already running, please wait until it has finished",
+		})
+		return
+	}
+
+	filename, err := storeImportFile(file, fileExt, userID.(int64))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to save file: " + err.Error(),
+		})
+		return
+	}
+
+	now := time.Now().Unix()
+	job := &models.NoteImportJob{
+		UserID:     userID.(int64),
+		FileName:   filepath.Base(header.Filename),
+		FilePath:   noteImportDir + "/" + filename,
+		OnConflict: onConflict,
+		Errors:     []models.NoteImportError{},
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := dao.CreateNoteImportJob(job); err != nil {
+		os.Remove(uploadFilePath(job.FilePath))
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to start import: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, Response{
+		Success: true,
+		Message: "Import started",
+		Data:    job,
+	})
+}
+
+// GetNoteImportHandler returns the progress of an import
+func GetNoteImportHandler(c *gin.Context) {
+	// Get user ID from context or session
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Message: "Unauthorized: User not authenticated",
+		})
+		return
+	}
+
+	jobID, err := strconv.ParseInt(c.Param("jobId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "Invalid import ID",
+		})
+		return
+	}
+
+	job, err := dao.GetNoteImportJob(jobID, userID.(int64))
+	if err != nil {
+		status := http.StatusInternalServerError
+		message := "Failed to retrieve import: " + err.Error()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			status, message = http.StatusNotFound, "Import not found"
+		}
+		c.JSON(status, Response{
+			Success: false,
+			Message: message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Import retrieved successfully",
+		Data:    job,
+	})
+}
+
+// ListNoteImportsHandler lists the user's latest imports, newest first
+func ListNoteImportsHandler(c *gin.Context) {
+	// Get user ID from context or session
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Message: "Unauthorized: User not authenticated",
+		})
+		return
+	}
+
+	jobs, err := dao.GetNoteImportJobs(userID.(int64), noteImportListLimit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to retrieve imports: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Imports retrieved successfully",
+		Data:    jobs,
+	})
+}
+
+// NoteImportJob returns the scheduler job that runs pending imports one after another
+func NoteImportJob() func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		for ctx.Err() == nil {
+			now := time.Now().Unix()
+			job, err := dao.ClaimNoteImportJob(now-int64(noteImportStaleAfter/time.Second), now)
+			if err != nil {
+				return err
+			}
+			if job == nil {
+				return nil
+			}
+			runNoteImport(ctx, job)
+		}
+		return nil
+	}
+}
+
+// runNoteImport reads the file of a job and writes its entries, saving
+// progress after each one. A job claimed again after a restart continues
+// after the entries it already processed, as the file is read in the same order.
+func runNoteImport(ctx context.Context, job *models.NoteImportJob) {
+	entries, readErrors, err := parseImportFile(uploadFilePath(job.FilePath), job.FileName, userLocation(job.UserID))
+	if err == nil && len(entries) > maxImportEntries {
+		err = fmt.Errorf("the file has entries for more than %d dates, please split it", maxImportEntries)
+	}
+	if err == nil && len(entries) == 0 && len(readErrors) == 0 {
+		err = errors.New("no entries found in the file")
+	}
+	if err != nil {
+		finishNoteImport(job, err)
+		return
+	}
+
+	job.Total = len(readErrors) + len(entries)
+	if job.Processed == 0 {
+		job.Processed = len(readErrors)
+		job.Failed = len(readErrors)
+		job.Errors = appendImportErrors(job.Errors, readErrors...)
+	}
+
+	for i := job.Processed - len(readErrors); i < len(entries); i++ {
+		if ctx.Err() != nil {
+			// Shutting down; the job is resumed once it is stale
+			return
+		}
+
+		entry := &entries[i]
+		switch outcome, err := importNoteEntry(job, entry); {
+		case err != nil:
+			job.Failed++
+			job.Errors = appendImportErrors(job.Errors, models.NoteImportError{
+				Source:  entry.Source,
+				Date:    entry.Date.String(),
+				Message: err.Error(),
+			})
+		case outcome == importMerged:
+			job.Merged++
+		case outcome == importSkipped:
+			job.Skipped++
+		default:
+			job.Created++
+		}
+
+		job.Processed++
+		job.UpdatedAt = time.Now().Unix()
+		if err := dao.SaveNoteImportJob(job); err != nil {
+			fmt.Printf("[Note Import] Failed to save progress of job %d: %v\n", job.JobID, err)
+			return
+		}
+	}
+
+	finishNoteImport(job, nil)
+}
+
+// importNoteEntry writes one entry: it creates a note, or resolves the
+// collision with the note of that date as the job says. It returns
+// importCreated, importMerged or importSkipped.
+func importNoteEntry(job *models.NoteImportJob, entry *importEntry) (string, error) {
+	entry.Content = strings.TrimSpace(entry.Content)
+	if entry.Content == "" {
+		return "", errors.New("the entry is empty")
+	}
+	if len([]rune(entry.Content)) > maxNoteContentRunes {
+		return "", fmt.Errorf("the entry is longer than %d characters", maxNoteContentRunes)
+	}
+	if err := entry.validate(); err != nil {
+		return "", err
+	}
+
+	existing, err := dao.GetNoteByUserAndDate(job.UserID, entry.Date)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+
+	now := time.Now().Unix()
+	if existing == nil {
+		note := &models.Note{
+			UserID:    job.UserID,
+			NoteDate:  entry.Date,
+			Content:   entry.Content,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if entry.CreatedAt > 0 && entry.CreatedAt < now {
+			note.CreatedAt = entry.CreatedAt
+		}
+		entry.apply(note)
+		if err := dao.CreateNote(note); err != nil {
+			return "", err
+		}
+		return importCreated, nil
+	}
+
+	if job.OnConflict != models.ImportConflictMerge {
+		return importSkipped, nil
+	}
+
+	// Merging the same file twice leaves the note as it is
+	merged := existing.Content
+	if !strings.Contains(merged, entry.Content) {
+		merged = joinNoteContent(merged, entry.Content)
+	}
+	if len([]rune(merged)) > maxNoteContentRunes {
+		return "", fmt.Errorf("merged with the existing note, it would be longer than %d characters", maxNoteContentRunes)
+	}
+
+	checkIn := mergeCheckIn(NoteCheckIn{
+		PatientMood:   existing.PatientMood,
+		CaregiverMood: existing.CaregiverMood,
+		SleepHours:    existing.SleepHours,
+		EmotionTags:   existing.EmotionTags,
+	}, entry.NoteCheckIn)
+	if err := checkIn.validate(); err != nil {
+		return "", err
+	}
+
+	version := existing.Version
+	existing.Content = merged
+	existing.UpdatedAt = now
+	checkIn.apply(existing)
+	if err := dao.UpdateNote(existing); err != nil {
+		return "", err
+	}
+	if existing.Version == version {
+		return importSkipped, nil
+	}
+	return importMerged, nil
+}
+
+// finishNoteImport marks a job completed, or failed if err is set, and removes its file
+func finishNoteImport(job *models.NoteImportJob, err error) {
+	now := time.Now().Unix()
+	job.Status = models.ImportStatusCompleted
+	if err != nil {
+		job.Status = models.ImportStatusFailed
+		job.Message = err.Error()
+	}
+	job.UpdatedAt = now
+	job.FinishedAt = now
+
+	if err := dao.SaveNoteImportJob(job); err != nil {
+		fmt.Printf("[Note Import] Failed to finish job %d: %v\n", job.JobID, err)
+		return
+	}
+	if err := os.Remove(uploadFilePath(job.FilePath)); err != nil {
+		fmt.Printf("Info: Could not remove import file %s: %v\n", job.FilePath, err)
+	}
+
+	fmt.Printf("[Note Import] Job %d for user %d %s: %d created, %d merged, %d skipped, %d failed\n",
+		job.JobID, job.UserID, job.Status, job.Created, job.Merged, job.Skipped, job.Failed)
+}
+
+// appendImportErrors adds entry errors to a job, keeping at most maxImportErrors
+func appendImportErrors(errs []models.NoteImportError, more ...models.NoteImportError) []models.NoteImportError {
+	for _, e := range more {
+		if len(errs) >= maxImportErrors {
+			break
+		}
+		errs = append(errs, e)
+	}
+	return errs
+}
+
+// storeImportFile saves an uploaded import file and returns its name in noteImportDir
+func storeImportFile(file io.Reader, fileExt string, userID int64) (string, error) {
+	uploadDir := filepath.Join(UploadsBasePath, noteImportDir)
+	if err := os.MkdirAll(uploadDir, 0755); err != nil {
+		return "", err
+	}
+
+	filename := fmt.Sprintf("%d-%s%s", userID, uuid.New().String(), fileExt)
+	dst, err := os.Create(filepath.Join(uploadDir, filename))
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := io.Copy(dst, file); err != nil {
+		dst.Close()
+		os.Remove(dst.Name())
+		return "", err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(dst.Name())
+		return "", err
+	}
+	return filename, nil
+}
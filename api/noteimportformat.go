@@ -0,0 +1,539 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"hope_backend/models"
+)
+
+const (
+	// maxImportBytes limits how much text is read from one import, including everything unpacked from a ZIP
+	maxImportBytes = 100 << 20
+	// maxImportEntries limits the number of dates one import can write
+	maxImportEntries = 5000
+)
+
+// importFileExts are the files an import accepts; inside a ZIP other files, such as photos, are ignored
+var importFileExts = map[string]bool{
+	".zip": true, ".json": true, ".csv": true, ".md": true, ".markdown": true, ".txt": true,
+}
+
+var (
+	// importDatePattern finds a date such as 2023-01-18, 2023.1.18, 2023/01/18 or 2023年1月18日
+	importDatePattern = regexp.MustCompile(`(\d{4})\s*[-./年]\s*(\d{1,2})\s*[-./月]\s*(\d{1,2})`)
+	// importHeadingPattern matches a Markdown heading of level 1 to 3
+	importHeadingPattern = regexp.MustCompile(`^#{1,3}\s+(.+?)\s*#*\s*$`)
+	// dayOneMomentPattern matches the photo placeholders Day One puts into the entry text
+	dayOneMomentPattern = regexp.MustCompile(`!\[[^\]]*\]\(dayone-moment:[^)]*\)`)
+
+	// The check-in lines written by noteCheckInMarkdown
+	importPatientMoodPattern   = regexp.MustCompile(`^- 心情: (\d+)/10$`)
+	importCaregiverMoodPattern = regexp.MustCompile(`^- 照护者心情: (\d+)/10$`)
+	importSleepPattern         = regexp.MustCompile(`^- 睡眠: ([\d.]+) 小时$`)
+	importEmotionsPattern      = regexp.MustCompile(`^- 情绪: (.+)$`)
+)
+
+// importEntry is a journal entry read from an import file
+type importEntry struct {
+	Source    string
+	Date      models.Date
+	Content   string
+	CreatedAt int64 // Unix seconds, 0 if the file does not say
+	NoteCheckIn
+}
+
+// importError reports an entry of a file that could not be read
+func importError(source string, format string, args ...interface{}) models.NoteImportError {
+	return models.NoteImportError{Source: source, Message: fmt.Sprintf(format, args...)}
+}
+
+// parseImportFile reads the entries of an uploaded file and combines entries
+// of the same date, ordered by date. Entries that cannot be read are returned
+// as import errors; an error means the file could not be read at all.
+// Times without a timezone are taken to be in loc.
+func parseImportFile(filePath, name string, loc *time.Location) ([]importEntry, []models.NoteImportError, error) {
+	var entries []importEntry
+	var readErrors []models.NoteImportError
+	var err error
+
+	if strings.ToLower(filepath.Ext(name)) == ".zip" {
+		entries, readErrors, err = parseImportZip(filePath, loc)
+	} else {
+		var data []byte
+		data, err = readImportFile(filePath)
+		if err == nil {
+			entries, readErrors, err = parseImportData(name, data, loc)
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return groupImportEntries(entries), readErrors, nil
+}
+
+// readImportFile reads an uploaded file up to maxImportBytes
+func readImportFile(filePath string) ([]byte, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxImportBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImportBytes {
+		return nil, fmt.Errorf("the file is larger than %d MB", maxImportBytes>>20)
+	}
+	return data, nil
+}
+
+// parseImportZip reads every supported file in a ZIP archive. A Hope data
+// export is recognised by its profile.json and only its notes.json is read,
+// as the other files hold revisions and messages, not journal entries.
+func parseImportZip(filePath string, loc *time.Location) ([]importEntry, []models.NoteImportError, error) {
+	zr, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("the file is not a valid ZIP archive: %v", err)
+	}
+	defer zr.Close()
+
+	var files []*zip.File
+	names := make(map[string]bool)
+	for _, f := range zr.File {
+		base := path.Base(f.Name)
+		ext := strings.ToLower(path.Ext(base))
+		if f.FileInfo().IsDir() || strings.HasPrefix(f.Name, "__MACOSX/") || strings.HasPrefix(base, ".") ||
+			ext == ".zip" || !importFileExts[ext] {
+			continue
+		}
+		files = append(files, f)
+		names[f.Name] = true
+	}
+	if names["profile.json"] && names["notes.json"] {
+		for _, f := range files {
+			if f.Name == "notes.json" {
+				files = []*zip.File{f}
+				break
+			}
+		}
+	}
+	if len(files) == 0 {
+		return nil, nil, errors.New("the archive holds no JSON, CSV or Markdown files")
+	}
+
+	var entries []importEntry
+	var readErrors []models.NoteImportError
+	remaining := int64(maxImportBytes)
+	for _, f := range files {
+		if f.UncompressedSize64 > uint64(remaining) {
+			return nil, nil, fmt.Errorf("the archive unpacks to more than %d MB", maxImportBytes>>20)
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			readErrors = append(readErrors, importError(f.Name, "could not open file: %v", err))
+			continue
+		}
+		// The size in the header can lie, so the read is limited as well
+		data, err := io.ReadAll(io.LimitReader(rc, remaining+1))
+		rc.Close()
+		if err != nil {
+			readErrors = append(readErrors, importError(f.Name, "could not read file: %v", err))
+			continue
+		}
+		if int64(len(data)) > remaining {
+			return nil, nil, fmt.Errorf("the archive unpacks to more than %d MB", maxImportBytes>>20)
+		}
+		remaining -= int64(len(data))
+
+		fileEntries, fileErrors, err := parseImportData(f.Name, data, loc)
+		if err != nil {
+			readErrors = append(readErrors, importError(f.Name, "%v", err))
+			continue
+		}
+		entries = append(entries, fileEntries...)
+		readErrors = append(readErrors, fileErrors...)
+	}
+	return entries, readErrors, nil
+}
+
+// parseImportData reads the entries of one file, choosing the format by its extension
+func parseImportData(name string, data []byte, loc *time.Location) ([]importEntry, []models.NoteImportError, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		return parseImportJSON(name, data, loc)
+	case ".csv":
+		return parseImportCSV(name, data)
+	default:
+		return parseImportMarkdown(name, string(data))
+	}
+}
+
+// dayOneExport is the part of a Day One JSON export that is imported
+type dayOneExport struct {
+	Entries []struct {
+		CreationDate string `json:"creationDate"` // RFC 3339 in UTC
+		TimeZone     string `json:"timeZone"`     // Where the entry was written, e.g. "Asia/Shanghai"
+		Text         string `json:"text"`
+	} `json:"entries"`
+}
+
+// hopeNoteExport is a note in notes.json of a Hope data export
+type hopeNoteExport struct {
+	NoteDate  string `json:"note_date"`
+	Content   string `json:"content"`
+	CreatedAt int64  `json:"created_at"`
+	DeletedAt int64  `json:"deleted_at"`
+	NoteCheckIn
+}
+
+// parseImportJSON reads a Day One export, an object with an entries array,
+// or a list of notes as in notes.json of a Hope data export
+func parseImportJSON(name string, data []byte, loc *time.Location) ([]importEntry, []models.NoteImportError, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		return parseHopeNotesJSON(name, data)
+	}
+
+	var export dayOneExport
+	if err := json.Unmarshal(data, &export); err != nil || export.Entries == nil {
+		return nil, nil, errors.New("unrecognised JSON, expected a Day One export or a list of notes")
+	}
+
+	var entries []importEntry
+	var readErrors []models.NoteImportError
+	for i, item := range export.Entries {
+		source := fmt.Sprintf("%s entry %d", name, i+1)
+
+		created, err := time.Parse(time.RFC3339, item.CreationDate)
+		if err != nil {
+			readErrors = append(readErrors, importError(source, "invalid creationDate %q", item.CreationDate))
+			continue
+		}
+		// The day is the one where the entry was written, as Day One shows it
+		entryLoc := loc
+		if item.TimeZone != "" {
+			if tz, err := time.LoadLocation(item.TimeZone); err == nil {
+				entryLoc = tz
+			}
+		}
+
+		entries = append(entries, importEntry{
+			Source:    source,
+			Date:      models.DateOf(created.In(entryLoc)),
+			Content:   strings.TrimSpace(dayOneMomentPattern.ReplaceAllString(item.Text, "")),
+			CreatedAt: created.Unix(),
+		})
+	}
+	return entries, readErrors, nil
+}
+
+// parseHopeNotesJSON reads a list of notes; notes that were in the trash are left out
+func parseHopeNotesJSON(name string, data []byte) ([]importEntry, []models.NoteImportError, error) {
+	var notes []hopeNoteExport
+	if err := json.Unmarshal(data, &notes); err != nil {
+		return nil, nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+
+	var entries []importEntry
+	var readErrors []models.NoteImportError
+	for i, note := range notes {
+		if note.DeletedAt > 0 {
+			continue
+		}
+		source := fmt.Sprintf("%s entry %d", name, i+1)
+
+		date, err := models.ParseDate(note.NoteDate)
+		if err != nil {
+			readErrors = append(readErrors, importError(source, "%v", err))
+			continue
+		}
+		entries = append(entries, importEntry{
+			Source:      source,
+			Date:        date,
+			Content:     note.Content,
+			CreatedAt:   note.CreatedAt,
+			NoteCheckIn: note.NoteCheckIn,
+		})
+	}
+	return entries, readErrors, nil
+}
+
+// parseImportCSV reads a CSV file with a header row. It needs a date column
+// (note_date or date) and a content column (content, text or entry); the
+// check-in columns of a Hope CSV export are read if present.
+func parseImportCSV(name string, data []byte) ([]importEntry, []models.NoteImportError, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not read the CSV header: %v", err)
+	}
+	columns := make(map[string]int)
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if _, exists := columns[column]; !exists {
+			columns[column] = i
+		}
+	}
+	column := func(names ...string) int {
+		for _, name := range names {
+			if i, ok := columns[name]; ok {
+				return i
+			}
+		}
+		return -1
+	}
+
+	dateColumn := column("note_date", "date")
+	contentColumn := column("content", "text", "entry")
+	if dateColumn < 0 || contentColumn < 0 {
+		return nil, nil, errors.New("the CSV needs a note_date or date column and a content column")
+	}
+	patientMoodColumn := column("patient_mood")
+	caregiverMoodColumn := column("caregiver_mood")
+	sleepColumn := column("sleep_hours")
+	tagsColumn := column("emotion_tags")
+
+	var entries []importEntry
+	var readErrors []models.NoteImportError
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		line, _ := r.FieldPos(0)
+		source := fmt.Sprintf("%s line %d", name, line)
+		if err != nil {
+			readErrors = append(readErrors, importError(source, "%v", err))
+			continue
+		}
+
+		field := func(i int) string {
+			if i < 0 || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		date, ok := parseImportDate(field(dateColumn))
+		if !ok {
+			readErrors = append(readErrors, importError(source, "invalid date %q", field(dateColumn)))
+			continue
+		}
+
+		entry := importEntry{Source: source, Date: date, Content: field(contentColumn)}
+		if entry.PatientMood, err = optionalImportInt(field(patientMoodColumn)); err != nil {
+			readErrors = append(readErrors, importError(source, "invalid patient_mood %q", field(patientMoodColumn)))
+			continue
+		}
+		if entry.CaregiverMood, err = optionalImportInt(field(caregiverMoodColumn)); err != nil {
+			readErrors = append(readErrors, importError(source, "invalid caregiver_mood %q", field(caregiverMoodColumn)))
+			continue
+		}
+		if value := field(sleepColumn); value != "" {
+			hours, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				readErrors = append(readErrors, importError(source, "invalid sleep_hours %q", value))
+				continue
+			}
+			entry.SleepHours = &hours
+		}
+		if value := field(tagsColumn); value != "" {
+			entry.EmotionTags = strings.FieldsFunc(value, func(r rune) bool { return r == ';' || r == ',' })
+		}
+
+		entries = append(entries, entry)
+	}
+	return entries, readErrors, nil
+}
+
+// parseImportMarkdown reads a Markdown or text file. Headings that start
+// with a date, as in a Hope Markdown export, begin a new entry; a file without
+// them is one entry dated by its file name, e.g. "2023-01-18.md".
+func parseImportMarkdown(name, text string) ([]importEntry, []models.NoteImportError, error) {
+	var entries []importEntry
+	var lines []string
+	flush := func() {
+		if len(entries) > 0 {
+			entry := &entries[len(entries)-1]
+			entry.NoteCheckIn, lines = parseCheckInMarkdown(lines)
+			entry.Content = strings.TrimSpace(strings.Join(lines, "\n"))
+		}
+		lines = nil
+	}
+
+	for i, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if match := importHeadingPattern.FindStringSubmatch(line); match != nil {
+			if loc := importDatePattern.FindStringIndex(match[1]); loc != nil && loc[0] == 0 {
+				if date, ok := parseImportDate(match[1]); ok {
+					flush()
+					entries = append(entries, importEntry{Source: fmt.Sprintf("%s line %d", name, i+1), Date: date})
+					continue
+				}
+			}
+		}
+		lines = append(lines, line)
+	}
+	if len(entries) > 0 {
+		flush()
+		return entries, nil, nil
+	}
+
+	date, ok := parseImportDate(path.Base(filepath.ToSlash(name)))
+	if !ok {
+		return nil, nil, errors.New("no date found in the file name or in headings")
+	}
+	entry := importEntry{Source: name, Date: date}
+	entry.NoteCheckIn, lines = parseCheckInMarkdown(strings.Split(strings.TrimSpace(text), "\n"))
+	entry.Content = strings.TrimSpace(strings.Join(lines, "\n"))
+	return []importEntry{entry}, nil, nil
+}
+
+// parseCheckInMarkdown reads the check-in lines noteCheckInMarkdown writes at
+// the start of an entry and returns the remaining lines
+func parseCheckInMarkdown(lines []string) (NoteCheckIn, []string) {
+	var checkIn NoteCheckIn
+
+	// Skip blank lines between the heading and the check-in
+	start := 0
+	for start < len(lines) && strings.TrimSpace(lines[start]) == "" {
+		start++
+	}
+
+	i := start
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if match := importPatientMoodPattern.FindStringSubmatch(line); match != nil {
+			mood, _ := strconv.Atoi(match[1])
+			checkIn.PatientMood = &mood
+		} else if match := importCaregiverMoodPattern.FindStringSubmatch(line); match != nil {
+			mood, _ := strconv.Atoi(match[1])
+			checkIn.CaregiverMood = &mood
+		} else if match := importSleepPattern.FindStringSubmatch(line); match != nil {
+			hours, err := strconv.ParseFloat(match[1], 64)
+			if err != nil {
+				break
+			}
+			checkIn.SleepHours = &hours
+		} else if match := importEmotionsPattern.FindStringSubmatch(line); match != nil {
+			checkIn.EmotionTags = strings.Split(match[1], ", ")
+		} else {
+			break
+		}
+	}
+	if i == start {
+		return NoteCheckIn{}, lines
+	}
+	return checkIn, lines[i:]
+}
+
+// parseImportDate finds a date in a string such as "2023-01-18", "2023.1.18" or "2023年1月18日 星期三"
+func parseImportDate(value string) (models.Date, bool) {
+	if date, err := models.ParseDate(value); err == nil {
+		return date, true
+	}
+
+	match := importDatePattern.FindStringSubmatch(value)
+	if match == nil {
+		return models.Date{}, false
+	}
+	year, _ := strconv.Atoi(match[1])
+	month, _ := strconv.Atoi(match[2])
+	day, _ := strconv.Atoi(match[3])
+
+	// ParseDate rejects days that do not exist, such as 2023-02-30
+	date, err := models.ParseDate(fmt.Sprintf("%04d-%02d-%02d", year, month, day))
+	return date, err == nil
+}
+
+// optionalImportInt parses an optional integer column
+func optionalImportInt(value string) (*int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// groupImportEntries combines entries of the same date into one, as a user
+// has one note per date, and orders them by date. Content is joined in file
+// order; of the check-in fields the first value found is kept.
+func groupImportEntries(entries []importEntry) []importEntry {
+	var grouped []importEntry
+	byDate := make(map[models.Date]int)
+	for _, entry := range entries {
+		i, exists := byDate[entry.Date]
+		if !exists {
+			byDate[entry.Date] = len(grouped)
+			grouped = append(grouped, entry)
+			continue
+		}
+
+		g := &grouped[i]
+		g.Source += ", " + entry.Source
+		g.Content = joinNoteContent(g.Content, entry.Content)
+		if entry.CreatedAt > 0 && (g.CreatedAt == 0 || entry.CreatedAt < g.CreatedAt) {
+			g.CreatedAt = entry.CreatedAt
+		}
+		g.NoteCheckIn = mergeCheckIn(g.NoteCheckIn, entry.NoteCheckIn)
+	}
+
+	sort.SliceStable(grouped, func(i, j int) bool {
+		return grouped[i].Date.Before(grouped[j].Date.Time)
+	})
+	return grouped
+}
+
+// joinNoteContent appends text to note content, separated by a blank line
+func joinNoteContent(content, text string) string {
+	switch {
+	case strings.TrimSpace(text) == "":
+		return content
+	case strings.TrimSpace(content) == "":
+		return text
+	default:
+		return content + "\n\n" + text
+	}
+}
+
+// mergeCheckIn fills the check-in fields a has not logged from b and combines the emotion tags
+func mergeCheckIn(a, b NoteCheckIn) NoteCheckIn {
+	if a.PatientMood == nil {
+		a.PatientMood = b.PatientMood
+	}
+	if a.CaregiverMood == nil {
+		a.CaregiverMood = b.CaregiverMood
+	}
+	if a.SleepHours == nil {
+		a.SleepHours = b.SleepHours
+	}
+	if len(b.EmotionTags) > 0 {
+		a.EmotionTags = append(append([]string{}, a.EmotionTags...), b.EmotionTags...)
+	}
+	return a
+}
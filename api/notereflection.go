@@ -0,0 +1,180 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"hope_backend/dao"
+	"hope_backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// reflectionSystemPrompt asks for a short, gentle reflection rather than a chat reply
+const reflectionSystemPrompt = "你是一位温和的陪伴者，正在阅读一位照顾抑郁症亲人的人写下的日记。" +
+	"请用不超过200字回复：先真诚地认可和肯定他们的感受与付出，再给出一个具体、今天就能做到的小建议。" +
+	"不要诊断，不要说教，不要列清单，也不要复述日记内容。"
+
+// ReflectNoteRequest is the optional request body of ReflectNoteHandler
+type ReflectNoteRequest struct {
+	IncludeMood bool `json:"include_mood"` // Also send the moods, sleep and emotion tags of the note
+}
+
+// ReflectNoteHandler asks the AI providers for a reflection on a note and
+// stores it with the note. Notes that match crisis screening get the crisis
+// response instead, and their content is not sent to any provider.
+func ReflectNoteHandler(c *gin.Context) {
+	// Get user ID from context or session
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Message: "Unauthorized: User not authenticated",
+		})
+		return
+	}
+
+	noteID, ok := noteIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req ReflectNoteRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	note := ownedNote(c, noteID, userID.(int64))
+	if note == nil {
+		return
+	}
+	if strings.TrimSpace(note.Content) == "" {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "The note is empty",
+		})
+		return
+	}
+
+	reflection := &models.NoteReflection{
+		NoteID:       note.NoteID,
+		UserID:       note.UserID,
+		IncludedMood: req.IncludeMood,
+		NoteVersion:  note.Version,
+	}
+
+	if screenCrisis(note.Content) {
+		fmt.Printf("[Crisis Screening] Note %d of user %d matched, sending crisis response\n", note.NoteID, note.UserID)
+		reflection.Content = crisisResponse
+		reflection.Crisis = true
+	} else {
+		// Reflections share the chat rate limit, both call the same providers
+		if !canMakeAPICall(userID.(int64)) {
+			c.JSON(http.StatusTooManyRequests, Response{
+				Success: false,
+				Message: "Please wait a moment before asking again",
+			})
+			return
+		}
+		recordAPICall(userID.(int64))
+
+		content, provider, model, err := completeWithFallback(reflectionSystemPrompt, reflectionPrompt(note, req.IncludeMood))
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, Response{
+				Success: false,
+				Message: aiFallbackResponse,
+			})
+			return
+		}
+		reflection.Content = content
+		reflection.Provider = string(provider)
+		reflection.Model = model
+	}
+
+	reflection.CreatedAt = time.Now().Unix()
+	if err := dao.SaveNoteReflection(reflection); err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to save reflection: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Reflection created successfully",
+		Data:    reflection,
+	})
+}
+
+// GetNoteReflectionHandler returns the stored reflection of a note
+func GetNoteReflectionHandler(c *gin.Context) {
+	// Get user ID from context or session
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Message: "Unauthorized: User not authenticated",
+		})
+		return
+	}
+
+	noteID, ok := noteIDParam(c)
+	if !ok {
+		return
+	}
+
+	reflection, err := dao.GetNoteReflection(noteID, userID.(int64))
+	if err != nil {
+		status := http.StatusInternalServerError
+		message := "Failed to retrieve reflection: " + err.Error()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			status, message = http.StatusNotFound, "Reflection not found"
+		}
+		c.JSON(status, Response{
+			Success: false,
+			Message: message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Reflection retrieved successfully",
+		Data:    reflection,
+	})
+}
+
+// reflectionPrompt builds the user prompt for a note, with its check-in fields if asked for
+func reflectionPrompt(note *models.Note, includeMood bool) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "日记日期：%s。\n", note.NoteDate)
+
+	if includeMood {
+		if note.PatientMood != nil {
+			fmt.Fprintf(&b, "患者心情：%d/10。\n", *note.PatientMood)
+		}
+		if note.CaregiverMood != nil {
+			fmt.Fprintf(&b, "照顾者心情：%d/10。\n", *note.CaregiverMood)
+		}
+		if note.SleepHours != nil {
+			fmt.Fprintf(&b, "患者睡眠：%.1f 小时。\n", *note.SleepHours)
+		}
+		if len(note.EmotionTags) > 0 {
+			fmt.Fprintf(&b, "情绪标签：%s。\n", strings.Join(note.EmotionTags, "、"))
+		}
+	}
+
+	fmt.Fprintf(&b, "日记内容：%s", note.Content)
+	return b.String()
+}
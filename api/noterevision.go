@@ -0,0 +1,548 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"hope_backend/dao"
+	"hope_backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// noteTrashRetention is how long deleted notes can be restored
+	noteTrashRetention = 30 * 24 * time.Hour
+	// NoteTrashPurgeInterval is how often expired notes are removed from the trash
+	NoteTrashPurgeInterval = time.Hour
+	// maxDiffCells bounds the memory of a diff: the changed parts of two texts
+	// are compared character by character up to this many table cells, then
+	// line by line, and beyond that not at all
+	maxDiffCells = 1000 * 1000
+)
+
+// Diff operations
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// DiffOp is one run of unchanged, inserted or deleted text
+type DiffOp struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// FieldChange describes a check-in field or the prompt differing between two versions
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// NoteDiff is the response of DiffNoteRevisionsHandler
+type NoteDiff struct {
+	From    string        `json:"from"` // Revision ID or "current"
+	To      string        `json:"to"`
+	Content []DiffOp      `json:"content"`
+	Fields  []FieldChange `json:"fields"`
+}
+
+// trashCutoff returns the oldest deleted_at that can still be restored
+func trashCutoff() int64 {
+	return time.Now().Add(-noteTrashRetention).Unix()
+}
+
+// noteIDParam parses the :id parameter; it responds and returns false if invalid
+func noteIDParam(c *gin.Context) (int, bool) {
+	noteID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "Invalid note ID",
+		})
+		return 0, false
+	}
+	return noteID, true
+}
+
+// ownedNote loads a live note of the current user; it responds and returns nil if unavailable
+func ownedNote(c *gin.Context, noteID int, userID int64) *models.Note {
+	note, err := dao.GetNoteByID(noteID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		message := "Failed to retrieve note: " + err.Error()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			status, message = http.StatusNotFound, "Note not found"
+		}
+		c.JSON(status, Response{
+			Success: false,
+			Message: message,
+		})
+		return nil
+	}
+
+	if note.UserID != userID {
+		c.JSON(http.StatusForbidden, Response{
+			Success: false,
+			Message: "You don't have permission to access this note",
+		})
+		return nil
+	}
+	return note
+}
+
+// ListNoteRevisionsHandler returns the previous versions of a note, newest first
+func ListNoteRevisionsHandler(c *gin.Context) {
+	// Get user ID from context or session
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Message: "Unauthorized: User not authenticated",
+		})
+		return
+	}
+
+	noteID, ok := noteIDParam(c)
+	if !ok {
+		return
+	}
+	if ownedNote(c, noteID, userID.(int64)) == nil {
+		return
+	}
+
+	revisions, err := dao.GetNoteRevisions(noteID, userID.(int64))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to retrieve revisions: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Revisions retrieved successfully",
+		Data:    revisions,
+	})
+}
+
+// DiffNoteRevisionsHandler compares two versions of a note.
+// Query parameters from and to are revision IDs or "current" (the default for to).
+func DiffNoteRevisionsHandler(c *gin.Context) {
+	// Get user ID from context or session
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Message: "Unauthorized: User not authenticated",
+		})
+		return
+	}
+
+	noteID, ok := noteIDParam(c)
+	if !ok {
+		return
+	}
+	note := ownedNote(c, noteID, userID.(int64))
+	if note == nil {
+		return
+	}
+
+	fromParam := c.Query("from")
+	toParam := c.DefaultQuery("to", "current")
+	if fromParam == "" {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "from parameter is required",
+		})
+		return
+	}
+
+	// Resolve both sides to a revision-shaped snapshot
+	resolve := func(param string) (*models.NoteRevision, error) {
+		if param == "current" {
+			return models.NewNoteRevision(note, note.UpdatedAt), nil
+		}
+		revisionID, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid revision ID %q", param)
+		}
+		return dao.GetNoteRevision(noteID, revisionID, userID.(int64))
+	}
+
+	from, err := resolve(fromParam)
+	if err == nil {
+		var to *models.NoteRevision
+		if to, err = resolve(toParam); err == nil {
+			content, ok := diffText(from.Content, to.Content)
+			if !ok {
+				c.JSON(http.StatusRequestEntityTooLarge, Response{
+					Success: false,
+					Message: "The changes are too large to diff",
+				})
+				return
+			}
+
+			c.JSON(http.StatusOK, Response{
+				Success: true,
+				Message: "Diff computed successfully",
+				Data: NoteDiff{
+					From:    fromParam,
+					To:      toParam,
+					Content: content,
+					Fields:  diffCheckIn(from, to),
+				},
+			})
+			return
+		}
+	}
+
+	status := http.StatusBadRequest
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		status, err = http.StatusNotFound, errors.New("revision not found")
+	}
+	c.JSON(status, Response{
+		Success: false,
+		Message: err.Error(),
+	})
+}
+
+// RestoreNoteRevisionHandler makes an old revision the current version of a note.
+// The version it replaces is saved as a new revision, so a restore can be undone.
+func RestoreNoteRevisionHandler(c *gin.Context) {
+	// Get user ID from context or session
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Message: "Unauthorized: User not authenticated",
+		})
+		return
+	}
+
+	noteID, ok := noteIDParam(c)
+	if !ok {
+		return
+	}
+	revisionID, err := strconv.ParseInt(c.Param("revisionId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "Invalid revision ID",
+		})
+		return
+	}
+
+	note := ownedNote(c, noteID, userID.(int64))
+	if note == nil {
+		return
+	}
+
+	revision, err := dao.GetNoteRevision(noteID, revisionID, userID.(int64))
+	if err != nil {
+		status := http.StatusInternalServerError
+		message := "Failed to retrieve revision: " + err.Error()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			status, message = http.StatusNotFound, "Revision not found"
+		}
+		c.JSON(status, Response{
+			Success: false,
+			Message: message,
+		})
+		return
+	}
+
+	note.Content = revision.Content
+	note.PromptID = revision.PromptID
+	note.PatientMood = revision.PatientMood
+	note.CaregiverMood = revision.CaregiverMood
+	note.SleepHours = revision.SleepHours
+	note.EmotionTags = revision.EmotionTags
+	note.UpdatedAt = time.Now().Unix()
+	if err := dao.UpdateNote(note); err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to restore revision: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Revision restored successfully",
+		Data:    note,
+	})
+}
+
+// ListTrashedNotesHandler returns the notes deleted within the restore window
+func ListTrashedNotesHandler(c *gin.Context) {
+	// Get user ID from context or session
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Message: "Unauthorized: User not authenticated",
+		})
+		return
+	}
+
+	notes, err := dao.GetTrashedNotes(userID.(int64), trashCutoff())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to retrieve trash: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Trash retrieved successfully",
+		Data:    notes,
+	})
+}
+
+// RestoreTrashedNoteHandler moves a deleted note back out of the trash
+func RestoreTrashedNoteHandler(c *gin.Context) {
+	// Get user ID from context or session
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Message: "Unauthorized: User not authenticated",
+		})
+		return
+	}
+
+	noteID, ok := noteIDParam(c)
+	if !ok {
+		return
+	}
+
+	note, err := dao.RestoreNote(noteID, userID.(int64), trashCutoff())
+	if err != nil {
+		status := http.StatusInternalServerError
+		message := "Failed to restore note: " + err.Error()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			status, message = http.StatusNotFound, "Note not found in trash"
+		} else if err.Error() == "a note already exists for this date" {
+			status, message = http.StatusConflict, "A note already exists for this date"
+		}
+		c.JSON(status, Response{
+			Success: false,
+			Message: message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Note restored successfully",
+		Data:    note,
+	})
+}
+
+// PurgeTrashedNoteHandler permanently deletes a note in the trash
+func PurgeTrashedNoteHandler(c *gin.Context) {
+	// Get user ID from context or session
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Message: "Unauthorized: User not authenticated",
+		})
+		return
+	}
+
+	noteID, ok := noteIDParam(c)
+	if !ok {
+		return
+	}
+
+	files, err := dao.PurgeNote(noteID, userID.(int64))
+	if err != nil {
+		status := http.StatusInternalServerError
+		message := "Failed to delete note: " + err.Error()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			status, message = http.StatusNotFound, "Note not found in trash"
+		}
+		c.JSON(status, Response{
+			Success: false,
+			Message: message,
+		})
+		return
+	}
+	for _, file := range files {
+		removeUploadedFile(file)
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Note deleted permanently",
+	})
+}
+
+// NoteTrashJob returns the scheduled job that purges notes that have been in the trash longer than the restore window
+func NoteTrashJob() func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		purged, files, err := dao.PurgeExpiredTrash(trashCutoff())
+		// Notes purged before a failure are gone, so their files are removed either way
+		for _, file := range files {
+			removeUploadedFile(file)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to purge expired notes: %w", err)
+		}
+		if purged > 0 {
+			fmt.Printf("[Note Trash] Purged %d expired notes\n", purged)
+		}
+		return nil
+	}
+}
+
+// diffCheckIn lists the prompt and check-in fields that differ between two versions
+func diffCheckIn(from, to *models.NoteRevision) []FieldChange {
+	changes := []FieldChange{}
+	add := func(field string, a, b interface{}) {
+		if fmt.Sprint(a) != fmt.Sprint(b) {
+			changes = append(changes, FieldChange{Field: field, From: a, To: b})
+		}
+	}
+
+	add("prompt_id", derefInt(from.PromptID), derefInt(to.PromptID))
+	add("patient_mood", derefInt(from.PatientMood), derefInt(to.PatientMood))
+	add("caregiver_mood", derefInt(from.CaregiverMood), derefInt(to.CaregiverMood))
+	add("sleep_hours", derefFloat(from.SleepHours), derefFloat(to.SleepHours))
+	add("emotion_tags", nonNilTags(from.EmotionTags), nonNilTags(to.EmotionTags))
+	return changes
+}
+
+func derefInt(v *int) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+func derefFloat(v *float64) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+func nonNilTags(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
+
+// diffText computes a character diff of two texts, falling back to a line
+// diff when the changed parts are too long for the quadratic algorithm. It
+// reports false when even the line diff would exceed maxDiffCells.
+func diffText(a, b string) ([]DiffOp, bool) {
+	runes := func(s string) []string {
+		rs := []rune(s)
+		out := make([]string, len(rs))
+		for i, r := range rs {
+			out[i] = string(r)
+		}
+		return out
+	}
+	if ops, ok := diffTokens(runes(a), runes(b)); ok {
+		return ops, true
+	}
+
+	lines := func(s string) []string {
+		parts := strings.SplitAfter(s, "\n")
+		if len(parts) > 0 && parts[len(parts)-1] == "" {
+			parts = parts[:len(parts)-1]
+		}
+		return parts
+	}
+	return diffTokens(lines(a), lines(b))
+}
+
+// diffTokens runs a longest-common-subsequence diff and merges adjacent
+// tokens with the same operation. The common prefix and suffix are set aside
+// first; it reports false if the rest needs more than maxDiffCells.
+func diffTokens(a, b []string) ([]DiffOp, bool) {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	head, tail := a[:prefix], a[len(a)-suffix:]
+	a, b = a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(a)*len(b) > maxDiffCells {
+		return nil, false
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	ops := []DiffOp{}
+	emit := func(op, text string) {
+		if n := len(ops); n > 0 && ops[n-1].Op == op {
+			ops[n-1].Text += text
+			return
+		}
+		ops = append(ops, DiffOp{Op: op, Text: text})
+	}
+
+	for _, token := range head {
+		emit(DiffEqual, token)
+	}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			emit(DiffEqual, a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			emit(DiffDelete, a[i])
+			i++
+		default:
+			emit(DiffInsert, b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		emit(DiffDelete, a[i])
+	}
+	for ; j < len(b); j++ {
+		emit(DiffInsert, b[j])
+	}
+	for _, token := range tail {
+		emit(DiffEqual, token)
+	}
+	return ops, true
+}
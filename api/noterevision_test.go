@@ -0,0 +1,38 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffText(t *testing.T) {
+	ops, ok := diffText("今天很累\n睡得不好\n", "今天很累\n睡得很好\n")
+	if !ok {
+		t.Fatal("small diff was refused")
+	}
+	var from, to string
+	for _, op := range ops {
+		if op.Op != DiffInsert {
+			from += op.Text
+		}
+		if op.Op != DiffDelete {
+			to += op.Text
+		}
+	}
+	if from != "今天很累\n睡得不好\n" || to != "今天很累\n睡得很好\n" {
+		t.Errorf("diff does not rebuild both texts: %+v", ops)
+	}
+
+	// Long texts with a small change fall back to lines around the change
+	long := strings.Repeat("line\n", 3000)
+	if _, ok := diffText(long+"a\n"+long, long+"b\n"+long); !ok {
+		t.Error("long texts with a small change were refused")
+	}
+
+	// Texts that differ everywhere would need a huge table even line by line
+	a := strings.Repeat("a\n", maxNoteContentRunes/2)
+	b := strings.Repeat("b\n", maxNoteContentRunes/2)
+	if _, ok := diffText(a, b); ok {
+		t.Error("diff of two unrelated maximum-length notes was computed")
+	}
+}
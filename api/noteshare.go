@@ -0,0 +1,673 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"hope_backend/config"
+	"hope_backend/dao"
+	"hope_backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// NoteShareLinkBaseURL is where read-only share links are opened; no login is needed
+	NoteShareLinkBaseURL = "https://hope.layu.cc/hope/shared"
+
+	// defaultShareLinkDays and maxShareLinkDays bound how long a link works; links always expire
+	defaultShareLinkDays = 7
+	maxShareLinkDays     = 90
+	// maxSharedNotes is the largest number of individual notes in one share
+	maxSharedNotes = 100
+	// maxShareRangeDays is the longest date range one share can cover
+	maxShareRangeDays = 366
+	// maxShareLabelRunes matches the note_shares.label column
+	maxShareLabelRunes = 50
+	// maxRecipientMobileLen matches the note_shares.recipient_mobile column
+	maxRecipientMobileLen = 20
+)
+
+// Note share statuses
+const (
+	ShareStatusActive  = "active"
+	ShareStatusExpired = "expired"
+	ShareStatusRevoked = "revoked"
+)
+
+// CreateNoteShareRequest is the expected request body for sharing notes.
+// Either NoteIDs or From and To ("YYYY.M.D" or "YYYY-MM-DD") select the notes.
+// With RecipientMobile the notes are shared with the user of that number, or
+// with whoever registers it later; without it a link is created.
+type CreateNoteShareRequest struct {
+	RecipientMobile string `json:"recipient_mobile"`
+	Label           string `json:"label"`
+	NoteIDs         []int  `json:"note_ids"`
+	From            string `json:"from"`
+	To              string `json:"to"`
+	ExpiresInDays   int    `json:"expires_in_days"` // Links: 1-90, default 7. Users: 0 shares until revoked
+}
+
+// NoteShareResponse describes a share to its owner or recipient
+type NoteShareResponse struct {
+	*models.NoteShare
+	Status    string `json:"status"`
+	Link      string `json:"link,omitempty"`       // Only returned when a link is created
+	OwnerName string `json:"owner_name,omitempty"` // For the recipient
+}
+
+// SharedNotesResponse is what a share link shows
+type SharedNotesResponse struct {
+	OwnerName string        `json:"owner_name"`
+	StartDate *models.Date  `json:"start_date"`
+	EndDate   *models.Date  `json:"end_date"`
+	ExpiresAt int64         `json:"expires_at"`
+	Notes     []models.Note `json:"notes"`
+}
+
+// CreateNoteShareHandler shares notes with another user or creates a read-only link
+func CreateNoteShareHandler(c *gin.Context) {
+	// Get user ID from context or session
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Message: "Unauthorized: User not authenticated",
+		})
+		return
+	}
+	ownerID := userID.(int64)
+
+	var req CreateNoteShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	share, err := req.share(ownerID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	// Individual notes must be the owner's live notes
+	if len(share.NoteIDs) > 0 {
+		count, err := dao.CountLiveNotes(ownerID, share.NoteIDs)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to check notes: " + err.Error(),
+			})
+			return
+		}
+		if count != int64(len(share.NoteIDs)) {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid request: some notes do not exist",
+			})
+			return
+		}
+	}
+
+	response := NoteShareResponse{NoteShare: share, Status: ShareStatusActive}
+	if req.RecipientMobile != "" {
+		// The response is the same whether or not the number is registered;
+		// a share for an unregistered number waits for it to sign up
+		share.RecipientMobile = req.RecipientMobile
+		recipient, err := dao.NewUserProfileDAO(config.DB).GetByMobileNumber(req.RecipientMobile)
+		if err != nil && err.Error() != "user profile not found" {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to find recipient: " + err.Error(),
+			})
+			return
+		}
+		if recipient != nil {
+			if recipient.ID == ownerID {
+				c.JSON(http.StatusBadRequest, Response{
+					Success: false,
+					Message: "Invalid request: notes cannot be shared with yourself",
+				})
+				return
+			}
+			share.RecipientID = &recipient.ID
+		}
+	} else {
+		token, err := newShareToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to create link: " + err.Error(),
+			})
+			return
+		}
+		tokenHash := hashShareToken(token)
+		share.TokenHash = &tokenHash
+		response.Link = NoteShareLinkBaseURL + "/" + token
+	}
+
+	if err := dao.CreateNoteShare(share); err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to share notes: " + err.Error(),
+		})
+		return
+	}
+
+	recordAudit(c, dao.NewAuditDAO(config.DB), AuditNoteShareCreate, ownerID, gin.H{
+		"share_id":     share.ShareID,
+		"recipient_id": share.RecipientID,
+		"link":         share.TokenHash != nil,
+		"note_ids":     share.NoteIDs,
+		"start_date":   share.StartDate,
+		"end_date":     share.EndDate,
+		"expires_at":   share.ExpiresAt,
+	})
+
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Message: "Notes shared successfully",
+		Data:    response,
+	})
+}
+
+// share validates the request and builds the share, without the recipient or link
+func (r *CreateNoteShareRequest) share(ownerID int64) (*models.NoteShare, error) {
+	now := time.Now()
+	share := &models.NoteShare{
+		OwnerID:   ownerID,
+		Label:     strings.TrimSpace(r.Label),
+		NoteIDs:   []int{},
+		CreatedAt: now.Unix(),
+	}
+	if len([]rune(share.Label)) > maxShareLabelRunes {
+		return nil, fmt.Errorf("label must be at most %d characters", maxShareLabelRunes)
+	}
+	if len(r.RecipientMobile) > maxRecipientMobileLen {
+		return nil, fmt.Errorf("recipient_mobile must be at most %d characters", maxRecipientMobileLen)
+	}
+
+	switch {
+	case len(r.NoteIDs) > 0 && (r.From != "" || r.To != ""):
+		return nil, errors.New("share either note_ids or a from-to range, not both")
+	case len(r.NoteIDs) > 0:
+		seen := make(map[int]bool)
+		for _, noteID := range r.NoteIDs {
+			if noteID < 1 {
+				return nil, errors.New("invalid note ID")
+			}
+			if !seen[noteID] {
+				seen[noteID] = true
+				share.NoteIDs = append(share.NoteIDs, noteID)
+			}
+		}
+		if len(share.NoteIDs) > maxSharedNotes {
+			return nil, fmt.Errorf("at most %d notes can be shared at once", maxSharedNotes)
+		}
+	case r.From != "" && r.To != "":
+		from, err := models.ParseDate(r.From)
+		if err != nil {
+			return nil, err
+		}
+		to, err := models.ParseDate(r.To)
+		if err != nil {
+			return nil, err
+		}
+		if from.After(to.Time) {
+			return nil, errors.New("from must not be after to")
+		}
+		if to.Sub(from.Time) >= maxShareRangeDays*24*time.Hour {
+			return nil, fmt.Errorf("the range can cover at most %d days", maxShareRangeDays)
+		}
+		share.StartDate, share.EndDate = &from, &to
+	default:
+		return nil, errors.New("note_ids or both from and to are required")
+	}
+
+	days := r.ExpiresInDays
+	if r.RecipientMobile == "" && days == 0 {
+		days = defaultShareLinkDays
+	}
+	if days < 0 {
+		return nil, errors.New("expires_in_days must not be negative")
+	}
+	if r.RecipientMobile == "" && days > maxShareLinkDays {
+		return nil, fmt.Errorf("links must expire within %d days", maxShareLinkDays)
+	}
+	if days > 0 {
+		share.ExpiresAt = now.AddDate(0, 0, days).Unix()
+	}
+	return share, nil
+}
+
+// ListNoteSharesHandler lists the shares the user created, newest first
+func ListNoteSharesHandler(c *gin.Context) {
+	// Get user ID from context or session
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Message: "Unauthorized: User not authenticated",
+		})
+		return
+	}
+
+	shares, err := dao.GetNoteSharesByOwner(userID.(int64))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to retrieve shares: " + err.Error(),
+		})
+		return
+	}
+
+	now := time.Now().Unix()
+	responses := make([]NoteShareResponse, len(shares))
+	for i := range shares {
+		responses[i] = NoteShareResponse{NoteShare: &shares[i], Status: shareStatus(&shares[i], now)}
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Shares retrieved successfully",
+		Data:    responses,
+	})
+}
+
+// RevokeNoteShareHandler ends a share; the recipient or link loses access immediately
+func RevokeNoteShareHandler(c *gin.Context) {
+	// Get user ID from context or session
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Message: "Unauthorized: User not authenticated",
+		})
+		return
+	}
+
+	shareID, err := strconv.ParseInt(c.Param("shareId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "Invalid share ID",
+		})
+		return
+	}
+
+	if err := dao.RevokeNoteShare(shareID, userID.(int64), time.Now().Unix()); err != nil {
+		status := http.StatusInternalServerError
+		message := "Failed to revoke share: " + err.Error()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			status, message = http.StatusNotFound, "Share not found"
+		}
+		c.JSON(status, Response{
+			Success: false,
+			Message: message,
+		})
+		return
+	}
+
+	recordAudit(c, dao.NewAuditDAO(config.DB), AuditNoteShareRevoke, userID.(int64), gin.H{"share_id": shareID})
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Share revoked successfully",
+	})
+}
+
+// NoteShareAccess is one read of the user's shared notes, as shown to the owner
+type NoteShareAccess struct {
+	ActorID   int64  `json:"actor_id"` // 0 when read through a link
+	Detail    string `json:"detail"`   // JSON with share_id, via and note_ids
+	CreatedAt int64  `json:"created_at"`
+}
+
+// ListNoteShareAccessHandler lists who read the user's shared notes and when, newest first
+func ListNoteShareAccessHandler(c *gin.Context) {
+	// Get user ID from context or session
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Message: "Unauthorized: User not authenticated",
+		})
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("size", strconv.Itoa(defaultPageSize)))
+	if err != nil || pageSize < 1 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	entries, total, err := dao.NewAuditDAO(config.DB).List(dao.AuditLogFilter{
+		TargetUserID: userID.(int64),
+		Action:       AuditSharedNoteAccess,
+	}, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to retrieve share access: " + err.Error(),
+		})
+		return
+	}
+
+	// IP addresses and user agents of readers stay in the admin audit log
+	accesses := make([]NoteShareAccess, len(entries))
+	for i, entry := range entries {
+		accesses[i] = NoteShareAccess{ActorID: entry.ActorID, Detail: entry.Detail, CreatedAt: entry.CreatedAt}
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    accesses,
+		Total:   total,
+		Page:    page,
+		Size:    pageSize,
+	})
+}
+
+// ListSharedWithMeHandler lists the active shares other users created for the user
+func ListSharedWithMeHandler(c *gin.Context) {
+	// Get user ID from context or session
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Message: "Unauthorized: User not authenticated",
+		})
+		return
+	}
+
+	shares, err := dao.GetActiveNoteSharesForRecipient(userID.(int64), 0, time.Now().Unix())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to retrieve shares: " + err.Error(),
+		})
+		return
+	}
+
+	profileDAO := dao.NewUserProfileDAO(config.DB)
+	responses := make([]NoteShareResponse, len(shares))
+	for i := range shares {
+		// The label is the owner's private name for the share
+		shares[i].Label = ""
+		shares[i].RecipientMobile = ""
+		responses[i] = NoteShareResponse{NoteShare: &shares[i], Status: ShareStatusActive}
+		if owner, err := profileDAO.GetByID(shares[i].OwnerID); err == nil {
+			responses[i].OwnerName = owner.UserNickname
+		}
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Shares retrieved successfully",
+		Data:    responses,
+	})
+}
+
+// GetSharedLinkHandler shows the notes of a share link. No login is needed;
+// every access is recorded in the owner's audit log.
+func GetSharedLinkHandler(c *gin.Context) {
+	share := activeShareLink(c)
+	if share == nil {
+		return
+	}
+
+	notes, err := dao.GetSharedNotes(share)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to retrieve notes: " + err.Error(),
+		})
+		return
+	}
+
+	// Photos are served through the link as well
+	linkURL := NoteShareLinkBaseURL + "/" + c.Param("token")
+	noteIDs := make([]int, len(notes))
+	for i := range notes {
+		noteIDs[i] = notes[i].NoteID
+		for j := range notes[i].Attachments {
+			attachment := &notes[i].Attachments[j]
+			attachment.URL = fmt.Sprintf("%s/attachments/%d", linkURL, attachment.AttachmentID)
+			attachment.ThumbnailURL = attachment.URL + "?size=thumbnail"
+		}
+	}
+
+	response := SharedNotesResponse{
+		StartDate: share.StartDate,
+		EndDate:   share.EndDate,
+		ExpiresAt: share.ExpiresAt,
+		Notes:     notes,
+	}
+	if owner, err := dao.NewUserProfileDAO(config.DB).GetByID(share.OwnerID); err == nil {
+		response.OwnerName = owner.UserNickname
+	}
+
+	recordSharedNoteAccess(c, share, noteIDs)
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Notes retrieved successfully",
+		Data:    response,
+	})
+}
+
+// GetSharedLinkAttachmentHandler serves a photo of a note shared through a link
+func GetSharedLinkAttachmentHandler(c *gin.Context) {
+	share := activeShareLink(c)
+	if share == nil {
+		return
+	}
+
+	attachmentID, err := strconv.ParseInt(c.Param("attachmentId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "Invalid attachment ID",
+		})
+		return
+	}
+
+	attachment, err := dao.GetNoteAttachment(attachmentID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to retrieve photo: " + err.Error(),
+		})
+		return
+	}
+
+	var note *models.Note
+	if attachment != nil {
+		if note, err = attachmentNote(attachment); err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to retrieve note: " + err.Error(),
+			})
+			return
+		}
+	}
+	if note == nil || !share.Covers(note) {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Message: "Photo not found",
+		})
+		return
+	}
+
+	recordSharedNoteAccess(c, share, []int{note.NoteID})
+	serveNoteAttachment(c, attachment)
+}
+
+// activeShareLink loads the link share named by the token parameter; it responds and returns nil if unavailable
+func activeShareLink(c *gin.Context) *models.NoteShare {
+	share, err := dao.GetActiveNoteShareByTokenHash(hashShareToken(c.Param("token")), time.Now().Unix())
+	if err != nil {
+		status, message := http.StatusInternalServerError, "Failed to retrieve share: "+err.Error()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			status, message = http.StatusNotFound, "This link has expired or was revoked"
+		}
+		c.JSON(status, Response{
+			Success: false,
+			Message: message,
+		})
+		return nil
+	}
+	return share
+}
+
+// noteOwnerParam reads the optional owner_id query parameter of note queries,
+// defaulting to the current user; it responds and returns false if it is invalid
+func noteOwnerParam(c *gin.Context, userID int64) (int64, bool) {
+	value := c.Query("owner_id")
+	if value == "" {
+		return userID, true
+	}
+
+	ownerID, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || ownerID < 1 {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "Invalid owner_id",
+		})
+		return 0, false
+	}
+	return ownerID, true
+}
+
+// noteShareFor returns the active share that lets a user read a note of someone else, or nil if there is none
+func noteShareFor(viewerID int64, note *models.Note) (*models.NoteShare, error) {
+	shares, err := dao.GetActiveNoteSharesForRecipient(viewerID, note.UserID, time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+	for i := range shares {
+		if shares[i].Covers(note) {
+			return &shares[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// sharedNotesInRange returns the notes of another user in a date range that are shared with the viewer
+func sharedNotesInRange(c *gin.Context, viewerID, ownerID int64, start, end models.Date) ([]models.Note, error) {
+	shares, err := dao.GetActiveNoteSharesForRecipient(viewerID, ownerID, time.Now().Unix())
+	if err != nil || len(shares) == 0 {
+		return []models.Note{}, err
+	}
+
+	notes, err := dao.GetNotesByDateRange(ownerID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each share is audited with the notes it gave access to
+	visible := []models.Note{}
+	noteIDs := make(map[int64][]int)
+	for i := range notes {
+		for j := range shares {
+			if shares[j].Covers(&notes[i]) {
+				visible = append(visible, notes[i])
+				noteIDs[shares[j].ShareID] = append(noteIDs[shares[j].ShareID], notes[i].NoteID)
+				break
+			}
+		}
+	}
+	for i := range shares {
+		if ids := noteIDs[shares[i].ShareID]; len(ids) > 0 {
+			recordSharedNoteAccess(c, &shares[i], ids)
+		}
+	}
+	return visible, nil
+}
+
+// recordSharedNoteAccess writes an audit entry for the owner when someone else reads shared notes
+func recordSharedNoteAccess(c *gin.Context, share *models.NoteShare, noteIDs []int) {
+	via := "user"
+	if share.TokenHash != nil {
+		via = "link"
+	}
+	recordAudit(c, dao.NewAuditDAO(config.DB), AuditSharedNoteAccess, share.OwnerID, gin.H{
+		"share_id": share.ShareID,
+		"via":      via,
+		"note_ids": noteIDs,
+	})
+}
+
+// shareStatus reports whether a share is active, expired or revoked
+func shareStatus(share *models.NoteShare, now int64) string {
+	switch {
+	case share.RevokedAt > 0:
+		return ShareStatusRevoked
+	case !share.Active(now):
+		return ShareStatusExpired
+	}
+	return ShareStatusActive
+}
+
+// newShareToken creates the secret part of a share link
+func newShareToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashShareToken returns the form of a link token that is stored
+func hashShareToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// attachmentNote loads the live note of an attachment; it returns nil if the note is in the trash
+func attachmentNote(attachment *models.NoteAttachment) (*models.Note, error) {
+	note, err := dao.GetNoteByID(attachment.NoteID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return note, err
+}
+
+// serveNoteAttachment writes the file of an attachment; ?size=thumbnail serves the thumbnail
+func serveNoteAttachment(c *gin.Context, attachment *models.NoteAttachment) {
+	filePath := uploadFilePath(attachment.FilePath)
+	if c.Query("size") == "thumbnail" {
+		// Fall back to the full image if the thumbnail could not be created
+		thumbnailPath := filepath.Join(filepath.Dir(filePath), ThumbnailPrefix+filepath.Base(filePath))
+		if _, err := os.Stat(thumbnailPath); err == nil {
+			filePath = thumbnailPath
+		}
+	}
+
+	// Files never change, but they are private to the users the note is shared with
+	c.Header("Cache-Control", "private, max-age=31536000, immutable")
+	c.File(filePath)
+}
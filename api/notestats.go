@@ -0,0 +1,447 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"hope_backend/dao"
+	"hope_backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultStatsWeeks is the range used when from/to are not given
+	defaultStatsWeeks = 12
+	// maxStatsDays limits how much history a single stats request may cover
+	maxStatsDays = 366 * 2
+	// topEmotionTagLimit is how many emotion tags are returned
+	topEmotionTagLimit = 10
+	// minCorrelationSamples is the fewest paired values a correlation or trend is computed from
+	minCorrelationSamples = 3
+	// flatTrendThreshold is the weekly mood change below which a trend counts as flat
+	flatTrendThreshold = 0.1
+)
+
+// Supported bucket sizes for note statistics
+const (
+	GranularityDay   = "day"
+	GranularityWeek  = "week"
+	GranularityMonth = "month"
+)
+
+// MoodSummary describes one mood score over the requested range
+type MoodSummary struct {
+	Average      *float64 `json:"average"`
+	Count        int      `json:"count"`
+	TrendPerWeek *float64 `json:"trend_per_week"` // Least-squares slope, in score points per week
+	Direction    string   `json:"direction"`      // up, down, flat or unknown
+}
+
+// StatsBucket aggregates the notes of one day, week or month
+type StatsBucket struct {
+	Start            string   `json:"start"`
+	End              string   `json:"end"`
+	Notes            int      `json:"notes"`
+	PatientMoodAvg   *float64 `json:"patient_mood_avg"`
+	CaregiverMoodAvg *float64 `json:"caregiver_mood_avg"`
+	SleepHoursAvg    *float64 `json:"sleep_hours_avg"`
+}
+
+// TagCount is how often an emotion tag was used
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// Correlation is a Pearson coefficient and the number of days it is based on
+type Correlation struct {
+	R       *float64 `json:"r"` // nil when there are too few samples or no variance
+	Samples int      `json:"samples"`
+}
+
+// NoteStats is the response of GetNoteStatsHandler
+type NoteStats struct {
+	From           string        `json:"from"`
+	To             string        `json:"to"`
+	Granularity    string        `json:"granularity"`
+	TotalNotes     int           `json:"total_notes"`
+	DaysInRange    int           `json:"days_in_range"`
+	CurrentStreak  int           `json:"current_streak"` // Consecutive days with a note ending at "to" (or the day before)
+	LongestStreak  int           `json:"longest_streak"`
+	PatientMood    MoodSummary   `json:"patient_mood"`
+	CaregiverMood  MoodSummary   `json:"caregiver_mood"`
+	SleepHoursAvg  *float64      `json:"sleep_hours_avg"`
+	Buckets        []StatsBucket `json:"buckets"`
+	TopEmotionTags []TagCount    `json:"top_emotion_tags"`
+	Correlations   struct {
+		SleepVsPatientMood     Correlation `json:"sleep_vs_patient_mood"`
+		CaregiverVsPatientMood Correlation `json:"caregiver_vs_patient_mood"`
+	} `json:"correlations"`
+}
+
+// datedNote is a note with its parsed date
+type datedNote struct {
+	date time.Time
+	note models.Note
+}
+
+// GetNoteStatsHandler returns journaling and mood statistics for the current user.
+// Query parameters: from and to as "YYYY.M.D" or "YYYY-MM-DD" (default: the 12 weeks
+// up to today in the user's timezone) and granularity as day, week or month (default: week).
+func GetNoteStatsHandler(c *gin.Context) {
+	// Get user ID from context or session
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Message: "Unauthorized: User not authenticated",
+		})
+		return
+	}
+
+	granularity := c.DefaultQuery("granularity", GranularityWeek)
+	if granularity != GranularityDay && granularity != GranularityWeek && granularity != GranularityMonth {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "Invalid granularity. Supported values: day, week, month",
+		})
+		return
+	}
+
+	to := userToday(userID.(int64))
+	if value := c.Query("to"); value != "" {
+		parsed, err := models.ParseDate(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid to date: " + err.Error(),
+			})
+			return
+		}
+		to = parsed
+	}
+
+	from := to.AddDays(-7*defaultStatsWeeks + 1)
+	if value := c.Query("from"); value != "" {
+		parsed, err := models.ParseDate(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid from date: " + err.Error(),
+			})
+			return
+		}
+		from = parsed
+	}
+
+	if from.After(to.Time) {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "from must not be after to",
+		})
+		return
+	}
+	if to.Sub(from.Time) > maxStatsDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "The range can cover at most two years",
+		})
+		return
+	}
+
+	notes, err := dao.GetNotesByDateRange(userID.(int64), from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to retrieve notes: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Statistics computed successfully",
+		Data:    computeNoteStats(notes, from.Time, to.Time, granularity),
+	})
+}
+
+// computeNoteStats aggregates the notes dated between from and to (inclusive)
+func computeNoteStats(notes []models.Note, from, to time.Time, granularity string) *NoteStats {
+	stats := &NoteStats{
+		From:           from.Format(models.DateLayout),
+		To:             to.Format(models.DateLayout),
+		Granularity:    granularity,
+		DaysInRange:    int(to.Sub(from).Hours()/24) + 1,
+		Buckets:        []StatsBucket{},
+		TopEmotionTags: []TagCount{},
+	}
+
+	// Drop anything outside the range
+	dated := make([]datedNote, 0, len(notes))
+	for _, note := range notes {
+		date := note.NoteDate.Time
+		if date.Before(from) || date.After(to) {
+			continue
+		}
+		dated = append(dated, datedNote{date: date, note: note})
+	}
+	sort.Slice(dated, func(i, j int) bool { return dated[i].date.Before(dated[j].date) })
+	stats.TotalNotes = len(dated)
+
+	stats.CurrentStreak, stats.LongestStreak = noteStreaks(dated, to)
+
+	var patientX, patientY, caregiverX, caregiverY []float64
+	var sleepPairs, caregiverPairs [2][]float64
+	var sleepSum float64
+	var sleepCount int
+	tagCounts := make(map[string]int)
+	for _, d := range dated {
+		day := d.date.Sub(from).Hours() / 24
+		if d.note.PatientMood != nil {
+			patientX = append(patientX, day)
+			patientY = append(patientY, float64(*d.note.PatientMood))
+		}
+		if d.note.CaregiverMood != nil {
+			caregiverX = append(caregiverX, day)
+			caregiverY = append(caregiverY, float64(*d.note.CaregiverMood))
+		}
+		if d.note.SleepHours != nil {
+			sleepSum += *d.note.SleepHours
+			sleepCount++
+		}
+		if d.note.SleepHours != nil && d.note.PatientMood != nil {
+			sleepPairs[0] = append(sleepPairs[0], *d.note.SleepHours)
+			sleepPairs[1] = append(sleepPairs[1], float64(*d.note.PatientMood))
+		}
+		if d.note.CaregiverMood != nil && d.note.PatientMood != nil {
+			caregiverPairs[0] = append(caregiverPairs[0], float64(*d.note.CaregiverMood))
+			caregiverPairs[1] = append(caregiverPairs[1], float64(*d.note.PatientMood))
+		}
+		for _, tag := range d.note.EmotionTags {
+			tagCounts[tag]++
+		}
+	}
+
+	stats.PatientMood = moodSummary(patientX, patientY)
+	stats.CaregiverMood = moodSummary(caregiverX, caregiverY)
+	if sleepCount > 0 {
+		stats.SleepHoursAvg = roundedPtr(sleepSum / float64(sleepCount))
+	}
+	stats.Correlations.SleepVsPatientMood = pearson(sleepPairs[0], sleepPairs[1])
+	stats.Correlations.CaregiverVsPatientMood = pearson(caregiverPairs[0], caregiverPairs[1])
+
+	for tag, count := range tagCounts {
+		stats.TopEmotionTags = append(stats.TopEmotionTags, TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(stats.TopEmotionTags, func(i, j int) bool {
+		a, b := stats.TopEmotionTags[i], stats.TopEmotionTags[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Tag < b.Tag
+	})
+	if len(stats.TopEmotionTags) > topEmotionTagLimit {
+		stats.TopEmotionTags = stats.TopEmotionTags[:topEmotionTagLimit]
+	}
+
+	stats.Buckets = statsBuckets(dated, from, to, granularity)
+	return stats
+}
+
+// noteStreaks returns the streak of consecutive note days ending at "to" (or the
+// day before, so a streak is not lost before today's note is written) and the longest streak
+func noteStreaks(dated []datedNote, to time.Time) (current, longest int) {
+	days := make(map[time.Time]bool, len(dated))
+	for _, d := range dated {
+		days[d.date] = true
+	}
+
+	run := 0
+	var previous time.Time
+	for i, d := range dated {
+		if i > 0 && d.date.Equal(previous) {
+			continue
+		}
+		if i > 0 && d.date.Equal(previous.AddDate(0, 0, 1)) {
+			run++
+		} else {
+			run = 1
+		}
+		if run > longest {
+			longest = run
+		}
+		previous = d.date
+	}
+
+	day := to
+	if !days[day] {
+		day = day.AddDate(0, 0, -1)
+	}
+	for days[day] {
+		current++
+		day = day.AddDate(0, 0, -1)
+	}
+	return current, longest
+}
+
+// statsBuckets groups the notes by day, ISO week (starting Monday) or calendar month
+func statsBuckets(dated []datedNote, from, to time.Time, granularity string) []StatsBucket {
+	bucketStart := func(t time.Time) time.Time {
+		switch granularity {
+		case GranularityDay:
+			return t
+		case GranularityMonth:
+			return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		default:
+			offset := (int(t.Weekday()) + 6) % 7 // Days since Monday
+			return t.AddDate(0, 0, -offset)
+		}
+	}
+	nextStart := func(t time.Time) time.Time {
+		switch granularity {
+		case GranularityDay:
+			return t.AddDate(0, 0, 1)
+		case GranularityMonth:
+			return t.AddDate(0, 1, 0)
+		default:
+			return t.AddDate(0, 0, 7)
+		}
+	}
+
+	buckets := []StatsBucket{}
+	i := 0
+	for start := bucketStart(from); !start.After(to); start = nextStart(start) {
+		end := nextStart(start).AddDate(0, 0, -1)
+
+		var patient, caregiver, sleep []float64
+		bucket := StatsBucket{Start: start.Format(models.DateLayout), End: end.Format(models.DateLayout)}
+		for ; i < len(dated) && !dated[i].date.After(end); i++ {
+			note := dated[i].note
+			bucket.Notes++
+			if note.PatientMood != nil {
+				patient = append(patient, float64(*note.PatientMood))
+			}
+			if note.CaregiverMood != nil {
+				caregiver = append(caregiver, float64(*note.CaregiverMood))
+			}
+			if note.SleepHours != nil {
+				sleep = append(sleep, *note.SleepHours)
+			}
+		}
+		bucket.PatientMoodAvg = average(patient)
+		bucket.CaregiverMoodAvg = average(caregiver)
+		bucket.SleepHoursAvg = average(sleep)
+		buckets = append(buckets, bucket)
+	}
+	return buckets
+}
+
+// moodSummary computes the average and weekly trend of mood scores logged on the given days
+func moodSummary(days, scores []float64) MoodSummary {
+	summary := MoodSummary{
+		Average:   average(scores),
+		Count:     len(scores),
+		Direction: "unknown",
+	}
+
+	if slope, ok := linearSlope(days, scores); ok {
+		summary.TrendPerWeek = roundedPtr(slope * 7)
+		switch {
+		case slope*7 >= flatTrendThreshold:
+			summary.Direction = "up"
+		case slope*7 <= -flatTrendThreshold:
+			summary.Direction = "down"
+		default:
+			summary.Direction = "flat"
+		}
+	}
+	return summary
+}
+
+// average returns the rounded mean, or nil for no values
+func average(values []float64) *float64 {
+	if len(values) == 0 {
+		return nil
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return roundedPtr(sum / float64(len(values)))
+}
+
+// linearSlope returns the least-squares slope of y over x
+func linearSlope(x, y []float64) (float64, bool) {
+	if len(x) < minCorrelationSamples {
+		return 0, false
+	}
+
+	varX, _, covXY := centredMoments(x, y)
+	if varX <= 0 {
+		return 0, false
+	}
+	return covXY / varX, true
+}
+
+// pearson returns the Pearson correlation coefficient of two equally long series
+func pearson(x, y []float64) Correlation {
+	result := Correlation{Samples: len(x)}
+	if len(x) < minCorrelationSamples {
+		return result
+	}
+
+	varX, varY, covXY := centredMoments(x, y)
+	if varX <= 0 || varY <= 0 {
+		return result
+	}
+	r := covXY / (math.Sqrt(varX) * math.Sqrt(varY))
+	result.R = roundedPtr(math.Max(-1, math.Min(1, r)))
+	return result
+}
+
+// centredMoments returns the sums of squared deviations of x and y from their
+// means and the sum of their cross products. The means are subtracted first,
+// because the one-pass formula n*Σx² - (Σx)² cancels to a small negative
+// number for constant series. Variances within rounding error of zero are
+// returned as zero.
+func centredMoments(x, y []float64) (varX, varY, covXY float64) {
+	n := float64(len(x))
+	var sumX, sumY float64
+	for i := range x {
+		sumX += x[i]
+		sumY += y[i]
+	}
+	meanX, meanY := sumX/n, sumY/n
+
+	for i := range x {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		varX += dx * dx
+		varY += dy * dy
+		covXY += dx * dy
+	}
+
+	// Deviations of a constant series are at most a few ulps of the mean
+	if varX <= roundingVariance(meanX, n) {
+		varX = 0
+	}
+	if varY <= roundingVariance(meanY, n) {
+		varY = 0
+	}
+	return varX, varY, covXY
+}
+
+// roundingVariance bounds the sum of squared deviations rounding alone can
+// produce for n values around mean
+func roundingVariance(mean, n float64) float64 {
+	ulps := 1e-12 * math.Max(1, math.Abs(mean))
+	return n * ulps * ulps
+}
+
+// roundedPtr rounds to two decimals and returns a pointer for nullable JSON fields
+func roundedPtr(v float64) *float64 {
+	rounded := math.Round(v*100) / 100
+	return &rounded
+}
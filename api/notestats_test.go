@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPearson(t *testing.T) {
+	repeat := func(v float64, n int) []float64 {
+		values := make([]float64, n)
+		for i := range values {
+			values[i] = v
+		}
+		return values
+	}
+
+	tests := []struct {
+		name string
+		x, y []float64
+		want *float64 // nil for no correlation
+	}{
+		{"constant sleep over 3 days", repeat(6.8, 3), []float64{3, 4, 5}, nil},
+		{"constant sleep over 12 days", repeat(7.2, 12), []float64{1, 2, 3, 4, 5, 1, 2, 3, 4, 5, 1, 2}, nil},
+		{"constant mood", []float64{6, 7, 8, 9}, repeat(0.1, 4), nil},
+		{"both constant", repeat(0.3, 30), repeat(0.7, 30), nil},
+		{"too few samples", []float64{1, 2}, []float64{2, 1}, nil},
+		{"near constant", []float64{7.2, 7.2, 7.2, 7.3}, []float64{3, 3, 3, 4}, floatPtr(1)},
+		{"perfect negative", []float64{5, 6, 7, 8}, []float64{8, 6, 4, 2}, floatPtr(-1)},
+		{"uncorrelated", []float64{1, 2, 3, 4}, []float64{1, 3, 3, 1}, floatPtr(0)},
+	}
+
+	for _, tt := range tests {
+		result := pearson(tt.x, tt.y)
+		if result.Samples != len(tt.x) {
+			t.Errorf("%s: samples %d, want %d", tt.name, result.Samples, len(tt.x))
+		}
+		switch {
+		case tt.want == nil && result.R != nil:
+			t.Errorf("%s: r = %v, want none", tt.name, *result.R)
+		case tt.want != nil && (result.R == nil || *result.R != *tt.want):
+			t.Errorf("%s: r = %v, want %v", tt.name, result.R, *tt.want)
+		}
+
+		// The stats response must always encode
+		if _, err := json.Marshal(result); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+	}
+}
+
+func TestLinearSlope(t *testing.T) {
+	if _, ok := linearSlope([]float64{7.2, 7.2, 7.2, 7.2}, []float64{1, 2, 3, 4}); ok {
+		t.Error("slope over a constant x should not be computed")
+	}
+	slope, ok := linearSlope([]float64{0, 7, 14}, []float64{6.8, 6.8, 6.8})
+	if !ok || slope != 0 {
+		t.Errorf("slope of a constant y = %v, %v, want 0", slope, ok)
+	}
+	slope, ok = linearSlope([]float64{0, 1, 2, 3}, []float64{1, 3, 5, 7})
+	if !ok || slope != 2 {
+		t.Errorf("slope = %v, %v, want 2", slope, ok)
+	}
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
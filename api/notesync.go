@@ -0,0 +1,443 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"hope_backend/dao"
+	"hope_backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxSyncMutations is the largest batch of client changes accepted in one request
+	maxSyncMutations = 100
+	// syncPageSize is the number of changes returned per sync response
+	syncPageSize = 500
+	// maxClientIDLength matches the client_id column
+	maxClientIDLength = 64
+)
+
+// Sync mutation operations
+const (
+	SyncOpUpsert = "upsert"
+	SyncOpDelete = "delete"
+)
+
+// Sync mutation results
+const (
+	SyncApplied  = "applied"  // The change was saved; Note holds the server copy
+	SyncConflict = "conflict" // The note changed on the server; Note holds the server copy to merge with
+	SyncRejected = "rejected" // The change is invalid and was not saved; see Error
+)
+
+// NoteMutation is a change the client made while offline.
+// Notes created offline are sent without note_id and with base_version 0;
+// changes to known notes carry the version the client last saw.
+type NoteMutation struct {
+	ClientID    string `json:"client_id" binding:"required"` // Generated by the client, also identifies the result
+	NoteID      int    `json:"note_id"`
+	Op          string `json:"op" binding:"required"` // upsert or delete
+	BaseVersion int    `json:"base_version"`
+	NoteDate    string `json:"note_date"` // Only used when creating; defaults to today
+	Content     string `json:"content"`
+	PromptID    *int   `json:"prompt_id"`
+	NoteCheckIn        // Replaces all check-in fields and prompt_id; left-out fields are cleared
+}
+
+// SyncNotesRequest is the expected request body for pushing changes and pulling the change feed
+type SyncNotesRequest struct {
+	Cursor    int64          `json:"cursor"` // Cursor from the previous response, 0 for a full sync
+	Mutations []NoteMutation `json:"mutations"`
+}
+
+// NoteMutationResult reports what happened to one client mutation
+type NoteMutationResult struct {
+	ClientID string       `json:"client_id"`
+	NoteID   int          `json:"note_id,omitempty"`
+	Status   string       `json:"status"`
+	Note     *models.Note `json:"note,omitempty"`
+	Error    string       `json:"error,omitempty"`
+}
+
+// NoteDeletion is a note that was moved to the trash or permanently deleted
+type NoteDeletion struct {
+	NoteID    int     `json:"note_id"`
+	ClientID  *string `json:"client_id"`
+	DeletedAt int64   `json:"deleted_at"`
+	Purged    bool    `json:"purged"` // Permanently deleted, it can no longer be restored
+}
+
+// NoteSyncResponse is the response of the sync endpoints. Clients store the
+// cursor and keep pulling while has_more is true.
+type NoteSyncResponse struct {
+	Cursor  int64                `json:"cursor"`
+	HasMore bool                 `json:"has_more"`
+	Changes []models.Note        `json:"changes"`
+	Deleted []NoteDeletion       `json:"deleted"`
+	Results []NoteMutationResult `json:"results,omitempty"`
+}
+
+// PullNoteChangesHandler returns the notes changed after ?cursor=
+func PullNoteChangesHandler(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Message: "Unauthorized: User not authenticated",
+		})
+		return
+	}
+
+	var cursor int64
+	if value := c.Query("cursor"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid cursor",
+			})
+			return
+		}
+		cursor = parsed
+	}
+
+	resp, err := noteChangesSince(userID.(int64), cursor)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to retrieve changes: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Changes retrieved successfully",
+		Data:    resp,
+	})
+}
+
+// SyncNotesHandler applies a batch of offline changes, then returns the
+// notes changed after the cursor, including the ones just written
+func SyncNotesHandler(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Message: "Unauthorized: User not authenticated",
+		})
+		return
+	}
+
+	var req SyncNotesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	if req.Cursor < 0 {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "Invalid cursor",
+		})
+		return
+	}
+	if len(req.Mutations) > maxSyncMutations {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "Too many mutations, send at most " + strconv.Itoa(maxSyncMutations) + " per request",
+		})
+		return
+	}
+
+	results := make([]NoteMutationResult, 0, len(req.Mutations))
+	for i := range req.Mutations {
+		results = append(results, applyNoteMutation(userID.(int64), &req.Mutations[i]))
+	}
+
+	resp, err := noteChangesSince(userID.(int64), req.Cursor)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to retrieve changes: " + err.Error(),
+		})
+		return
+	}
+	resp.Results = results
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Notes synced successfully",
+		Data:    resp,
+	})
+}
+
+// noteChangesSince builds one page of the user's change feed after the cursor
+func noteChangesSince(userID int64, cursor int64) (*NoteSyncResponse, error) {
+	notes, err := dao.GetNoteChanges(userID, cursor, syncPageSize+1)
+	if err != nil {
+		return nil, err
+	}
+	tombstones, err := dao.GetNoteTombstones(userID, cursor, syncPageSize+1)
+	if err != nil {
+		return nil, err
+	}
+
+	// Both lists are ordered by sync_seq; keep the first page of the merged feed
+	type change struct {
+		seq  int64
+		note *models.Note
+		gone *models.NoteTombstone
+	}
+	feed := make([]change, 0, len(notes)+len(tombstones))
+	for i := range notes {
+		feed = append(feed, change{seq: notes[i].SyncSeq, note: &notes[i]})
+	}
+	for i := range tombstones {
+		feed = append(feed, change{seq: tombstones[i].SyncSeq, gone: &tombstones[i]})
+	}
+	sort.Slice(feed, func(i, j int) bool { return feed[i].seq < feed[j].seq })
+
+	resp := &NoteSyncResponse{
+		Cursor:  cursor,
+		Changes: []models.Note{},
+		Deleted: []NoteDeletion{},
+	}
+	if len(feed) > syncPageSize {
+		feed = feed[:syncPageSize]
+		resp.HasMore = true
+	}
+
+	for _, ch := range feed {
+		resp.Cursor = ch.seq
+		switch {
+		case ch.gone != nil:
+			resp.Deleted = append(resp.Deleted, NoteDeletion{
+				NoteID:    ch.gone.NoteID,
+				ClientID:  ch.gone.ClientID,
+				DeletedAt: ch.gone.DeletedAt,
+				Purged:    true,
+			})
+		case ch.note.DeletedAt > 0:
+			resp.Deleted = append(resp.Deleted, NoteDeletion{
+				NoteID:    ch.note.NoteID,
+				ClientID:  ch.note.ClientID,
+				DeletedAt: ch.note.DeletedAt,
+			})
+		default:
+			resp.Changes = append(resp.Changes, *ch.note)
+		}
+	}
+
+	return resp, nil
+}
+
+// applyNoteMutation saves one offline change, checking it against the server version
+func applyNoteMutation(userID int64, m *NoteMutation) NoteMutationResult {
+	result := NoteMutationResult{ClientID: m.ClientID, NoteID: m.NoteID}
+	reject := func(message string) NoteMutationResult {
+		result.Status = SyncRejected
+		result.Error = message
+		return result
+	}
+
+	if len(m.ClientID) > maxClientIDLength {
+		return reject("client_id can be at most " + strconv.Itoa(maxClientIDLength) + " characters")
+	}
+	if m.Op != SyncOpUpsert && m.Op != SyncOpDelete {
+		return reject("op must be upsert or delete")
+	}
+
+	// Find the server copy by note ID, or by client ID for notes created offline
+	var existing *models.Note
+	var err error
+	if m.NoteID > 0 {
+		existing, err = dao.GetNoteForSync(m.NoteID, userID)
+	} else {
+		existing, err = dao.GetNoteByClientID(userID, m.ClientID)
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return reject("Failed to retrieve note: " + err.Error())
+	}
+
+	if m.Op == SyncOpDelete {
+		return applyNoteDeletion(userID, m, existing, result)
+	}
+
+	if err := m.validate(); err != nil {
+		return reject(err.Error())
+	}
+	if err := validPromptID(m.PromptID); err != nil {
+		return reject(err.Error())
+	}
+	if m.Content == "" {
+		return reject("content is required")
+	}
+	if len([]rune(m.Content)) > maxNoteContentRunes {
+		return reject(fmt.Sprintf("content must be at most %d characters", maxNoteContentRunes))
+	}
+
+	if existing == nil {
+		if m.NoteID > 0 {
+			return reject("Note not found")
+		}
+		return createSyncedNote(userID, m, result)
+	}
+
+	result.NoteID = existing.NoteID
+	conflict := func(note *models.Note) NoteMutationResult {
+		result.Status = SyncConflict
+		result.Note = note
+		return result
+	}
+
+	if m.BaseVersion == 0 {
+		// A retried create: it was applied unless the note has changed since
+		if m.NoteID == 0 && existing.Version == 1 && existing.DeletedAt == 0 {
+			result.Status = SyncApplied
+			result.Note = existing
+			return result
+		}
+		return conflict(existing)
+	}
+	if existing.DeletedAt > 0 || existing.Version != m.BaseVersion {
+		return conflict(existing)
+	}
+
+	if m.NoteDate != "" {
+		date, err := models.ParseDate(m.NoteDate)
+		if err != nil {
+			return reject(err.Error())
+		}
+		if !date.Equal(existing.NoteDate.Time) {
+			return reject("note_date cannot be changed")
+		}
+	}
+
+	note := *existing
+	note.Content = m.Content
+	note.PromptID = promptIDValue(m.PromptID)
+	m.replace(&note)
+	note.UpdatedAt = time.Now().Unix()
+	if err := dao.UpdateNoteAtVersion(&note, m.BaseVersion); err != nil {
+		if err.Error() == "note version conflict" || errors.Is(err, gorm.ErrRecordNotFound) {
+			if current, err := dao.GetNoteForSync(existing.NoteID, userID); err == nil {
+				return conflict(current)
+			}
+		}
+		return reject("Failed to update note: " + err.Error())
+	}
+
+	result.Status = SyncApplied
+	result.Note = &note
+	return result
+}
+
+// createSyncedNote saves a note that was created offline
+func createSyncedNote(userID int64, m *NoteMutation, result NoteMutationResult) NoteMutationResult {
+	if m.BaseVersion != 0 {
+		result.Status = SyncRejected
+		result.Error = "Note not found"
+		return result
+	}
+
+	noteDate := userToday(userID)
+	if m.NoteDate != "" {
+		parsed, err := models.ParseDate(m.NoteDate)
+		if err != nil {
+			result.Status = SyncRejected
+			result.Error = err.Error()
+			return result
+		}
+		noteDate = parsed
+	}
+
+	// Another device may already have written a note for this day
+	existing, err := dao.GetNoteByUserAndDate(userID, noteDate)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		result.Status = SyncRejected
+		result.Error = "Failed to check for existing note: " + err.Error()
+		return result
+	}
+	if existing != nil {
+		result.Status = SyncConflict
+		result.NoteID = existing.NoteID
+		result.Note = existing
+		return result
+	}
+
+	now := time.Now().Unix()
+	clientID := m.ClientID
+	note := &models.Note{
+		UserID:    userID,
+		NoteDate:  noteDate,
+		Content:   m.Content,
+		PromptID:  promptIDValue(m.PromptID),
+		ClientID:  &clientID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	m.replace(note)
+
+	if err := dao.CreateNote(note); err != nil {
+		result.Status = SyncRejected
+		result.Error = "Failed to create note: " + err.Error()
+		return result
+	}
+
+	result.Status = SyncApplied
+	result.NoteID = note.NoteID
+	result.Note = note
+	return result
+}
+
+// applyNoteDeletion moves a note to the trash unless it changed since the client saw it.
+// Deleting a note that is already gone succeeds.
+func applyNoteDeletion(userID int64, m *NoteMutation, existing *models.Note, result NoteMutationResult) NoteMutationResult {
+	if existing == nil || existing.DeletedAt > 0 {
+		result.Status = SyncApplied
+		return result
+	}
+
+	result.NoteID = existing.NoteID
+	if m.BaseVersion != existing.Version {
+		result.Status = SyncConflict
+		result.Note = existing
+		return result
+	}
+
+	if err := dao.DeleteNoteAtVersion(existing.NoteID, userID, m.BaseVersion); err != nil {
+		if err.Error() == "note version conflict" {
+			if current, err := dao.GetNoteForSync(existing.NoteID, userID); err == nil {
+				result.Status = SyncConflict
+				result.Note = current
+				return result
+			}
+		}
+		result.Status = SyncRejected
+		result.Error = "Failed to delete note: " + err.Error()
+		return result
+	}
+
+	result.Status = SyncApplied
+	return result
+}
+
+// replace sets all check-in fields of a note to the client's values
+func (r *NoteCheckIn) replace(note *models.Note) {
+	note.PatientMood = nil
+	note.CaregiverMood = nil
+	note.SleepHours = r.SleepHours
+	note.EmotionTags = r.EmotionTags
+	r.apply(note)
+}
@@ -0,0 +1,289 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"hope_backend/dao"
+	"hope_backend/oauth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// fakeOIDCPath is where the fake identity provider is mounted when enabled
+const fakeOIDCPath = "/hope/dev/oidc"
+
+// linkTokenKey signs link tokens; it differs from jwtKey so they can never be used as login tokens
+var linkTokenKey = append([]byte("oauth-link:"), jwtKey...)
+
+// OAuthLoginRequest represents the request body for third-party login and linking
+type OAuthLoginRequest struct {
+	Credential string `json:"credential" binding:"required"` // Authorization code or ID token
+	DeviceName string `json:"device_name"`
+	Platform   string `json:"platform"`
+}
+
+// linkClaims carries a verified external identity until it is linked to a new account
+type linkClaims struct {
+	Provider string `json:"provider"`
+	Subject  string `json:"subject"`
+	Nickname string `json:"nickname"`
+	jwt.RegisteredClaims
+}
+
+// generateLinkToken signs a short-lived token for an identity that is not linked to any account yet
+func generateLinkToken(identity *oauth.Identity) (string, error) {
+	claims := &linkClaims{
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Nickname: identity.Nickname,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(15 * time.Minute)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(linkTokenKey)
+}
+
+// parseLinkToken validates a link token and returns the identity it carries
+func parseLinkToken(tokenString string) (*linkClaims, error) {
+	claims := &linkClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return linkTokenKey, nil
+	})
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid or expired link token")
+	}
+
+	return claims, nil
+}
+
+// OAuthLoginHandler logs in with an external identity provider.
+// If the identity is not linked yet, it responds 404 with a link_token that can be
+// passed to the registration endpoint to create an account and link it in one step.
+func OAuthLoginHandler(registry oauth.Registry, identityDAO *dao.IdentityDAO, profileDAO *dao.UserProfileDAO, sessionDAO *dao.SessionDAO, twoFactorDAO *dao.TwoFactorDAO, auditDAO *dao.AuditDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		connector, err := registry.Get(c.Param("provider"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, Response{
+				Success: false,
+				Message: err.Error(),
+			})
+			return
+		}
+
+		var req OAuthLoginRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+
+		identity, err := connector.Exchange(c.Request.Context(), req.Credential)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, Response{
+				Success: false,
+				Message: "Failed to verify identity: " + err.Error(),
+			})
+			return
+		}
+
+		linked, err := identityDAO.GetByProviderSubject(identity.Provider, identity.Subject)
+		if err != nil {
+			if err.Error() != "identity not found" {
+				c.JSON(http.StatusInternalServerError, Response{
+					Success: false,
+					Message: "Error looking up identity: " + err.Error(),
+				})
+				return
+			}
+
+			linkToken, err := generateLinkToken(identity)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, Response{
+					Success: false,
+					Message: "Failed to generate link token",
+				})
+				return
+			}
+
+			c.JSON(http.StatusNotFound, Response{
+				Success: false,
+				Message: "No account is linked to this identity",
+				Data: gin.H{
+					"link_token": linkToken,
+					"nickname":   identity.Nickname,
+					"avatar_url": identity.AvatarURL,
+				},
+			})
+			return
+		}
+
+		profile, err := profileDAO.GetByID(linked.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to retrieve user profile",
+			})
+			return
+		}
+
+		// Issue a token, or a challenge if the user has 2FA enabled
+		completeLogin(c, twoFactorDAO, sessionDAO, auditDAO, profile, req.DeviceName, req.Platform, "oauth:"+identity.Provider)
+	}
+}
+
+// ListIdentitiesHandler returns the external identities linked to the current user
+func ListIdentitiesHandler(identityDAO *dao.IdentityDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get authenticated user ID
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, Response{
+				Success: false,
+				Message: "Authentication required",
+			})
+			return
+		}
+
+		identities, err := identityDAO.ListByUser(userID.(int64))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to retrieve identities: " + err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, Response{
+			Success: true,
+			Data:    identities,
+		})
+	}
+}
+
+// LinkIdentityHandler links an external identity to the current user
+func LinkIdentityHandler(registry oauth.Registry, identityDAO *dao.IdentityDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get authenticated user ID
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, Response{
+				Success: false,
+				Message: "Authentication required",
+			})
+			return
+		}
+
+		connector, err := registry.Get(c.Param("provider"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, Response{
+				Success: false,
+				Message: err.Error(),
+			})
+			return
+		}
+
+		var req OAuthLoginRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+
+		identity, err := connector.Exchange(c.Request.Context(), req.Credential)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, Response{
+				Success: false,
+				Message: "Failed to verify identity: " + err.Error(),
+			})
+			return
+		}
+
+		linked := &dao.UserIdentity{
+			UserID:   userID.(int64),
+			Provider: identity.Provider,
+			Subject:  identity.Subject,
+			Nickname: identity.Nickname,
+		}
+		if err := identityDAO.Link(linked); err != nil {
+			status := http.StatusInternalServerError
+			if err.Error() == "identity already linked" {
+				status = http.StatusConflict
+			}
+			c.JSON(status, Response{
+				Success: false,
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusCreated, Response{
+			Success: true,
+			Message: "Identity linked successfully",
+			Data:    linked,
+		})
+	}
+}
+
+// UnlinkIdentityHandler removes an external identity from the current user
+func UnlinkIdentityHandler(identityDAO *dao.IdentityDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get authenticated user ID
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, Response{
+				Success: false,
+				Message: "Authentication required",
+			})
+			return
+		}
+
+		if err := identityDAO.Unlink(userID.(int64), c.Param("provider")); err != nil {
+			status := http.StatusInternalServerError
+			if err.Error() == "identity not found" {
+				status = http.StatusNotFound
+			}
+			c.JSON(status, Response{
+				Success: false,
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, Response{
+			Success: true,
+			Message: "Identity unlinked successfully",
+		})
+	}
+}
+
+// SetupFakeOIDCProvider mounts the fake identity provider when FAKE_OIDC_ISSUER is set.
+// The issuer must point at this server, e.g. http://127.0.0.1:12580/hope/dev/oidc
+func SetupFakeOIDCProvider(router *gin.Engine) {
+	issuer := os.Getenv("FAKE_OIDC_ISSUER")
+	if issuer == "" {
+		return
+	}
+
+	provider, err := oauth.NewFakeProvider(issuer)
+	if err != nil {
+		fmt.Printf("Warning: Failed to start fake OIDC provider: %v\n", err)
+		return
+	}
+
+	PublicGroup(&router.RouterGroup, fakeOIDCPath).Any("/*path", gin.WrapH(http.StripPrefix(fakeOIDCPath, provider)))
+	fmt.Printf("Fake OIDC provider enabled at %s\n", issuer)
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"hope_backend/dao"
+	"hope_backend/dao/daotest"
+	"hope_backend/oauth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// TestOAuthLoginLinkUnlink drives the third-party login endpoints against
+// the fake OpenID Connect provider and a stub database
+func TestOAuthLoginLinkUnlink(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const userID = 7
+
+	var provider *oauth.FakeProvider
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		provider.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	provider, err := oauth.NewFakeProvider(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := oauth.Registry{}
+	registry.Register(oauth.NewFakeConnector(server.URL))
+
+	stub := daotest.New(map[string][]daotest.Row{
+		"user_profiles": {{"id": int64(userID), "user_nickname": "hope", "role": RoleUser, "key_version": int64(0)}},
+	})
+	db := stub.Open(t)
+	identityDAO := dao.NewIdentityDAO(db)
+	profileDAO := dao.NewUserProfileDAO(db)
+	sessionDAO := dao.NewSessionDAO(db)
+	twoFactorDAO := dao.NewTwoFactorDAO(db)
+	auditDAO := dao.NewAuditDAO(db)
+
+	r := gin.New()
+	r.POST("/oauth/:provider/login", OAuthLoginHandler(registry, identityDAO, profileDAO, sessionDAO, twoFactorDAO, auditDAO))
+	user := r.Group("", func(c *gin.Context) { c.Set("userID", int64(userID)) })
+	user.POST("/identities/:provider", LinkIdentityHandler(registry, identityDAO))
+	user.DELETE("/identities/:provider", UnlinkIdentityHandler(identityDAO))
+
+	call := func(method, path string, body interface{}) (int, Response) {
+		t.Helper()
+		var payload bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&payload).Encode(body)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, &payload))
+
+		var resp Response
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s %s: %v: %s", method, path, err, w.Body)
+		}
+		return w.Code, resp
+	}
+	login := func() (int, Response) {
+		t.Helper()
+		return call(http.MethodPost, "/oauth/fake/login", OAuthLoginRequest{Credential: provider.IssueCode("alice", "Alice")})
+	}
+
+	// An identity nobody linked yet gets a link token for registration
+	status, resp := login()
+	if status != http.StatusNotFound {
+		t.Fatalf("login before linking: status %d, want 404: %+v", status, resp)
+	}
+	data, _ := resp.Data.(map[string]interface{})
+	linkToken, _ := data["link_token"].(string)
+	claims, err := parseLinkToken(linkToken)
+	if err != nil {
+		t.Fatalf("login before linking: link token %q: %v", linkToken, err)
+	}
+	if claims.Provider != "fake" || claims.Subject != "alice" || claims.Nickname != "Alice" {
+		t.Errorf("link token carries %+v", claims)
+	}
+	if _, err := parseChallengeToken(linkToken); err == nil {
+		t.Error("link token accepted as a challenge token")
+	}
+
+	// Codes the provider never issued are refused
+	if status, _ := call(http.MethodPost, "/oauth/fake/login", OAuthLoginRequest{Credential: "forged"}); status != http.StatusUnauthorized {
+		t.Errorf("forged code: status %d, want 401", status)
+	}
+
+	status, resp = call(http.MethodPost, "/identities/fake", OAuthLoginRequest{Credential: provider.IssueCode("alice", "Alice")})
+	if status != http.StatusCreated {
+		t.Fatalf("link: status %d, want 201: %+v", status, resp)
+	}
+	if status, _ := call(http.MethodPost, "/identities/fake", OAuthLoginRequest{Credential: provider.IssueCode("bob", "Bob")}); status != http.StatusConflict {
+		t.Errorf("second identity of the same provider: status %d, want 409", status)
+	}
+
+	// Once linked, the identity logs in to the account
+	status, resp = login()
+	if status != http.StatusOK {
+		t.Fatalf("login after linking: status %d, want 200: %+v", status, resp)
+	}
+	data, _ = resp.Data.(map[string]interface{})
+	token, _ := data["token"].(string)
+	tokenClaims := &Claims{}
+	if _, err := jwt.ParseWithClaims(token, tokenClaims, func(*jwt.Token) (interface{}, error) { return jwtKey, nil }); err != nil {
+		t.Fatalf("login after linking: token %q: %v", token, err)
+	}
+	if tokenClaims.UserID != userID {
+		t.Errorf("logged in as user %d, want %d", tokenClaims.UserID, userID)
+	}
+	if sessions := stub.Rows("user_sessions"); len(sessions) != 1 || sessions[0]["token_id"] != tokenClaims.ID {
+		t.Errorf("sessions %v, want the session of the new token", sessions)
+	}
+
+	status, resp = call(http.MethodDelete, "/identities/fake", nil)
+	if status != http.StatusOK {
+		t.Fatalf("unlink: status %d, want 200: %+v", status, resp)
+	}
+	if status, _ := call(http.MethodDelete, "/identities/fake", nil); status != http.StatusNotFound {
+		t.Errorf("second unlink: status %d, want 404", status)
+	}
+	if status, _ := login(); status != http.StatusNotFound {
+		t.Errorf("login after unlinking: status %d, want 404", status)
+	}
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// User roles stored in user_profiles.role and carried in the JWT
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Action is something a user may want to do to a resource
+type Action string
+
+const (
+	ActionUpdatePost    Action = "post:update"
+	ActionDeletePost    Action = "post:delete"
+	ActionDeleteComment Action = "comment:delete"
+	ActionViewHidden    Action = "content:view_hidden" // Read a post hidden after reports
+	ActionManageUsers   Action = "user:manage"
+)
+
+// moderatorActions are allowed for moderators on any user's content
+var moderatorActions = map[Action]bool{
+	ActionDeletePost:    true,
+	ActionDeleteComment: true,
+	ActionViewHidden:    true,
+}
+
+// isValidRole checks if the role is one of the known roles
+func isValidRole(role string) bool {
+	return role == RoleUser || role == RoleModerator || role == RoleAdmin
+}
+
+// Can reports whether a user with the given role may perform action on a resource owned by ownerID.
+// Owners may act on their own content; moderators may remove any content; admins may do anything.
+func Can(userID int64, role string, action Action, ownerID int64) bool {
+	switch role {
+	case RoleAdmin:
+		return true
+	case RoleModerator:
+		if moderatorActions[action] {
+			return true
+		}
+	}
+
+	if action == ActionManageUsers {
+		return false
+	}
+
+	return ownerID != 0 && userID == ownerID
+}
+
+// currentRole returns the role of the authenticated user, defaulting to RoleUser
+func currentRole(c *gin.Context) string {
+	role, _ := c.Get("role")
+	if r, ok := role.(string); ok && r != "" {
+		return r
+	}
+	return RoleUser
+}
+
+// canAccess applies Can to the authenticated user of the request
+func canAccess(c *gin.Context, action Action, ownerID int64) bool {
+	userID, _ := c.Get("userID")
+	id, _ := userID.(int64)
+	return Can(id, currentRole(c), action, ownerID)
+}
+
+// RequireRole aborts the request unless the authenticated user has one of the roles
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := currentRole(c)
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, Response{
+			Success: false,
+			Message: "You do not have permission to access this resource",
+		})
+		c.Abort()
+	}
+}
@@ -11,7 +11,6 @@ import (
 	"hope_backend/dao"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 )
 
 // PostRequest represents the request body for creating a post
@@ -21,8 +20,9 @@ type PostRequest struct {
 
 // CommentRequest represents the request body for creating a comment
 type CommentRequest struct {
-	Content  string `json:"content" binding:"required"`
-	ParentID *int64 `json:"parent_id"`
+	Content   string `json:"content" binding:"required"`
+	ParentID  *int64 `json:"parent_id"`
+	Anonymous bool   `json:"anonymous"` // Show the comment under a pseudonym
 }
 
 const (
@@ -30,8 +30,6 @@ const (
 	maxPageSize       = 50
 	uploadDir         = "uploads/posts"
 	maxImageDimension = 1920 // Max width/height for full images
-	thumbnailWidth    = 800  // Width for thumbnails
-	thumbnailPrefix   = "thumb_"
 )
 
 // Initialize the upload directory
@@ -73,6 +71,19 @@ func CreatePostHandler(postDAO *dao.PostDAO) gin.HandlerFunc {
 			return
 		}
 
+		// Anonymous posts are shown under a pseudonym
+		anonymous := false
+		if value := c.Request.FormValue("anonymous"); value != "" {
+			var err error
+			if anonymous, err = strconv.ParseBool(value); err != nil {
+				c.JSON(http.StatusBadRequest, Response{
+					Success: false,
+					Message: "Invalid anonymous flag",
+				})
+				return
+			}
+		}
+
 		// Get images (up to 9)
 		form, _ := c.MultipartForm()
 		files := form.File["images"]
@@ -100,14 +111,19 @@ func CreatePostHandler(postDAO *dao.PostDAO) gin.HandlerFunc {
 				return
 			}
 
-			// Generate unique filename
-			uniqueID := uuid.New().String()
-			newFilename := uniqueID + ext
-			filePath := filepath.Join(uploadDir, newFilename)
-			relativePath := filepath.Join("posts", newFilename) // Store relative path in DB
+			src, err := file.Open()
+			if err != nil {
+				c.JSON(http.StatusBadRequest, Response{
+					Success: false,
+					Message: "Error retrieving image: " + err.Error(),
+				})
+				return
+			}
 
-			// Save the uploaded file
-			if err := c.SaveUploadedFile(file, filePath); err != nil {
+			// Post images are not named after the user, so anonymous posts do not reveal their author
+			newFilename, err := storeImage(src, ext, uploadDir, 0)
+			src.Close()
+			if err != nil {
 				c.JSON(http.StatusInternalServerError, Response{
 					Success: false,
 					Message: "Failed to save image: " + err.Error(),
@@ -115,31 +131,25 @@ func CreatePostHandler(postDAO *dao.PostDAO) gin.HandlerFunc {
 				return
 			}
 
-			// Optimize the image (resize and compress)
-			if err := optimizeImage(filePath, filePath, maxImageDimension); err != nil {
-				fmt.Printf("Warning: Image optimization failed for %s: %v\n", newFilename, err)
-				// Continue even if optimization fails
-			}
-
-			// Generate thumbnail
-			thumbnailFilename := thumbnailPrefix + newFilename
-			thumbnailPath := filepath.Join(uploadDir, thumbnailFilename)
-			if err := createThumbnail(filePath, thumbnailPath, thumbnailWidth); err != nil {
-				fmt.Printf("Warning: Thumbnail creation failed for %s: %v\n", newFilename, err)
-				// Continue even if thumbnail creation fails
-			}
-
-			imagePaths = append(imagePaths, relativePath)
+			imagePaths = append(imagePaths, filepath.Join("posts", newFilename)) // Store relative path in DB
 		}
 
 		// Create the post
 		post := &dao.Post{
-			UserID:  userID.(int64),
-			Content: content,
+			UserID:    userID.(int64),
+			Content:   content,
+			Anonymous: anonymous,
 		}
 
 		postID, err := postDAO.Create(post, imagePaths)
 		if err != nil {
+			if err.Error() == "banned from the community" {
+				c.JSON(http.StatusForbidden, Response{
+					Success: false,
+					Message: "You are banned from posting in the community",
+				})
+				return
+			}
 			c.JSON(http.StatusInternalServerError, Response{
 				Success: false,
 				Message: "Failed to create post: " + err.Error(),
@@ -189,16 +199,8 @@ func GetPostHandler(postDAO *dao.PostDAO) gin.HandlerFunc {
 		}
 
 		// Get post with images
-		post, err := postDAO.GetByID(postID, userID.(int64))
-		if err != nil {
-			status := http.StatusInternalServerError
-			if err.Error() == "post not found" {
-				status = http.StatusNotFound
-			}
-			c.JSON(status, Response{
-				Success: false,
-				Message: err.Error(),
-			})
+		post := findVisiblePost(c, postDAO, postID, userID.(int64))
+		if post == nil {
 			return
 		}
 
@@ -209,7 +211,37 @@ func GetPostHandler(postDAO *dao.PostDAO) gin.HandlerFunc {
 	}
 }
 
-// ListPostsHandler handles GET requests to list posts with pagination
+// findVisiblePost loads a post for the current user. It responds with 404 and
+// returns nil if the post does not exist or is hidden: hidden posts are only
+// shown to their author and moderators.
+func findVisiblePost(c *gin.Context, postDAO *dao.PostDAO, postID, userID int64) *dao.Post {
+	post, err := postDAO.GetByID(postID, userID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err.Error() == "post not found" {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, Response{
+			Success: false,
+			Message: err.Error(),
+		})
+		return nil
+	}
+
+	if post.Hidden && !canAccess(c, ActionViewHidden, post.UserID) {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Message: "post not found",
+		})
+		return nil
+	}
+	return post
+}
+
+// ListPostsHandler handles GET requests to list posts with pagination. With
+// ?cursor= (empty for the first page) pages are read after the next_cursor of
+// the previous response, which keeps scrolling stable while posts are added;
+// otherwise ?page= selects a page by offset.
 func ListPostsHandler(postDAO *dao.PostDAO) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get authenticated user ID
@@ -239,6 +271,28 @@ func ListPostsHandler(postDAO *dao.PostDAO) gin.HandlerFunc {
 		// Parse filter by user ID
 		filterUserID, _ := strconv.ParseInt(c.Query("user_id"), 10, 64)
 
+		// Cursor pagination, used when the cursor parameter is present
+		if cursor, useCursor, ok := pageCursorParam(c); !ok {
+			return
+		} else if useCursor {
+			posts, next, err := postDAO.ListPostsAfter(cursor, pageSize, filterUserID, userID.(int64))
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, Response{
+					Success: false,
+					Message: "Failed to retrieve posts: " + err.Error(),
+				})
+				return
+			}
+
+			c.JSON(http.StatusOK, Response{
+				Success:    true,
+				Data:       posts,
+				Size:       pageSize,
+				NextCursor: cursorString(next),
+			})
+			return
+		}
+
 		// Get posts with pagination
 		posts, total, err := postDAO.ListPosts(page, pageSize, filterUserID, userID.(int64))
 		if err != nil {
@@ -296,8 +350,8 @@ func UpdatePostHandler(postDAO *dao.PostDAO) gin.HandlerFunc {
 			return
 		}
 
-		// Check if user is the owner of the post
-		if post.UserID != userID.(int64) {
+		// Check if user may update the post
+		if !canAccess(c, ActionUpdatePost, post.UserID) {
 			c.JSON(http.StatusForbidden, Response{
 				Success: false,
 				Message: "You do not have permission to update this post",
@@ -335,7 +389,7 @@ func UpdatePostHandler(postDAO *dao.PostDAO) gin.HandlerFunc {
 }
 
 // DeletePostHandler handles DELETE requests to delete a post
-func DeletePostHandler(postDAO *dao.PostDAO) gin.HandlerFunc {
+func DeletePostHandler(postDAO *dao.PostDAO, auditDAO *dao.AuditDAO) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get authenticated user ID
 		userID, exists := c.Get("userID")
@@ -357,7 +411,7 @@ func DeletePostHandler(postDAO *dao.PostDAO) gin.HandlerFunc {
 			return
 		}
 
-		// Get the post to check ownership
+		// Get the post to check permissions
 		post, err := postDAO.GetByID(postID, userID.(int64))
 		if err != nil {
 			status := http.StatusInternalServerError
@@ -371,8 +425,8 @@ func DeletePostHandler(postDAO *dao.PostDAO) gin.HandlerFunc {
 			return
 		}
 
-		// Check if user is the owner of the post
-		if post.UserID != userID.(int64) {
+		// Owners and moderators may delete the post
+		if !canAccess(c, ActionDeletePost, post.UserID) {
 			c.JSON(http.StatusForbidden, Response{
 				Success: false,
 				Message: "You do not have permission to delete this post",
@@ -381,22 +435,7 @@ func DeletePostHandler(postDAO *dao.PostDAO) gin.HandlerFunc {
 		}
 
 		// Delete images and thumbnails from filesystem
-		for _, image := range post.Images {
-			// Convert DB path to filesystem path
-			filePath := filepath.Join("uploads", image.ImagePath)
-
-			// Delete main image
-			if err := os.Remove(filePath); err != nil {
-				fmt.Printf("Warning: Could not remove image %s: %v\n", filePath, err)
-			}
-
-			// Delete thumbnail
-			filename := filepath.Base(filePath)
-			thumbnailPath := filepath.Join(filepath.Dir(filePath), thumbnailPrefix+filename)
-			if err := os.Remove(thumbnailPath); err != nil {
-				fmt.Printf("Info: Could not remove thumbnail %s: %v\n", thumbnailPath, err)
-			}
-		}
+		removePostImageFiles(post.Images)
 
 		// Delete the post and all related data
 		if err := postDAO.Delete(postID); err != nil {
@@ -407,6 +446,11 @@ func DeletePostHandler(postDAO *dao.PostDAO) gin.HandlerFunc {
 			return
 		}
 
+		// Removing someone else's post is a moderation action
+		if post.UserID != userID.(int64) {
+			recordAudit(c, auditDAO, AuditModeratorDeletePost, post.UserID, gin.H{"post_id": postID})
+		}
+
 		c.JSON(http.StatusOK, Response{
 			Success: true,
 			Message: "Post deleted successfully",
@@ -414,6 +458,26 @@ func DeletePostHandler(postDAO *dao.PostDAO) gin.HandlerFunc {
 	}
 }
 
+// removePostImageFiles deletes the files of post images and their thumbnails
+func removePostImageFiles(images []dao.PostImage) {
+	for _, image := range images {
+		// Convert DB path to filesystem path
+		filePath := filepath.Join("uploads", image.ImagePath)
+
+		// Delete main image
+		if err := os.Remove(filePath); err != nil {
+			fmt.Printf("Warning: Could not remove image %s: %v\n", filePath, err)
+		}
+
+		// Delete thumbnail
+		filename := filepath.Base(filePath)
+		thumbnailPath := filepath.Join(filepath.Dir(filePath), ThumbnailPrefix+filename)
+		if err := os.Remove(thumbnailPath); err != nil {
+			fmt.Printf("Info: Could not remove thumbnail %s: %v\n", thumbnailPath, err)
+		}
+	}
+}
+
 // LikePostHandler handles POST requests to like a post
 func LikePostHandler(postDAO *dao.PostDAO) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -437,6 +501,10 @@ func LikePostHandler(postDAO *dao.PostDAO) gin.HandlerFunc {
 			return
 		}
 
+		if findVisiblePost(c, postDAO, postID, userID.(int64)) == nil {
+			return
+		}
+
 		// Add like
 		err = postDAO.LikePost(postID, userID.(int64))
 		if err != nil {
@@ -486,6 +554,10 @@ func UnlikePostHandler(postDAO *dao.PostDAO) gin.HandlerFunc {
 			return
 		}
 
+		if findVisiblePost(c, postDAO, postID, userID.(int64)) == nil {
+			return
+		}
+
 		// Remove like
 		err = postDAO.UnlikePost(postID, userID.(int64))
 		if err != nil {
@@ -511,3 +583,34 @@ func UnlikePostHandler(postDAO *dao.PostDAO) gin.HandlerFunc {
 		})
 	}
 }
+
+// pageCursorParam reads the cursor query parameter. useCursor reports whether
+// cursor pagination was asked for; an empty cursor means the first page.
+// It responds and returns ok false if the cursor is invalid.
+func pageCursorParam(c *gin.Context) (cursor *dao.PageCursor, useCursor bool, ok bool) {
+	value, present := c.GetQuery("cursor")
+	if !present {
+		return nil, false, true
+	}
+	if value == "" {
+		return nil, true, true
+	}
+
+	cursor, err := dao.ParsePageCursor(value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "Invalid cursor",
+		})
+		return nil, true, false
+	}
+	return cursor, true, true
+}
+
+// cursorString encodes the cursor of the next page, empty on the last page
+func cursorString(cursor *dao.PageCursor) string {
+	if cursor == nil {
+		return ""
+	}
+	return cursor.String()
+}
@@ -0,0 +1,461 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"hope_backend/dao"
+	"hope_backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultPromptLocale is used when the client's language has no prompts
+	defaultPromptLocale = "zh-CN"
+	// promptRepeatWindowDays is how long an answered prompt is not offered again
+	promptRepeatWindowDays = 30
+	// maxNoteTemplates limits how many templates a user can save
+	maxNoteTemplates = 50
+	// maxTemplateNameRunes and maxTemplateContentRunes match the note_templates columns
+	maxTemplateNameRunes    = 50
+	maxTemplateContentRunes = 1000
+)
+
+// PromptCategories are the categories of the prompt library
+var PromptCategories = []string{"gratitude", "coping", "reflection", "self_care", "connection"}
+
+// NoteTemplateRequest is the expected request body for creating or updating a template
+type NoteTemplateRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Content  string `json:"content" binding:"required"`
+	PromptID *int   `json:"prompt_id"`
+}
+
+// ListPromptsHandler returns the prompt library for the client's locale.
+// Query parameters: locale (default: from Accept-Language) and category.
+func ListPromptsHandler(c *gin.Context) {
+	category, ok := promptCategoryParam(c)
+	if !ok {
+		return
+	}
+
+	locale, err := resolvePromptLocale(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to retrieve prompts: " + err.Error(),
+		})
+		return
+	}
+
+	prompts, err := dao.GetPrompts(locale, category)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to retrieve prompts: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Prompts retrieved successfully",
+		Data: gin.H{
+			"locale":     locale,
+			"categories": PromptCategories,
+			"prompts":    prompts,
+		},
+	})
+}
+
+// GetDailyPromptHandler returns today's prompt for the current user. The
+// prompt stays the same all day, rotates through the whole library before
+// repeating, and skips prompts the user answered in the last 30 days.
+func GetDailyPromptHandler(c *gin.Context) {
+	// Get user ID from context or session
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Message: "Unauthorized: User not authenticated",
+		})
+		return
+	}
+
+	category, ok := promptCategoryParam(c)
+	if !ok {
+		return
+	}
+
+	locale, err := resolvePromptLocale(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to retrieve prompts: " + err.Error(),
+		})
+		return
+	}
+
+	prompts, err := dao.GetPrompts(locale, category)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to retrieve prompts: " + err.Error(),
+		})
+		return
+	}
+	if len(prompts) == 0 {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Message: "No prompts available",
+		})
+		return
+	}
+
+	// Notes written today do not count, so the prompt does not change once answered
+	today := userToday(userID.(int64))
+	answered, err := dao.GetAnsweredPromptIDs(userID.(int64), today.AddDays(-promptRepeatWindowDays), today)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to retrieve prompts: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Daily prompt retrieved successfully",
+		Data:    dailyPrompt(userID.(int64), prompts, today, answered),
+	})
+}
+
+// dailyPrompt picks the prompt for a day. Each user gets their own fixed
+// order of the prompts, and consecutive days take consecutive prompts from it.
+func dailyPrompt(userID int64, prompts []models.JournalPrompt, day models.Date, answered []int) models.JournalPrompt {
+	order := make([]models.JournalPrompt, len(prompts))
+	copy(order, prompts)
+	rank := func(p models.JournalPrompt) uint64 {
+		h := fnv.New64a()
+		fmt.Fprintf(h, "%d:%d", userID, p.PromptID)
+		return h.Sum64()
+	}
+	sort.Slice(order, func(i, j int) bool { return rank(order[i]) < rank(order[j]) })
+
+	skip := make(map[int]bool, len(answered))
+	for _, id := range answered {
+		skip[id] = true
+	}
+
+	start := int(day.Unix()/86400) % len(order)
+	for i := 0; i < len(order); i++ {
+		if prompt := order[(start+i)%len(order)]; !skip[prompt.PromptID] {
+			return prompt
+		}
+	}
+	// Everything was answered recently; repeat rather than return nothing
+	return order[start]
+}
+
+// promptCategoryParam validates the optional ?category=; it responds and returns false if invalid
+func promptCategoryParam(c *gin.Context) (string, bool) {
+	category := c.Query("category")
+	if category == "" {
+		return "", true
+	}
+	for _, known := range PromptCategories {
+		if category == known {
+			return category, true
+		}
+	}
+
+	c.JSON(http.StatusBadRequest, Response{
+		Success: false,
+		Message: "Invalid category. Supported values: " + strings.Join(PromptCategories, ", "),
+	})
+	return "", false
+}
+
+// resolvePromptLocale picks the best available prompt locale for ?locale= or the
+// Accept-Language header: an exact match, then a match on the language alone,
+// then the default locale
+func resolvePromptLocale(c *gin.Context) (string, error) {
+	available, err := dao.GetPromptLocales()
+	if err != nil {
+		return "", err
+	}
+
+	var wanted []string
+	if locale := c.Query("locale"); locale != "" {
+		wanted = append(wanted, locale)
+	}
+	for _, part := range strings.Split(c.GetHeader("Accept-Language"), ",") {
+		if tag := strings.TrimSpace(strings.SplitN(part, ";", 2)[0]); tag != "" && tag != "*" {
+			wanted = append(wanted, tag)
+		}
+	}
+
+	for _, tag := range wanted {
+		tag = strings.ReplaceAll(tag, "_", "-")
+		for _, locale := range available {
+			if strings.EqualFold(tag, locale) {
+				return locale, nil
+			}
+		}
+		language := strings.SplitN(tag, "-", 2)[0]
+		for _, locale := range available {
+			if strings.EqualFold(language, strings.SplitN(locale, "-", 2)[0]) {
+				return locale, nil
+			}
+		}
+	}
+	return defaultPromptLocale, nil
+}
+
+// validPromptID checks that an optional prompt ID refers to a prompt; 0 clears the prompt
+func validPromptID(promptID *int) error {
+	if promptID == nil || *promptID == 0 {
+		return nil
+	}
+	if _, err := dao.GetPromptByID(*promptID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("prompt_id does not refer to a prompt")
+		}
+		return err
+	}
+	return nil
+}
+
+// promptIDValue turns the 0 used by clients to clear a prompt into nil
+func promptIDValue(promptID *int) *int {
+	if promptID == nil || *promptID == 0 {
+		return nil
+	}
+	return promptID
+}
+
+// ListNoteTemplatesHandler returns the templates of the current user
+func ListNoteTemplatesHandler(c *gin.Context) {
+	// Get user ID from context or session
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Message: "Unauthorized: User not authenticated",
+		})
+		return
+	}
+
+	templates, err := dao.GetNoteTemplates(userID.(int64))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to retrieve templates: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Templates retrieved successfully",
+		Data:    templates,
+	})
+}
+
+// CreateNoteTemplateHandler saves a new template for the current user
+func CreateNoteTemplateHandler(c *gin.Context) {
+	// Get user ID from context or session
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Message: "Unauthorized: User not authenticated",
+		})
+		return
+	}
+
+	req, ok := bindNoteTemplateRequest(c)
+	if !ok {
+		return
+	}
+
+	count, err := dao.CountNoteTemplates(userID.(int64))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to create template: " + err.Error(),
+		})
+		return
+	}
+	if count >= maxNoteTemplates {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: fmt.Sprintf("You can save at most %d templates", maxNoteTemplates),
+		})
+		return
+	}
+
+	now := time.Now().Unix()
+	template := &models.NoteTemplate{
+		UserID:    userID.(int64),
+		Name:      req.Name,
+		Content:   req.Content,
+		PromptID:  promptIDValue(req.PromptID),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := dao.CreateNoteTemplate(template); err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to create template: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Message: "Template created successfully",
+		Data:    template,
+	})
+}
+
+// UpdateNoteTemplateHandler replaces the name, content and prompt of a template
+func UpdateNoteTemplateHandler(c *gin.Context) {
+	// Get user ID from context or session
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Message: "Unauthorized: User not authenticated",
+		})
+		return
+	}
+
+	templateID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "Invalid template ID",
+		})
+		return
+	}
+
+	req, ok := bindNoteTemplateRequest(c)
+	if !ok {
+		return
+	}
+
+	template, err := dao.GetNoteTemplate(templateID, userID.(int64))
+	if err != nil {
+		status := http.StatusInternalServerError
+		message := "Failed to retrieve template: " + err.Error()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			status, message = http.StatusNotFound, "Template not found"
+		}
+		c.JSON(status, Response{
+			Success: false,
+			Message: message,
+		})
+		return
+	}
+
+	template.Name = req.Name
+	template.Content = req.Content
+	template.PromptID = promptIDValue(req.PromptID)
+	template.UpdatedAt = time.Now().Unix()
+	if err := dao.UpdateNoteTemplate(template); err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to update template: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Template updated successfully",
+		Data:    template,
+	})
+}
+
+// DeleteNoteTemplateHandler removes a template of the current user
+func DeleteNoteTemplateHandler(c *gin.Context) {
+	// Get user ID from context or session
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Message: "Unauthorized: User not authenticated",
+		})
+		return
+	}
+
+	templateID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "Invalid template ID",
+		})
+		return
+	}
+
+	if err := dao.DeleteNoteTemplate(templateID, userID.(int64)); err != nil {
+		status := http.StatusInternalServerError
+		message := "Failed to delete template: " + err.Error()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			status, message = http.StatusNotFound, "Template not found"
+		}
+		c.JSON(status, Response{
+			Success: false,
+			Message: message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Template deleted successfully",
+	})
+}
+
+// bindNoteTemplateRequest parses and validates a template body; it responds and returns false if invalid
+func bindNoteTemplateRequest(c *gin.Context) (*NoteTemplateRequest, bool) {
+	var req NoteTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return nil, false
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	var err error
+	switch {
+	case req.Name == "":
+		err = errors.New("name must not be empty")
+	case len([]rune(req.Name)) > maxTemplateNameRunes:
+		err = fmt.Errorf("name can be at most %d characters", maxTemplateNameRunes)
+	case len([]rune(req.Content)) > maxTemplateContentRunes:
+		err = fmt.Errorf("content can be at most %d characters", maxTemplateContentRunes)
+	default:
+		err = validPromptID(req.PromptID)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return nil, false
+	}
+
+	return &req, true
+}
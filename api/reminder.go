@@ -0,0 +1,398 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"hope_backend/dao"
+	"hope_backend/models"
+	"hope_backend/notify"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// NoteReminderInterval is how often the reminder job checks for due reminders
+	NoteReminderInterval = time.Minute
+	// reminderCatchUpWindow is how late a reminder may still be sent, e.g. after a restart
+	reminderCatchUpWindow = time.Hour
+	// defaultReminderTime is suggested to users who have not set up reminders
+	defaultReminderTime = "21:00"
+	// reminderTimeLayout is the format of ReminderSetting.TimeOfDay
+	reminderTimeLayout = "15:04"
+	// maxPushTokenLength matches the token column
+	maxPushTokenLength = 255
+)
+
+// UpdateReminderSettingsRequest is the expected request body for changing reminder settings
+type UpdateReminderSettingsRequest struct {
+	Enabled   bool   `json:"enabled"`
+	TimeOfDay string `json:"time_of_day" binding:"required"` // "HH:MM"
+	Timezone  string `json:"timezone"`                       // Empty to follow the profile timezone
+	Weekdays  []int  `json:"weekdays"`                       // 0 = Sunday ... 6 = Saturday, empty for every day
+}
+
+// RegisterPushDeviceRequest is the expected request body for registering a push token
+type RegisterPushDeviceRequest struct {
+	Platform string `json:"platform" binding:"required"` // ios or android
+	Token    string `json:"token" binding:"required"`
+}
+
+// everyWeekday is the default reminder schedule
+var everyWeekday = []int{0, 1, 2, 3, 4, 5, 6}
+
+// GetReminderSettingsHandler returns the reminder settings of the current user
+func GetReminderSettingsHandler(reminderDAO *dao.ReminderDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get authenticated user ID
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, Response{
+				Success: false,
+				Message: "Authentication required",
+			})
+			return
+		}
+
+		setting, err := reminderDAO.GetSetting(userID.(int64))
+		if err != nil {
+			if err.Error() != "reminder setting not found" {
+				c.JSON(http.StatusInternalServerError, Response{
+					Success: false,
+					Message: "Failed to retrieve reminder settings: " + err.Error(),
+				})
+				return
+			}
+			// Not set up yet, suggest the defaults
+			setting = &dao.ReminderSetting{TimeOfDay: defaultReminderTime, Weekdays: everyWeekday}
+		}
+
+		c.JSON(http.StatusOK, Response{
+			Success: true,
+			Message: "Reminder settings retrieved successfully",
+			Data:    setting,
+		})
+	}
+}
+
+// UpdateReminderSettingsHandler changes the reminder settings of the current user
+func UpdateReminderSettingsHandler(reminderDAO *dao.ReminderDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get authenticated user ID
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, Response{
+				Success: false,
+				Message: "Authentication required",
+			})
+			return
+		}
+
+		var req UpdateReminderSettingsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+
+		scheduled, err := time.Parse(reminderTimeLayout, req.TimeOfDay)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid time_of_day, expected HH:MM",
+			})
+			return
+		}
+
+		if req.Timezone != "" {
+			if _, err := loadTimezone(req.Timezone); err != nil {
+				c.JSON(http.StatusBadRequest, Response{
+					Success: false,
+					Message: "Invalid timezone: " + req.Timezone,
+				})
+				return
+			}
+		}
+
+		weekdays, err := normalizeWeekdays(req.Weekdays)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: err.Error(),
+			})
+			return
+		}
+
+		setting := &dao.ReminderSetting{
+			UserID:    userID.(int64),
+			Enabled:   req.Enabled,
+			TimeOfDay: scheduled.Format(reminderTimeLayout),
+			Timezone:  req.Timezone,
+			Weekdays:  weekdays,
+		}
+		if existing, err := reminderDAO.GetSetting(userID.(int64)); err == nil {
+			setting.CreatedAt = existing.CreatedAt
+			setting.LastSentAt = existing.LastSentAt
+		}
+
+		if err := reminderDAO.SaveSetting(setting); err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to update reminder settings: " + err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, Response{
+			Success: true,
+			Message: "Reminder settings updated successfully",
+			Data:    setting,
+		})
+	}
+}
+
+// RegisterPushDeviceHandler stores the push token of the app install making the request
+func RegisterPushDeviceHandler(reminderDAO *dao.ReminderDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get authenticated user ID
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, Response{
+				Success: false,
+				Message: "Authentication required",
+			})
+			return
+		}
+
+		var req RegisterPushDeviceRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+
+		if req.Platform != notify.PlatformIOS && req.Platform != notify.PlatformAndroid {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid platform. Supported values: ios, android",
+			})
+			return
+		}
+		if len(req.Token) > maxPushTokenLength {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid push token",
+			})
+			return
+		}
+
+		device, err := reminderDAO.RegisterDevice(userID.(int64), req.Platform, req.Token)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to register device: " + err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, Response{
+			Success: true,
+			Message: "Device registered successfully",
+			Data:    device,
+		})
+	}
+}
+
+// UnregisterPushDeviceHandler removes a push token, e.g. on logout
+func UnregisterPushDeviceHandler(reminderDAO *dao.ReminderDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get authenticated user ID
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, Response{
+				Success: false,
+				Message: "Authentication required",
+			})
+			return
+		}
+
+		if err := reminderDAO.UnregisterDevice(userID.(int64), c.Param("token")); err != nil {
+			if err.Error() == "push device not found" {
+				c.JSON(http.StatusNotFound, Response{
+					Success: false,
+					Message: "Device not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to unregister device: " + err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, Response{
+			Success: true,
+			Message: "Device unregistered successfully",
+		})
+	}
+}
+
+// NoteReminderJob returns the scheduled job that reminds users who have not
+// written today's note once their reminder time has passed
+func NoteReminderJob(reminderDAO *dao.ReminderDAO, notifier notify.Notifier) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		settings, err := reminderDAO.ListEnabled()
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		sent := 0
+		for i := range settings {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			setting := &settings[i]
+			loc := reminderLocation(setting)
+			if !reminderDue(setting, now.In(loc)) {
+				continue
+			}
+
+			today := models.DateOf(now.In(loc))
+			if _, err := dao.GetNoteByUserAndDate(setting.UserID, today); err == nil {
+				continue // Already written
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				fmt.Printf("[Note Reminder] Failed to check note of user %d: %v\n", setting.UserID, err)
+				continue
+			}
+
+			err := notifier.Notify(ctx, notify.Notification{
+				UserID:   setting.UserID,
+				Category: "note_reminder",
+				Title:    "今天的日记还没写",
+				Body:     "花几分钟记录一下今天吧",
+				Data:     map[string]string{"note_date": today.String()},
+			})
+			if err != nil {
+				fmt.Printf("[Note Reminder] Failed to enqueue reminder for user %d: %v\n", setting.UserID, err)
+				continue
+			}
+
+			if err := reminderDAO.MarkSent(setting.UserID, now.UnixMilli()); err != nil {
+				fmt.Printf("[Note Reminder] Failed to record reminder for user %d: %v\n", setting.UserID, err)
+			}
+			sent++
+		}
+
+		if sent > 0 {
+			fmt.Printf("[Note Reminder] Enqueued %d reminders\n", sent)
+		}
+		return nil
+	}
+}
+
+// reminderLocation returns the timezone a reminder is scheduled in
+func reminderLocation(setting *dao.ReminderSetting) *time.Location {
+	if setting.Timezone != "" {
+		if loc, err := loadTimezone(setting.Timezone); err == nil {
+			return loc
+		}
+	}
+	return userLocation(setting.UserID)
+}
+
+// reminderDue reports whether a reminder should be sent at the given local
+// time: on a selected weekday, within the catch-up window after the reminder
+// time, and not already sent that day
+func reminderDue(setting *dao.ReminderSetting, local time.Time) bool {
+	if len(setting.Weekdays) > 0 {
+		selected := false
+		for _, day := range setting.Weekdays {
+			if time.Weekday(day) == local.Weekday() {
+				selected = true
+				break
+			}
+		}
+		if !selected {
+			return false
+		}
+	}
+
+	t, err := time.Parse(reminderTimeLayout, setting.TimeOfDay)
+	if err != nil {
+		return false
+	}
+	scheduled := time.Date(local.Year(), local.Month(), local.Day(), t.Hour(), t.Minute(), 0, 0, local.Location())
+	if local.Before(scheduled) || local.Sub(scheduled) >= reminderCatchUpWindow {
+		return false
+	}
+
+	if setting.LastSentAt > 0 {
+		lastSent := time.UnixMilli(setting.LastSentAt).In(local.Location())
+		if models.DateOf(lastSent).Equal(models.DateOf(local).Time) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeWeekdays validates and sorts a weekday list, defaulting to every day
+func normalizeWeekdays(days []int) ([]int, error) {
+	if len(days) == 0 {
+		return everyWeekday, nil
+	}
+
+	seen := make(map[int]bool)
+	result := make([]int, 0, len(days))
+	for _, day := range days {
+		if day < 0 || day > 6 {
+			return nil, errors.New("weekdays must be between 0 (Sunday) and 6 (Saturday)")
+		}
+		if !seen[day] {
+			seen[day] = true
+			result = append(result, day)
+		}
+	}
+	sort.Ints(result)
+	return result, nil
+}
+
+// pushDeviceStore lets notifiers look up the push tokens stored by ReminderDAO
+type pushDeviceStore struct {
+	reminderDAO *dao.ReminderDAO
+}
+
+// NewPushDeviceStore adapts a ReminderDAO to notify.DeviceStore
+func NewPushDeviceStore(reminderDAO *dao.ReminderDAO) notify.DeviceStore {
+	return &pushDeviceStore{reminderDAO: reminderDAO}
+}
+
+// Devices implements notify.DeviceStore
+func (s *pushDeviceStore) Devices(userID int64) ([]notify.Device, error) {
+	rows, err := s.reminderDAO.ListDevices(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	devices := make([]notify.Device, 0, len(rows))
+	for _, row := range rows {
+		devices = append(devices, notify.Device{Platform: row.Platform, Token: row.Token})
+	}
+	return devices, nil
+}
+
+// RemoveDevice implements notify.DeviceStore
+func (s *pushDeviceStore) RemoveDevice(token string) error {
+	return s.reminderDAO.DeleteDeviceByToken(token)
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"hope_backend/dao"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxReportDetailLength limits the free text a reporter can add
+const maxReportDetailLength = 1000
+
+// ReportRequest represents the request body for reporting a post or comment
+type ReportRequest struct {
+	Reason string `json:"reason" binding:"required"` // One of the dao.ReportReason codes
+	Detail string `json:"detail"`
+}
+
+// ReportPostHandler handles POST requests to report a post
+func ReportPostHandler(reportDAO *dao.ReportDAO) gin.HandlerFunc {
+	return reportHandler(reportDAO, dao.ReportTargetPost)
+}
+
+// ReportCommentHandler handles POST requests to report a comment
+func ReportCommentHandler(reportDAO *dao.ReportDAO) gin.HandlerFunc {
+	return reportHandler(reportDAO, dao.ReportTargetComment)
+}
+
+// reportHandler files a report on the post or comment in the id parameter.
+// Content reported by enough users is hidden until a moderator decides.
+func reportHandler(reportDAO *dao.ReportDAO, targetType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get authenticated user ID
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, Response{
+				Success: false,
+				Message: "Authentication required",
+			})
+			return
+		}
+
+		targetID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid " + targetType + " ID format",
+			})
+			return
+		}
+
+		var req ReportRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+
+		if !dao.IsValidReportReason(req.Reason) {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid reason. Supported reasons: spam, harassment, hate, self_harm, sexual, misinformation, privacy, other",
+			})
+			return
+		}
+
+		detail := strings.TrimSpace(req.Detail)
+		if len([]rune(detail)) > maxReportDetailLength {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Detail is too long. Maximum length is " + strconv.Itoa(maxReportDetailLength) + " characters",
+			})
+			return
+		}
+
+		report := &dao.Report{
+			TargetType: targetType,
+			TargetID:   targetID,
+			ReporterID: userID.(int64),
+			Reason:     req.Reason,
+			Detail:     detail,
+		}
+		if err := reportDAO.Create(report); err != nil {
+			status := http.StatusInternalServerError
+			switch err.Error() {
+			case targetType + " not found":
+				status = http.StatusNotFound
+			case "cannot report your own content":
+				status = http.StatusBadRequest
+			case "already reported":
+				status = http.StatusConflict
+			}
+			c.JSON(status, Response{
+				Success: false,
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Reporters are not told whether the content was hidden, so reports cannot be used to probe the threshold
+		c.JSON(http.StatusCreated, Response{
+			Success: true,
+			Message: "Thank you, our moderators will review this " + targetType,
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"hope_backend/dao"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// issueSessionToken records a new session for the user and returns a JWT bound to it.
+// Device details fall back to request headers when the client does not send them in the body.
+func issueSessionToken(c *gin.Context, sessionDAO *dao.SessionDAO, userID int64, role string, mfa bool, deviceName, platform string) (string, error) {
+	if deviceName == "" {
+		deviceName = c.GetHeader("X-Device-Name")
+	}
+	if platform == "" {
+		platform = c.GetHeader("X-Device-Platform")
+	}
+
+	expiresAt := time.Now().Add(tokenLifetime)
+	session := &dao.UserSession{
+		UserID:     userID,
+		TokenID:    uuid.New().String(),
+		DeviceName: deviceName,
+		Platform:   platform,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+		ExpiresAt:  expiresAt.UnixMilli(),
+	}
+	if err := sessionDAO.Create(session); err != nil {
+		return "", err
+	}
+
+	return GenerateToken(userID, role, session.TokenID, mfa, expiresAt)
+}
+
+// currentSessionID returns the session ID of the authenticated request, or 0 for legacy tokens
+func currentSessionID(c *gin.Context) int64 {
+	sessionID, _ := c.Get("sessionID")
+	id, _ := sessionID.(int64)
+	return id
+}
+
+// ListSessionsHandler returns all devices the user is currently logged in on
+func ListSessionsHandler(sessionDAO *dao.SessionDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get authenticated user ID
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, Response{
+				Success: false,
+				Message: "Authentication required",
+			})
+			return
+		}
+
+		sessions, err := sessionDAO.ListActive(userID.(int64))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to retrieve sessions: " + err.Error(),
+			})
+			return
+		}
+
+		// Flag the session making this request
+		currentID := currentSessionID(c)
+		for i := range sessions {
+			sessions[i].Current = sessions[i].ID == currentID
+		}
+
+		c.JSON(http.StatusOK, Response{
+			Success: true,
+			Data:    sessions,
+		})
+	}
+}
+
+// RevokeSessionHandler signs out a single session of the current user
+func RevokeSessionHandler(sessionDAO *dao.SessionDAO, auditDAO *dao.AuditDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get authenticated user ID
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, Response{
+				Success: false,
+				Message: "Authentication required",
+			})
+			return
+		}
+
+		// Get session ID from URL parameter
+		sessionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid session ID format",
+			})
+			return
+		}
+
+		if err := sessionDAO.Revoke(userID.(int64), sessionID); err != nil {
+			status := http.StatusInternalServerError
+			if err.Error() == "session not found" {
+				status = http.StatusNotFound
+			}
+			c.JSON(status, Response{
+				Success: false,
+				Message: err.Error(),
+			})
+			return
+		}
+
+		recordAudit(c, auditDAO, AuditSessionRevoke, userID.(int64), gin.H{"session_id": sessionID})
+
+		c.JSON(http.StatusOK, Response{
+			Success: true,
+			Message: "Session signed out successfully",
+		})
+	}
+}
+
+// RevokeOtherSessionsHandler signs out every session of the current user except the one making the request
+func RevokeOtherSessionsHandler(sessionDAO *dao.SessionDAO, auditDAO *dao.AuditDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get authenticated user ID
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, Response{
+				Success: false,
+				Message: "Authentication required",
+			})
+			return
+		}
+
+		revoked, err := sessionDAO.RevokeAllExcept(userID.(int64), currentSessionID(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to sign out other sessions: " + err.Error(),
+			})
+			return
+		}
+		recordAudit(c, auditDAO, AuditSessionRevokeOthers, userID.(int64), gin.H{"revoked": revoked})
+
+		c.JSON(http.StatusOK, Response{
+			Success: true,
+			Message: "Other sessions signed out successfully",
+			Data: gin.H{
+				"revoked": revoked,
+			},
+		})
+	}
+}
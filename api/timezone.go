@@ -0,0 +1,40 @@
+package api
+
+import (
+	"time"
+	_ "time/tzdata" // Zone data for hosts without a system zoneinfo database
+
+	"hope_backend/config"
+	"hope_backend/dao"
+	"hope_backend/models"
+)
+
+// defaultTimezone is used for users who have not set a timezone
+const defaultTimezone = "Asia/Shanghai"
+
+// loadTimezone validates an IANA timezone name such as "Europe/Berlin"
+func loadTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		name = defaultTimezone
+	}
+	return time.LoadLocation(name)
+}
+
+// userLocation returns the timezone of a user, falling back to the default timezone
+func userLocation(userID int64) *time.Location {
+	var name string
+	if profile, err := dao.NewUserProfileDAO(config.DB).GetByID(userID); err == nil {
+		name = profile.Timezone
+	}
+
+	loc, err := loadTimezone(name)
+	if err != nil {
+		loc, _ = loadTimezone(defaultTimezone)
+	}
+	return loc
+}
+
+// userToday returns the current calendar day in the user's timezone
+func userToday(userID int64) models.Date {
+	return models.DateOf(time.Now().In(userLocation(userID)))
+}
@@ -0,0 +1,770 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"hope_backend/dao"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+const (
+	totpIssuer           = "Hope"
+	totpPeriod           = 30 // seconds
+	totpDigits           = 6
+	recoveryCodeCount    = 10
+	challengeTokenTTL    = 5 * time.Minute
+	maxChallengeAttempts = 5
+)
+
+// challengeTokenKey signs login challenge tokens; they can never be used as login tokens
+var challengeTokenKey = append([]byte("2fa-challenge:"), jwtKey...)
+
+// Failed code attempts per challenge token, to stop brute-forcing the 6-digit code
+var (
+	challengeAttempts = make(map[string]challengeAttempt)
+	challengeMutex    sync.Mutex
+)
+
+type challengeAttempt struct {
+	count   int
+	expires time.Time
+}
+
+// TwoFactorCodeRequest represents a request carrying a TOTP code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// DisableTwoFactorRequest represents the request body for turning off 2FA
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// LoginTwoFactorRequest represents the second login step
+type LoginTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+// challengeClaims identifies a user who passed the first login step
+type challengeClaims struct {
+	UserID     int64  `json:"user_id"`
+	DeviceName string `json:"device_name"`
+	Platform   string `json:"platform"`
+	jwt.RegisteredClaims
+}
+
+// generateTOTPSecret creates a random 160-bit base32 secret
+func generateTOTPSecret() (string, error) {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(buf), nil
+}
+
+// totpURI builds the otpauth:// URI that authenticator apps read from a QR code
+func totpURI(secret, account string) string {
+	label := url.PathEscape(totpIssuer + ":" + account)
+	params := url.Values{
+		"secret":    {secret},
+		"issuer":    {totpIssuer},
+		"algorithm": {"SHA1"},
+		"digits":    {fmt.Sprint(totpDigits)},
+		"period":    {fmt.Sprint(totpPeriod)},
+	}
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// totpCode computes the RFC 6238 code for a time step
+func totpCode(secret string, counter int64) (string, error) {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", value%1000000), nil
+}
+
+// verifyTOTP checks a code against the current time step, allowing one step of
+// clock drift. It returns the time step the code belongs to.
+func verifyTOTP(secret, code string, now time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	counter := now.Unix() / totpPeriod
+	for _, step := range []int64{-1, 0, 1} {
+		expected, err := totpCode(secret, counter+step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return counter + step, true
+		}
+	}
+	return 0, false
+}
+
+// useTOTP checks a code and uses up its time step, so the same code cannot be
+// accepted twice. Wrong and replayed codes count toward the user's lockout.
+func useTOTP(twoFactorDAO *dao.TwoFactorDAO, tf *dao.TwoFactor, code string) (bool, error) {
+	if step, ok := verifyTOTP(tf.Secret, code, time.Now()); ok {
+		accepted, err := twoFactorDAO.UseStep(tf.UserID, step)
+		if err != nil || accepted {
+			return accepted, err
+		}
+	}
+	return false, twoFactorDAO.RecordFailure(tf.UserID)
+}
+
+// respondTwoFactorLocked answers a code check while the user is locked out
+func respondTwoFactorLocked(c *gin.Context, tf *dao.TwoFactor) {
+	c.Header("Retry-After", strconv.FormatInt((tf.LockedUntil-time.Now().UnixMilli())/1000+1, 10))
+	c.JSON(http.StatusTooManyRequests, Response{
+		Success: false,
+		Message: "Too many invalid two-factor codes, try again later",
+	})
+}
+
+// generateRecoveryCodes creates single-use codes in the form xxxxx-xxxxx
+func generateRecoveryCodes() ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		s := hex.EncodeToString(buf)
+		codes = append(codes, s[:5]+"-"+s[5:])
+	}
+	return codes, nil
+}
+
+// generateChallengeToken signs a short-lived token for the second login step
+func generateChallengeToken(userID int64, deviceName, platform string) (string, error) {
+	claims := &challengeClaims{
+		UserID:     userID,
+		DeviceName: deviceName,
+		Platform:   platform,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(challengeTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(challengeTokenKey)
+}
+
+// parseChallengeToken validates a challenge token
+func parseChallengeToken(tokenString string) (*challengeClaims, error) {
+	claims := &challengeClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return challengeTokenKey, nil
+	})
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid or expired challenge token")
+	}
+
+	return claims, nil
+}
+
+// recordChallengeFailure counts a wrong code for a challenge
+func recordChallengeFailure(challengeID string, expires time.Time) {
+	challengeMutex.Lock()
+	defer challengeMutex.Unlock()
+
+	// Drop entries of expired challenges
+	now := time.Now()
+	for id, attempt := range challengeAttempts {
+		if now.After(attempt.expires) {
+			delete(challengeAttempts, id)
+		}
+	}
+
+	attempt := challengeAttempts[challengeID]
+	attempt.count++
+	attempt.expires = expires
+	challengeAttempts[challengeID] = attempt
+}
+
+// challengeExhausted reports whether a challenge has used up its attempts
+func challengeExhausted(challengeID string) bool {
+	challengeMutex.Lock()
+	defer challengeMutex.Unlock()
+
+	return challengeAttempts[challengeID].count >= maxChallengeAttempts
+}
+
+// completeLogin responds to a successful first login step. Users with 2FA enabled
+// get a challenge token for LoginTwoFactorHandler, everyone else gets a session token.
+// method names the first factor in the audit log, e.g. "password" or "oauth:wechat".
+func completeLogin(c *gin.Context, twoFactorDAO *dao.TwoFactorDAO, sessionDAO *dao.SessionDAO, auditDAO *dao.AuditDAO, profile *dao.UserProfile, deviceName, platform, method string) {
+	enabled, err := twoFactorDAO.IsEnabled(profile.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to check two-factor settings",
+		})
+		return
+	}
+
+	if enabled {
+		challenge, err := generateChallengeToken(profile.ID, deviceName, platform)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to generate challenge token",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, Response{
+			Success: true,
+			Message: "Two-factor code required",
+			Data: gin.H{
+				"two_factor_required": true,
+				"challenge_token":     challenge,
+			},
+		})
+		return
+	}
+
+	// Generate JWT token and record the session it belongs to
+	token, err := issueSessionToken(c, sessionDAO, profile.ID, profile.Role, false, deviceName, platform)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "Failed to generate token",
+		})
+		return
+	}
+	recordAudit(c, auditDAO, AuditLogin, profile.ID, gin.H{
+		"method":      method,
+		"device_name": deviceName,
+		"platform":    platform,
+	})
+
+	// Return success with token and user profile
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Login successful",
+		Data: gin.H{
+			"token":   token,
+			"profile": profile,
+		},
+	})
+}
+
+// LoginTwoFactorHandler completes a login with a TOTP or recovery code
+func LoginTwoFactorHandler(profileDAO *dao.UserProfileDAO, twoFactorDAO *dao.TwoFactorDAO, sessionDAO *dao.SessionDAO, auditDAO *dao.AuditDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req LoginTwoFactorRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+
+		challenge, err := parseChallengeToken(req.ChallengeToken)
+		if err != nil || challengeExhausted(challenge.ID) {
+			c.JSON(http.StatusUnauthorized, Response{
+				Success: false,
+				Message: "Invalid or expired challenge token",
+			})
+			return
+		}
+
+		tf, err := twoFactorDAO.Get(challenge.UserID)
+		if err != nil || !tf.Enabled {
+			c.JSON(http.StatusUnauthorized, Response{
+				Success: false,
+				Message: "Two-factor authentication is not enabled",
+			})
+			return
+		}
+
+		// Failures are also counted per user, so a new challenge does not bring new attempts
+		if tf.Locked() {
+			respondTwoFactorLocked(c, tf)
+			return
+		}
+
+		valid := false
+		if req.Code != "" {
+			valid, err = useTOTP(twoFactorDAO, tf, req.Code)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, Response{
+					Success: false,
+					Message: "Error verifying two-factor code: " + err.Error(),
+				})
+				return
+			}
+		} else if req.RecoveryCode != "" {
+			valid, err = twoFactorDAO.UseRecoveryCode(challenge.UserID, strings.ToLower(strings.TrimSpace(req.RecoveryCode)))
+			if err == nil {
+				if valid {
+					err = twoFactorDAO.ResetFailures(challenge.UserID)
+				} else {
+					err = twoFactorDAO.RecordFailure(challenge.UserID)
+				}
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, Response{
+					Success: false,
+					Message: "Error verifying recovery code: " + err.Error(),
+				})
+				return
+			}
+		}
+
+		if !valid {
+			recordChallengeFailure(challenge.ID, challenge.ExpiresAt.Time)
+			recordAudit(c, auditDAO, AuditTwoFactorFailed, challenge.UserID, nil)
+			c.JSON(http.StatusUnauthorized, Response{
+				Success: false,
+				Message: "Invalid two-factor code",
+			})
+			return
+		}
+
+		profile, err := profileDAO.GetByID(challenge.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to retrieve user profile",
+			})
+			return
+		}
+
+		// Generate JWT token and record the session it belongs to
+		token, err := issueSessionToken(c, sessionDAO, profile.ID, profile.Role, true, challenge.DeviceName, challenge.Platform)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to generate token",
+			})
+			return
+		}
+		recordAudit(c, auditDAO, AuditLogin, profile.ID, gin.H{
+			"method":        "2fa",
+			"recovery_code": req.Code == "",
+			"device_name":   challenge.DeviceName,
+			"platform":      challenge.Platform,
+		})
+
+		c.JSON(http.StatusOK, Response{
+			Success: true,
+			Message: "Login successful",
+			Data: gin.H{
+				"token":   token,
+				"profile": profile,
+			},
+		})
+	}
+}
+
+// GetTwoFactorStatusHandler reports whether 2FA is enabled and how many recovery codes remain
+func GetTwoFactorStatusHandler(twoFactorDAO *dao.TwoFactorDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get authenticated user ID
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, Response{
+				Success: false,
+				Message: "Authentication required",
+			})
+			return
+		}
+
+		enabled, err := twoFactorDAO.IsEnabled(userID.(int64))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to check two-factor settings: " + err.Error(),
+			})
+			return
+		}
+
+		remaining, err := twoFactorDAO.CountUnusedRecoveryCodes(userID.(int64))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to count recovery codes: " + err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, Response{
+			Success: true,
+			Data: gin.H{
+				"enabled":                  enabled,
+				"recovery_codes_remaining": remaining,
+			},
+		})
+	}
+}
+
+// SetupTwoFactorHandler generates a new TOTP secret for enrollment
+func SetupTwoFactorHandler(profileDAO *dao.UserProfileDAO, twoFactorDAO *dao.TwoFactorDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get authenticated user ID
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, Response{
+				Success: false,
+				Message: "Authentication required",
+			})
+			return
+		}
+
+		profile, err := profileDAO.GetByID(userID.(int64))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to retrieve user profile",
+			})
+			return
+		}
+
+		secret, err := generateTOTPSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to generate secret",
+			})
+			return
+		}
+
+		if err := twoFactorDAO.SaveSecret(profile.ID, secret); err != nil {
+			status := http.StatusInternalServerError
+			if err.Error() == "two-factor already enabled" {
+				status = http.StatusConflict
+			}
+			c.JSON(status, Response{
+				Success: false,
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, Response{
+			Success: true,
+			Message: "Scan the QR code with an authenticator app, then confirm with a code",
+			Data: gin.H{
+				"secret":      secret,
+				"otpauth_uri": totpURI(secret, profile.MobileNumber),
+			},
+		})
+	}
+}
+
+// EnableTwoFactorHandler confirms enrollment with a code and returns the recovery codes.
+// The response also carries a new token for the current session, because tokens
+// without a completed 2FA step are rejected once 2FA is on.
+func EnableTwoFactorHandler(twoFactorDAO *dao.TwoFactorDAO, sessionDAO *dao.SessionDAO, auditDAO *dao.AuditDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get authenticated user ID
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, Response{
+				Success: false,
+				Message: "Authentication required",
+			})
+			return
+		}
+
+		var req TwoFactorCodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+
+		tf, err := twoFactorDAO.Get(userID.(int64))
+		if err != nil {
+			status := http.StatusInternalServerError
+			if err.Error() == "two-factor not set up" {
+				status = http.StatusBadRequest
+			}
+			c.JSON(status, Response{
+				Success: false,
+				Message: err.Error(),
+			})
+			return
+		}
+		if tf.Enabled {
+			c.JSON(http.StatusConflict, Response{
+				Success: false,
+				Message: "two-factor already enabled",
+			})
+			return
+		}
+
+		if tf.Locked() {
+			respondTwoFactorLocked(c, tf)
+			return
+		}
+		valid, err := useTOTP(twoFactorDAO, tf, req.Code)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Error verifying two-factor code: " + err.Error(),
+			})
+			return
+		}
+		if !valid {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid two-factor code",
+			})
+			return
+		}
+
+		codes, err := generateRecoveryCodes()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to generate recovery codes",
+			})
+			return
+		}
+
+		if err := twoFactorDAO.Enable(userID.(int64), codes); err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to enable two-factor authentication: " + err.Error(),
+			})
+			return
+		}
+		recordAudit(c, auditDAO, AuditTwoFactorEnable, userID.(int64), nil)
+
+		// Re-issue the token of the current session with the 2FA step completed.
+		// It expires with the session.
+		var token string
+		if tokenID := c.GetString("tokenID"); tokenID != "" {
+			var session *dao.UserSession
+			if session, err = sessionDAO.GetByTokenID(tokenID); err == nil {
+				token, err = GenerateToken(userID.(int64), currentRole(c), tokenID, true, time.UnixMilli(session.ExpiresAt))
+			}
+		} else {
+			token, err = issueSessionToken(c, sessionDAO, userID.(int64), currentRole(c), true, "", "")
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to generate token",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, Response{
+			Success: true,
+			Message: "Two-factor authentication enabled. Store the recovery codes somewhere safe",
+			Data: gin.H{
+				"recovery_codes": codes,
+				"token":          token,
+			},
+		})
+	}
+}
+
+// RegenerateRecoveryCodesHandler replaces all recovery codes after checking a TOTP code
+func RegenerateRecoveryCodesHandler(twoFactorDAO *dao.TwoFactorDAO, auditDAO *dao.AuditDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get authenticated user ID
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, Response{
+				Success: false,
+				Message: "Authentication required",
+			})
+			return
+		}
+
+		var req TwoFactorCodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+
+		tf, err := twoFactorDAO.Get(userID.(int64))
+		if err != nil || !tf.Enabled {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Two-factor authentication is not enabled",
+			})
+			return
+		}
+
+		if tf.Locked() {
+			respondTwoFactorLocked(c, tf)
+			return
+		}
+		valid, err := useTOTP(twoFactorDAO, tf, req.Code)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Error verifying two-factor code: " + err.Error(),
+			})
+			return
+		}
+		if !valid {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid two-factor code",
+			})
+			return
+		}
+
+		codes, err := generateRecoveryCodes()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to generate recovery codes",
+			})
+			return
+		}
+
+		if err := twoFactorDAO.ReplaceRecoveryCodes(userID.(int64), codes); err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to save recovery codes: " + err.Error(),
+			})
+			return
+		}
+		recordAudit(c, auditDAO, AuditRecoveryCodesRegenerate, userID.(int64), nil)
+
+		c.JSON(http.StatusOK, Response{
+			Success: true,
+			Message: "Recovery codes regenerated",
+			Data: gin.H{
+				"recovery_codes": codes,
+			},
+		})
+	}
+}
+
+// DisableTwoFactorHandler turns off 2FA after checking the password and a TOTP code
+func DisableTwoFactorHandler(profileDAO *dao.UserProfileDAO, twoFactorDAO *dao.TwoFactorDAO, auditDAO *dao.AuditDAO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get authenticated user ID
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, Response{
+				Success: false,
+				Message: "Authentication required",
+			})
+			return
+		}
+
+		var req DisableTwoFactorRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+
+		profile, err := profileDAO.GetByID(userID.(int64))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to retrieve user profile",
+			})
+			return
+		}
+
+		isValid, _, err := profileDAO.VerifyPassword(profile.MobileNumber, req.Password)
+		if err != nil || !isValid {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Password is incorrect",
+			})
+			return
+		}
+
+		tf, err := twoFactorDAO.Get(profile.ID)
+		if err != nil || !tf.Enabled {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Two-factor authentication is not enabled",
+			})
+			return
+		}
+
+		if tf.Locked() {
+			respondTwoFactorLocked(c, tf)
+			return
+		}
+		valid, err := useTOTP(twoFactorDAO, tf, req.Code)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Error verifying two-factor code: " + err.Error(),
+			})
+			return
+		}
+		if !valid {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "Invalid two-factor code",
+			})
+			return
+		}
+
+		if err := twoFactorDAO.Disable(profile.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: "Failed to disable two-factor authentication: " + err.Error(),
+			})
+			return
+		}
+		recordAudit(c, auditDAO, AuditTwoFactorDisable, profile.ID, nil)
+
+		c.JSON(http.StatusOK, Response{
+			Success: true,
+			Message: "Two-factor authentication disabled",
+		})
+	}
+}
@@ -102,15 +102,6 @@ func FileUploadHandler(profileDAO *dao.UserProfileDAO) gin.HandlerFunc {
 		// Limit the upload size
 		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxUploadSize)
 
-		// Create uploads directory if it doesn't exist
-		if err := os.MkdirAll(uploadDir, 0755); err != nil {
-			c.JSON(http.StatusInternalServerError, Response{
-				Success: false,
-				Message: "Failed to create upload directory: " + err.Error(),
-			})
-			return
-		}
-
 		// Get the file from the request
 		file, header, err := c.Request.FormFile("file")
 		if err != nil {
@@ -132,53 +123,16 @@ func FileUploadHandler(profileDAO *dao.UserProfileDAO) gin.HandlerFunc {
 			return
 		}
 
-		// Generate a unique filename
-		newFilename := fmt.Sprintf("%d-%s%s", id, uuid.New().String(), fileExt)
-		filePath := filepath.Join(uploadDir, newFilename)
-
-		// Create the file on the server
-		dst, err := os.Create(filePath)
+		// Save, optimize and thumbnail the image
+		newFilename, err := storeImage(file, fileExt, uploadDir, id)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, Response{
 				Success: false,
-				Message: "Failed to create file: " + err.Error(),
+				Message: "Failed to save file: " + err.Error(),
 			})
 			return
 		}
-		defer dst.Close()
-
-		// Copy the file content
-		if _, err = file.Seek(0, 0); err != nil {
-			c.JSON(http.StatusInternalServerError, Response{
-				Success: false,
-				Message: "Error processing file: " + err.Error(),
-			})
-			return
-		}
-
-		// Copy file contents to destination
-		if _, err = io.Copy(dst, file); err != nil {
-			c.JSON(http.StatusInternalServerError, Response{
-				Success: false,
-				Message: "Error copying file: " + err.Error(),
-			})
-			return
-		}
-		dst.Close()
-
-		// Optimize the image (resize and compress)
-		if err := optimizeImage(filePath, filePath, 1920); err != nil {
-			fmt.Printf("Warning: Image optimization failed: %v\n", err)
-			// Continue even if optimization fails
-		}
-
-		// Generate thumbnail
 		thumbnailFilename := ThumbnailPrefix + newFilename
-		thumbnailPath := filepath.Join(uploadDir, thumbnailFilename)
-		if err := createThumbnail(filePath, thumbnailPath, ThumbnailWidth); err != nil {
-			fmt.Printf("Warning: Thumbnail creation failed: %v\n", err)
-			// Continue even if thumbnail creation fails
-		}
 
 		// Generate the public URLs
 		fileURL := fmt.Sprintf("%s/%s", publicURLBase, newFilename)
@@ -254,15 +208,6 @@ func PostImageUploadHandler() gin.HandlerFunc {
 		// Limit the upload size
 		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxUploadSize)
 
-		// Create uploads directory if it doesn't exist
-		if err := os.MkdirAll(uploadDir, 0755); err != nil {
-			c.JSON(http.StatusInternalServerError, Response{
-				Success: false,
-				Message: "Failed to create upload directory: " + err.Error(),
-			})
-			return
-		}
-
 		// Get the file from the request
 		file, header, err := c.Request.FormFile("file")
 		if err != nil {
@@ -284,51 +229,16 @@ func PostImageUploadHandler() gin.HandlerFunc {
 			return
 		}
 
-		// Generate a unique filename
-		newFilename := fmt.Sprintf("%d-%s%s", id, uuid.New().String(), fileExt)
-		filePath := filepath.Join(uploadDir, newFilename)
-
-		// Create the file on the server
-		dst, err := os.Create(filePath)
+		// Save, optimize and thumbnail the image
+		newFilename, err := storeImage(file, fileExt, uploadDir, id)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, Response{
 				Success: false,
-				Message: "Failed to create file: " + err.Error(),
-			})
-			return
-		}
-		defer dst.Close()
-
-		// Copy the file content
-		if _, err = file.Seek(0, 0); err != nil {
-			c.JSON(http.StatusInternalServerError, Response{
-				Success: false,
-				Message: "Error processing file: " + err.Error(),
+				Message: "Failed to save file: " + err.Error(),
 			})
 			return
 		}
-
-		// Copy file contents to destination
-		if _, err = io.Copy(dst, file); err != nil {
-			c.JSON(http.StatusInternalServerError, Response{
-				Success: false,
-				Message: "Error copying file: " + err.Error(),
-			})
-			return
-		}
-		dst.Close()
-
-		// Optimize the image (resize and compress)
-		if err := optimizeImage(filePath, filePath, 1920); err != nil {
-			fmt.Printf("Warning: Image optimization failed: %v\n", err)
-		}
-
-		// Generate thumbnail
 		thumbnailFilename := ThumbnailPrefix + newFilename
-		thumbnailPath := filepath.Join(uploadDir, thumbnailFilename)
-		if err := createThumbnail(filePath, thumbnailPath, ThumbnailWidth); err != nil {
-			fmt.Printf("Warning: Thumbnail creation failed: %v\n", err)
-		}
 
 		// Generate the public URLs
 		fileURL := fmt.Sprintf("%s/%s", publicURLBase, newFilename)
@@ -378,10 +288,55 @@ func SetupStaticFileServer(router *gin.Engine) {
 			c.Next()
 		})
 
-		router.Static(staticPath, uploadDir)
+		PublicGroup(&router.RouterGroup, "").Static(staticPath, uploadDir)
 	}
 }
 
+// storeImage saves an uploaded image under uploadDir with a unique name, then
+// optimizes it and creates its thumbnail. It returns the new file name. Names
+// start with the owner's ID unless userID is 0.
+func storeImage(file io.ReadSeeker, fileExt, uploadDir string, userID int64) (string, error) {
+	if err := os.MkdirAll(uploadDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	newFilename := uuid.New().String() + fileExt
+	if userID != 0 {
+		newFilename = fmt.Sprintf("%d-%s", userID, newFilename)
+	}
+	filePath := filepath.Join(uploadDir, newFilename)
+
+	if _, err := file.Seek(0, 0); err != nil {
+		return "", fmt.Errorf("error processing file: %w", err)
+	}
+
+	dst, err := os.Create(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+	_, err = io.Copy(dst, file)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(filePath)
+		return "", fmt.Errorf("error copying file: %w", err)
+	}
+
+	// Optimize the image (resize and compress)
+	if err := optimizeImage(filePath, filePath, maxImageDimension); err != nil {
+		fmt.Printf("Warning: Image optimization failed: %v\n", err)
+	}
+
+	// Generate thumbnail
+	thumbnailPath := filepath.Join(uploadDir, ThumbnailPrefix+newFilename)
+	if err := createThumbnail(filePath, thumbnailPath, ThumbnailWidth); err != nil {
+		fmt.Printf("Warning: Thumbnail creation failed: %v\n", err)
+	}
+
+	return newFilename, nil
+}
+
 // optimizeImage resizes and compresses an image
 func optimizeImage(srcPath, dstPath string, maxWidth int) error {
 	// Open the source image
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -115,10 +116,11 @@ func GetUserProfileHandler(profileDAO *dao.UserProfileDAO) gin.HandlerFunc {
 type UpdateProfileRequest struct {
 	PatientName           string `json:"patient_name" binding:"required"`
 	RelationshipToPatient string `json:"relationship_to_patient" binding:"required"`
-	IllnessCause          string `json:"illness_cause"`
+	IllnessCause          string `json:"illness_cause" binding:"max=200"`
 	ChatBackground        string `json:"chat_background"`
 	UserAvatar            string `json:"user_avatar"`
 	UserNickname          string `json:"user_nickname" binding:"required"`
+	Timezone              string `json:"timezone"` // IANA name such as "Asia/Shanghai"; unchanged if empty
 }
 
 // UpdatePasswordRequest represents the request body for changing password
@@ -190,6 +192,16 @@ func UpdateUserProfileHandler(profileDAO *dao.UserProfileDAO) gin.HandlerFunc {
 		profile.ChatBackground = req.ChatBackground
 		profile.UserAvatar = req.UserAvatar
 		profile.UserNickname = req.UserNickname
+		if req.Timezone != "" {
+			if _, err := loadTimezone(req.Timezone); err != nil {
+				c.JSON(http.StatusBadRequest, Response{
+					Success: false,
+					Message: "Invalid timezone: " + req.Timezone,
+				})
+				return
+			}
+			profile.Timezone = req.Timezone
+		}
 
 		// Save updated profile
 		if err := profileDAO.Update(profile); err != nil {
@@ -209,7 +221,7 @@ func UpdateUserProfileHandler(profileDAO *dao.UserProfileDAO) gin.HandlerFunc {
 }
 
 // UpdatePasswordHandler returns a handler for changing user password
-func UpdatePasswordHandler(profileDAO *dao.UserProfileDAO) gin.HandlerFunc {
+func UpdatePasswordHandler(profileDAO *dao.UserProfileDAO, auditDAO *dao.AuditDAO) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get authenticated user ID
 		userID, exists := c.Get("userID")
@@ -245,6 +257,7 @@ func UpdatePasswordHandler(profileDAO *dao.UserProfileDAO) gin.HandlerFunc {
 		err := profileDAO.UpdatePassword(id, req.CurrentPassword, req.NewPassword)
 		if err != nil {
 			if err.Error() == "current password is incorrect" {
+				recordAudit(c, auditDAO, AuditPasswordChangeFailed, id, nil)
 				c.JSON(http.StatusBadRequest, Response{
 					Success: false,
 					Message: "Current password is incorrect",
@@ -258,6 +271,8 @@ func UpdatePasswordHandler(profileDAO *dao.UserProfileDAO) gin.HandlerFunc {
 			return
 		}
 
+		recordAudit(c, auditDAO, AuditPasswordChange, id, nil)
+
 		c.JSON(http.StatusOK, Response{
 			Success: true,
 			Message: "Password updated successfully",
@@ -266,7 +281,7 @@ func UpdatePasswordHandler(profileDAO *dao.UserProfileDAO) gin.HandlerFunc {
 }
 
 // UpdateMobileNumberHandler returns a handler for updating mobile number
-func UpdateMobileNumberHandler(profileDAO *dao.UserProfileDAO) gin.HandlerFunc {
+func UpdateMobileNumberHandler(profileDAO *dao.UserProfileDAO, auditDAO *dao.AuditDAO) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get authenticated user ID
 		userID, exists := c.Get("userID")
@@ -298,6 +313,12 @@ func UpdateMobileNumberHandler(profileDAO *dao.UserProfileDAO) gin.HandlerFunc {
 			return
 		}
 
+		// Remember the old number for the audit log
+		var oldMobile string
+		if profile, err := profileDAO.GetByID(id); err == nil {
+			oldMobile = profile.MobileNumber
+		}
+
 		// Update mobile number with verification
 		err := profileDAO.UpdateMobileNumber(id, req.MobileNumber, req.VerificationCode)
 		if err != nil {
@@ -315,6 +336,16 @@ func UpdateMobileNumberHandler(profileDAO *dao.UserProfileDAO) gin.HandlerFunc {
 			return
 		}
 
+		recordAudit(c, auditDAO, AuditMobileChange, id, gin.H{
+			"old_mobile": maskMobile(oldMobile),
+			"new_mobile": maskMobile(req.MobileNumber),
+		})
+
+		// Notes shared with the number before it was registered
+		if err := dao.ClaimPendingNoteShares(id, req.MobileNumber); err != nil {
+			fmt.Printf("Warning: Failed to claim note shares for user %d: %v\n", id, err)
+		}
+
 		c.JSON(http.StatusOK, Response{
 			Success: true,
 			Message: "Mobile number updated successfully",
@@ -0,0 +1,343 @@
+package dao
+
+import (
+	"errors"
+	"time"
+
+	"hope_backend/models"
+
+	"gorm.io/gorm"
+)
+
+// Account deletion job statuses
+const (
+	DeletionStatusPending   = "pending"
+	DeletionStatusCancelled = "cancelled"
+	DeletionStatusCompleted = "completed"
+	DeletionStatusFailed    = "failed"
+)
+
+// deletedCommentContent replaces the text of comments left on other users' posts
+const deletedCommentContent = "该评论已删除"
+
+// AccountDeletionJob represents the account_deletion_jobs table structure
+type AccountDeletionJob struct {
+	ID          int64  `json:"id" gorm:"primaryKey"`
+	UserID      int64  `json:"user_id" gorm:"index"`
+	Status      string `json:"status"`
+	RequestedAt int64  `json:"requested_at"`
+	ScheduledAt int64  `json:"scheduled_at"` // Deletion runs after this time
+	CompletedAt int64  `json:"completed_at"`
+	Error       string `json:"error,omitempty"`
+}
+
+// TableName specifies the table name for GORM
+func (AccountDeletionJob) TableName() string {
+	return "account_deletion_jobs"
+}
+
+// UserDataExport holds everything stored about a user
+type UserDataExport struct {
+	Profile       *UserProfile            `json:"profile"`
+	Notes         []models.Note           `json:"notes"` // Including notes in the trash, with their attachments
+	NoteRevisions []models.NoteRevision   `json:"note_revisions"`
+	Reflections   []models.NoteReflection `json:"note_reflections"`
+	NoteTemplates []models.NoteTemplate   `json:"note_templates"`
+	Messages      []models.Message        `json:"messages"`
+	Posts         []Post                  `json:"posts"`
+	Comments      []Comment               `json:"comments"`
+}
+
+// AccountDAO handles account-wide operations such as data export and deletion
+type AccountDAO struct {
+	db *gorm.DB
+}
+
+// NewAccountDAO creates a new AccountDAO
+func NewAccountDAO(db *gorm.DB) *AccountDAO {
+	return &AccountDAO{db: db}
+}
+
+// Export collects all data stored for a user
+func (dao *AccountDAO) Export(userID int64) (*UserDataExport, error) {
+	profile, err := NewUserProfileDAO(dao.db).GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &UserDataExport{Profile: profile}
+
+	if err := dao.db.Where("user_id = ?", userID).Order("note_date ASC").Find(&export.Notes).Error; err != nil {
+		return nil, err
+	}
+	if err := loadNotes(export.Notes); err != nil {
+		return nil, err
+	}
+	if err := dao.db.Where("user_id = ?", userID).Order("revision_id ASC").Find(&export.NoteRevisions).Error; err != nil {
+		return nil, err
+	}
+	if err := openNoteRevisions(export.NoteRevisions); err != nil {
+		return nil, err
+	}
+	if err := dao.db.Where("user_id = ?", userID).Order("reflection_id ASC").Find(&export.Reflections).Error; err != nil {
+		return nil, err
+	}
+	for i := range export.Reflections {
+		if err := openReflection(&export.Reflections[i]); err != nil {
+			return nil, err
+		}
+	}
+	if err := dao.db.Where("user_id = ?", userID).Order("template_id ASC").Find(&export.NoteTemplates).Error; err != nil {
+		return nil, err
+	}
+
+	if err := dao.db.Where("sender_id = ? OR receiver_id = ?", userID, userID).Order("id").Find(&export.Messages).Error; err != nil {
+		return nil, err
+	}
+	if err := openMessages(export.Messages); err != nil {
+		return nil, err
+	}
+
+	if err := dao.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&export.Posts).Error; err != nil {
+		return nil, err
+	}
+	for i := range export.Posts {
+		if err := dao.db.Where("post_id = ?", export.Posts[i].ID).Order("display_order").Find(&export.Posts[i].Images).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	if err := dao.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&export.Comments).Error; err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+// ScheduleDeletion creates a pending deletion job that runs after the grace period
+func (dao *AccountDAO) ScheduleDeletion(userID int64, gracePeriod time.Duration) (*AccountDeletionJob, error) {
+	if job, err := dao.GetPendingDeletion(userID); err == nil {
+		return job, nil
+	}
+
+	now := time.Now()
+	job := &AccountDeletionJob{
+		UserID:      userID,
+		Status:      DeletionStatusPending,
+		RequestedAt: now.UnixMilli(),
+		ScheduledAt: now.Add(gracePeriod).UnixMilli(),
+	}
+	if err := dao.db.Create(job).Error; err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// GetPendingDeletion retrieves the pending deletion job for a user
+func (dao *AccountDAO) GetPendingDeletion(userID int64) (*AccountDeletionJob, error) {
+	var job AccountDeletionJob
+	err := dao.db.Where("user_id = ? AND status = ?", userID, DeletionStatusPending).First(&job).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("deletion job not found")
+		}
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// CancelDeletion cancels a pending deletion job during the grace period
+func (dao *AccountDAO) CancelDeletion(userID int64) error {
+	result := dao.db.Model(&AccountDeletionJob{}).
+		Where("user_id = ? AND status = ?", userID, DeletionStatusPending).
+		Update("status", DeletionStatusCancelled)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("deletion job not found")
+	}
+
+	return nil
+}
+
+// ListDueDeletions retrieves pending jobs whose grace period has ended
+func (dao *AccountDAO) ListDueDeletions(now int64) ([]AccountDeletionJob, error) {
+	var jobs []AccountDeletionJob
+	err := dao.db.Where("status = ? AND scheduled_at <= ?", DeletionStatusPending, now).
+		Order("scheduled_at ASC").Find(&jobs).Error
+	return jobs, err
+}
+
+// FinishDeletion records the outcome of a deletion job
+func (dao *AccountDAO) FinishDeletion(jobID int64, jobErr error) error {
+	updates := map[string]interface{}{
+		"status":       DeletionStatusCompleted,
+		"completed_at": time.Now().UnixMilli(),
+	}
+	if jobErr != nil {
+		updates["status"] = DeletionStatusFailed
+		updates["error"] = jobErr.Error()
+	}
+
+	return dao.db.Model(&AccountDeletionJob{ID: jobID}).Updates(updates).Error
+}
+
+// DeleteUserData removes or anonymises every row belonging to a user.
+// Posts, notes, messages, likes, sessions and identities are hard-deleted;
+// comments on other users' posts are anonymised so reply threads stay intact.
+// It returns the upload paths that should be removed from disk afterwards.
+func (dao *AccountDAO) DeleteUserData(userID int64) ([]string, error) {
+	var files []string
+
+	err := dao.db.Transaction(func(tx *gorm.DB) error {
+		var profile UserProfile
+		if err := tx.First(&profile, userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("user profile not found")
+			}
+			return err
+		}
+		files = append(files, profile.UserAvatar, profile.ChatBackground)
+
+		// Posts with their images, likes and comments
+		var posts []Post
+		if err := tx.Where("user_id = ?", userID).Find(&posts).Error; err != nil {
+			return err
+		}
+		for _, post := range posts {
+			var images []PostImage
+			if err := tx.Where("post_id = ?", post.ID).Find(&images).Error; err != nil {
+				return err
+			}
+			for _, image := range images {
+				files = append(files, image.ImagePath)
+			}
+
+			if err := tx.Where("post_id = ?", post.ID).Delete(&PostImage{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("post_id = ?", post.ID).Delete(&PostLike{}).Error; err != nil {
+				return err
+			}
+			if err := NewCommentDAO(tx).DeleteAllForPost(post.ID); err != nil {
+				return err
+			}
+			if err := tx.Delete(&Post{ID: post.ID}).Error; err != nil {
+				return err
+			}
+		}
+
+		// Likes on other users' content
+		if err := tx.Model(&Post{}).
+			Where("id IN (SELECT post_id FROM post_likes WHERE user_id = ?)", userID).
+			UpdateColumn("like_count", gorm.Expr("like_count - ?", 1)).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&PostLike{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&Comment{}).
+			Where("id IN (SELECT comment_id FROM comment_likes WHERE user_id = ?)", userID).
+			UpdateColumn("like_count", gorm.Expr("like_count - ?", 1)).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&CommentLike{}).Error; err != nil {
+			return err
+		}
+
+		// Reports filed by the user or about their content, and decisions about it
+		if err := tx.Where("reporter_id = ? OR target_user_id = ?", userID, userID).Delete(&Report{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("target_user_id = ?", userID).Delete(&ModerationDecision{}).Error; err != nil {
+			return err
+		}
+
+		// Comments on other users' posts keep their place in the thread
+		if err := tx.Model(&Comment{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+			"user_id":    0,
+			"content":    deletedCommentContent,
+			"updated_at": time.Now().UnixMilli(),
+		}).Error; err != nil {
+			return err
+		}
+
+		// Journal and chat history, and shares given or received
+		if err := tx.Where("owner_id = ? OR recipient_id = ?", userID, userID).Delete(&models.NoteShare{}).Error; err != nil {
+			return err
+		}
+		var importFiles []string
+		if err := tx.Model(&models.NoteImportJob{}).
+			Where("user_id = ? AND status IN ?", userID, []string{models.ImportStatusPending, models.ImportStatusRunning}).
+			Pluck("file_path", &importFiles).Error; err != nil {
+			return err
+		}
+		files = append(files, importFiles...)
+		if err := tx.Where("user_id = ?", userID).Delete(&models.NoteImportJob{}).Error; err != nil {
+			return err
+		}
+		var attachmentFiles []string
+		if err := tx.Model(&models.NoteAttachment{}).Where("user_id = ?", userID).Pluck("file_path", &attachmentFiles).Error; err != nil {
+			return err
+		}
+		files = append(files, attachmentFiles...)
+		if err := tx.Where("user_id = ?", userID).Delete(&models.NoteAttachment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.NoteRevision{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.NoteReflection{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.Note{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.NoteTombstone{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.NoteTemplate{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.NoteSyncState{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&ReminderSetting{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&PushDevice{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("sender_id = ? OR receiver_id = ?", userID, userID).Delete(&models.Message{}).Error; err != nil {
+			return err
+		}
+
+		// Sessions, linked identities, 2FA settings, verification codes and finally the profile itself
+		if err := tx.Where("user_id = ?", userID).Delete(&UserSession{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&UserIdentity{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&TwoFactor{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("mobile_number = ?", profile.MobileNumber).Delete(&VerificationCode{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&UserProfile{ID: userID}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
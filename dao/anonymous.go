@@ -0,0 +1,40 @@
+package dao
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+)
+
+// Words pseudonyms are built from, gentle on purpose
+var (
+	pseudonymAdjectives = []string{
+		"Quiet", "Gentle", "Brave", "Calm", "Warm", "Bright", "Patient", "Kind",
+		"Steady", "Hopeful", "Tender", "Silver", "Golden", "Soft", "Clear", "Morning",
+	}
+	pseudonymNouns = []string{
+		"Willow", "River", "Sparrow", "Lantern", "Harbor", "Meadow", "Pine", "Candle",
+		"Cloud", "Maple", "Robin", "Stone", "Lily", "Brook", "Orchid", "Heron",
+	}
+)
+
+// pseudonym names an anonymous author within one post. The name is derived
+// from the secret seed of the post, so it stays the same for every post and
+// comment of the user there, differs between posts, and cannot be traced back
+// to the user ID without the seed.
+func pseudonym(seed string, userID int64) string {
+	mac := hmac.New(sha256.New, []byte(seed))
+	binary.Write(mac, binary.BigEndian, userID)
+	sum := mac.Sum(nil)
+
+	adjective := pseudonymAdjectives[int(sum[0])%len(pseudonymAdjectives)]
+	noun := pseudonymNouns[int(sum[1])%len(pseudonymNouns)]
+	return fmt.Sprintf("%s %s %d", adjective, noun, int(binary.BigEndian.Uint16(sum[2:]))%100)
+}
+
+// anonymousAuthor is the public view of the author of anonymous content. It
+// has no ID and no avatar, so clients show their generic one.
+func anonymousAuthor(seed string, userID int64) *PublicAuthor {
+	return &PublicAuthor{Nickname: pseudonym(seed, userID), Anonymous: true}
+}
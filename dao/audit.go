@@ -0,0 +1,104 @@
+package dao
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AuditLog represents the audit_logs table structure.
+// Rows are only ever inserted, and removed by the retention job once they expire.
+type AuditLog struct {
+	ID           int64  `json:"id" gorm:"primaryKey"`
+	ActorID      int64  `json:"actor_id" gorm:"index"` // User who performed the action, 0 for anonymous or system
+	Action       string `json:"action" gorm:"index"`
+	TargetUserID int64  `json:"target_user_id" gorm:"index"` // User the action was performed on, 0 if unknown
+	Detail       string `json:"detail"`                      // JSON object with action specific fields
+	IP           string `json:"ip"`
+	UserAgent    string `json:"user_agent"`
+	RequestID    string `json:"request_id" gorm:"index"`
+	CreatedAt    int64  `json:"created_at" gorm:"index"`
+}
+
+// TableName specifies the table name for GORM
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// AuditLogFilter narrows down an audit log query. Zero values are ignored.
+type AuditLogFilter struct {
+	ActorID      int64
+	TargetUserID int64
+	Action       string
+	IP           string
+	RequestID    string
+	From         int64 // Inclusive, milliseconds since epoch
+	To           int64 // Exclusive, milliseconds since epoch
+}
+
+// AuditDAO handles database operations for the audit log
+type AuditDAO struct {
+	db *gorm.DB
+}
+
+// NewAuditDAO creates a new AuditDAO
+func NewAuditDAO(db *gorm.DB) *AuditDAO {
+	return &AuditDAO{db: db}
+}
+
+// Create appends an entry to the audit log
+func (dao *AuditDAO) Create(entry *AuditLog) error {
+	if entry.CreatedAt == 0 {
+		entry.CreatedAt = time.Now().UnixMilli()
+	}
+
+	return dao.db.Create(entry).Error
+}
+
+// List retrieves audit log entries matching the filter, newest first
+func (dao *AuditDAO) List(filter AuditLogFilter, page, pageSize int) ([]AuditLog, int64, error) {
+	var entries []AuditLog
+	var total int64
+
+	query := dao.db.Model(&AuditLog{})
+	if filter.ActorID > 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.TargetUserID > 0 {
+		query = query.Where("target_user_id = ?", filter.TargetUserID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.IP != "" {
+		query = query.Where("ip = ?", filter.IP)
+	}
+	if filter.RequestID != "" {
+		query = query.Where("request_id = ?", filter.RequestID)
+	}
+	if filter.From > 0 {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if filter.To > 0 {
+		query = query.Where("created_at < ?", filter.To)
+	}
+
+	// Count total records for pagination
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Order("created_at DESC, id DESC").Offset(offset).Limit(pageSize).Find(&entries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return entries, total, nil
+}
+
+// DeleteOlderThan removes entries created before the cutoff and returns how many were removed
+func (dao *AuditDAO) DeleteOlderThan(cutoff int64) (int64, error) {
+	result := dao.db.Where("created_at < ?", cutoff).Delete(&AuditLog{})
+	return result.RowsAffected, result.Error
+}
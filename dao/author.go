@@ -0,0 +1,54 @@
+package dao
+
+// PublicAuthor is what community readers see of the author of a post or
+// comment. It holds only the fields a profile shows publicly, so contact and
+// medical details never reach the feed.
+type PublicAuthor struct {
+	ID        int64  `json:"id,omitempty"` // 0 for anonymous authors
+	Nickname  string `json:"nickname"`
+	Avatar    string `json:"avatar"`
+	Badge     string `json:"badge,omitempty"` // Staff role such as moderator, empty for regular users
+	Anonymous bool   `json:"anonymous,omitempty"`
+}
+
+// authorRow is the part of user_profiles a PublicAuthor is built from
+type authorRow struct {
+	ID           int64
+	UserNickname string
+	UserAvatar   string
+	Role         string
+}
+
+// TableName specifies the table name for GORM
+func (authorRow) TableName() string {
+	return "user_profiles"
+}
+
+// publicAuthor builds the public view of a profile row
+func publicAuthor(row authorRow) *PublicAuthor {
+	author := &PublicAuthor{ID: row.ID, Nickname: row.UserNickname, Avatar: row.UserAvatar}
+	if row.Role != "" && row.Role != "user" {
+		author.Badge = row.Role
+	}
+	return author
+}
+
+// GetPublicAuthors retrieves the public view of several users in one query,
+// keyed by ID. Only the public columns are read. Users that do not exist are
+// left out.
+func (dao *UserProfileDAO) GetPublicAuthors(ids []int64) (map[int64]*PublicAuthor, error) {
+	authors := make(map[int64]*PublicAuthor, len(ids))
+	if len(ids) == 0 {
+		return authors, nil
+	}
+
+	var rows []authorRow
+	if err := dao.db.Select("id", "user_nickname", "user_avatar", "role").
+		Where("id IN ?", uniqueIDs(ids)).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		authors[row.ID] = publicAuthor(row)
+	}
+	return authors, nil
+}
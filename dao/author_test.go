@@ -0,0 +1,113 @@
+package dao
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// Private profile details of the fixture users, which must never be serialized
+const (
+	privatePatientName  = "Private Patient"
+	privateIllnessCause = "private illness cause"
+	privateMobileNumber = "13800000000"
+)
+
+// privateProfileKeys are the JSON keys of the private UserProfile fields
+var privateProfileKeys = []string{"patient_name", "illness_cause", "mobile_number", "relationship_to_patient", "chat_background", "timezone"}
+
+// assertNoPrivateFields fails if the JSON holds a private profile key or value
+func assertNoPrivateFields(t *testing.T, name string, data []byte) {
+	t.Helper()
+	body := string(data)
+	for _, key := range privateProfileKeys {
+		if strings.Contains(body, `"`+key+`"`) {
+			t.Errorf("%s: response contains private field %q", name, key)
+		}
+	}
+	for _, value := range []string{privatePatientName, privateIllnessCause, privateMobileNumber} {
+		if strings.Contains(body, value) {
+			t.Errorf("%s: response contains private value %q", name, value)
+		}
+	}
+}
+
+func TestPublicAuthorJSON(t *testing.T) {
+	data, err := json.Marshal(publicAuthor(authorRow{ID: 7, UserNickname: "hope", UserAvatar: "a.png", Role: "user"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"id":7,"nickname":"hope","avatar":"a.png"}`; string(data) != want {
+		t.Errorf("regular user: got %s, want %s", data, want)
+	}
+
+	data, err = json.Marshal(publicAuthor(authorRow{ID: 8, UserNickname: "mod", Role: "moderator"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"id":8,"nickname":"mod","avatar":"","badge":"moderator"}`; string(data) != want {
+		t.Errorf("moderator: got %s, want %s", data, want)
+	}
+}
+
+func TestFeedResponsesHidePrivateProfileFields(t *testing.T) {
+	f := newFeedFixture(3, 2)
+	db := f.db(t)
+
+	posts, _, err := NewPostDAO(db).ListPostsAfter(nil, 10, 0, feedViewerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	comments, _, err := NewCommentDAO(db).ListCommentsAfter(1, nil, 10, feedViewerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) == 0 || len(comments) == 0 || len(comments[0].Replies) == 0 {
+		t.Fatalf("fixture returned %d posts and %d comments", len(posts), len(comments))
+	}
+
+	for name, value := range map[string]interface{}{"feed": posts, "comments": comments} {
+		data, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), `"nickname":"user`) {
+			t.Errorf("%s: response has no author nickname: %s", name, data)
+		}
+		assertNoPrivateFields(t, name, data)
+	}
+}
+
+func TestAnonymousContentHidesAuthor(t *testing.T) {
+	const seed = "post-seed"
+	post := Post{ID: 1, UserID: 42, Anonymous: true, PseudonymSeed: seed, UserInfo: anonymousAuthor(seed, 42)}
+	comment := Comment{ID: 2, PostID: 1, UserID: 42, Anonymous: true, UserInfo: anonymousAuthor(seed, 42)}
+
+	if post.UserInfo.Nickname != comment.UserInfo.Nickname {
+		t.Errorf("pseudonyms differ within a post: %q and %q", post.UserInfo.Nickname, comment.UserInfo.Nickname)
+	}
+	if other := pseudonym("other-seed", 42); other == post.UserInfo.Nickname {
+		t.Errorf("pseudonym %q repeats in another post", other)
+	}
+
+	for name, value := range map[string]interface{}{"post": post, "comment": comment} {
+		data, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body := string(data)
+		for _, leak := range []string{`"user_id"`, `"id":42`, seed} {
+			if strings.Contains(body, leak) {
+				t.Errorf("%s: response contains %s: %s", name, leak, body)
+			}
+		}
+	}
+
+	data, err := json.Marshal(Post{ID: 1, UserID: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"user_id":42`) {
+		t.Errorf("named post lost its user_id: %s", data)
+	}
+}
@@ -1,6 +1,7 @@
 package dao
 
 import (
+	"encoding/json"
 	"errors"
 	"time"
 
@@ -17,12 +18,15 @@ type Comment struct {
 	LikeCount  int    `json:"like_count" gorm:"default:0"`
 	ReplyCount int    `json:"reply_count" gorm:"default:0"`
 	Level      int    `json:"level" gorm:"default:0"`
+	Anonymous  bool   `json:"anonymous" gorm:"default:false"` // Shown under the author's pseudonym in the post
+	Hidden     bool   `json:"hidden" gorm:"default:false"`    // Hidden with its replies after reports, see ReportDAO
 	CreatedAt  int64  `json:"created_at"`
 	UpdatedAt  int64  `json:"updated_at"`
 	// Virtual fields, not stored in database
-	Liked    bool         `json:"liked" gorm:"-"`
-	UserInfo *UserProfile `json:"user_info,omitempty" gorm:"-"`
-	Replies  []Comment    `json:"replies,omitempty" gorm:"-"`
+	Liked    bool          `json:"liked" gorm:"-"`
+	IsAuthor bool          `json:"is_author" gorm:"-"` // Whether the current user wrote the comment
+	UserInfo *PublicAuthor `json:"user_info,omitempty" gorm:"-"`
+	Replies  []Comment     `json:"replies,omitempty" gorm:"-"`
 }
 
 // TableName specifies the table name for GORM
@@ -30,6 +34,19 @@ func (Comment) TableName() string {
 	return "comments"
 }
 
+// MarshalJSON leaves out the user ID of anonymous comments
+func (c Comment) MarshalJSON() ([]byte, error) {
+	type comment Comment
+	view := struct {
+		comment
+		UserID *int64 `json:"user_id,omitempty"`
+	}{comment: comment(c)}
+	if !c.Anonymous {
+		view.UserID = &c.UserID
+	}
+	return json.Marshal(view)
+}
+
 // CommentLike represents the comment_likes table structure
 type CommentLike struct {
 	ID        int64 `json:"id" gorm:"primaryKey"`
@@ -43,6 +60,9 @@ func (CommentLike) TableName() string {
 	return "comment_likes"
 }
 
+// maxCommentLevel is the deepest nesting level of a reply; top-level comments are level 0
+const maxCommentLevel = 3
+
 // CommentDAO handles database operations for comments
 type CommentDAO struct {
 	db *gorm.DB
@@ -60,6 +80,10 @@ func (dao *CommentDAO) Create(comment *Comment) (int64, error) {
 	comment.CreatedAt = now
 	comment.UpdatedAt = now
 
+	if err := checkCommunityBan(dao.db, comment.UserID); err != nil {
+		return 0, err
+	}
+
 	// Start a transaction
 	tx := dao.db.Begin()
 	if tx.Error != nil {
@@ -80,8 +104,8 @@ func (dao *CommentDAO) Create(comment *Comment) (int64, error) {
 		// Set level based on parent
 		comment.Level = parentComment.Level + 1
 
-		// Check max nesting level (3)
-		if comment.Level > 3 {
+		// Check max nesting level
+		if comment.Level > maxCommentLevel {
 			tx.Rollback()
 			return 0, errors.New("maximum comment nesting level reached")
 		}
@@ -124,62 +148,32 @@ func (dao *CommentDAO) GetByID(id int64, currentUserID int64) (*Comment, error)
 		return nil, err
 	}
 
-	// Check if current user liked this comment
-	var count int64
-	dao.db.Model(&CommentLike{}).Where("comment_id = ? AND user_id = ?", id, currentUserID).Count(&count)
-	comment.Liked = count > 0
-
-	// Get user info
-	userDAO := NewUserProfileDAO(dao.db)
-	userProfile, err := userDAO.GetByID(comment.UserID)
-	if err == nil {
-		comment.UserInfo = userProfile
-	}
-
-	// If this is a top-level comment, get replies
+	// Get the like state and user info, and replies if this is a top-level comment
+	comments := []Comment{comment}
 	if comment.Level == 0 {
-		replies, err := dao.GetReplies(id, currentUserID)
-		if err != nil {
-			return nil, err
-		}
-		comment.Replies = replies
+		err = dao.fillComments(comments, currentUserID)
+	} else {
+		err = dao.fillCommentDetails([]*Comment{&comments[0]}, currentUserID)
 	}
+	if err != nil {
+		return nil, err
+	}
+	comment = comments[0]
 
 	return &comment, nil
 }
 
-// GetReplies gets replies for a comment
+// GetReplies gets replies for a comment, with their own replies down to maxCommentLevel
 func (dao *CommentDAO) GetReplies(commentID int64, currentUserID int64) ([]Comment, error) {
 	var replies []Comment
-	err := dao.db.Where("parent_id = ?", commentID).Order("created_at ASC").Find(&replies).Error
+	err := dao.db.Where("parent_id = ? AND hidden = ?", commentID, false).Order("created_at ASC, id ASC").Find(&replies).Error
 	if err != nil {
 		return nil, err
 	}
 
-	// Get additional data for each reply
-	for i := range replies {
-		// Check if current user liked this reply
-		var count int64
-		dao.db.Model(&CommentLike{}).Where("comment_id = ? AND user_id = ?", replies[i].ID, currentUserID).Count(&count)
-		replies[i].Liked = count > 0
-
-		// Get user info
-		userDAO := NewUserProfileDAO(dao.db)
-		userProfile, err := userDAO.GetByID(replies[i].UserID)
-		if err == nil {
-			replies[i].UserInfo = userProfile
-		}
-
-		// If this is not a level 3 comment, get its replies too
-		if replies[i].Level < 3 {
-			nestedReplies, err := dao.GetReplies(replies[i].ID, currentUserID)
-			if err != nil {
-				return nil, err
-			}
-			replies[i].Replies = nestedReplies
-		}
+	if err := dao.fillComments(replies, currentUserID); err != nil {
+		return nil, err
 	}
-
 	return replies, nil
 }
 
@@ -189,43 +183,163 @@ func (dao *CommentDAO) ListComments(postID int64, page, pageSize int, currentUse
 	var total int64
 
 	// Only get top-level comments (level = 0)
-	query := dao.db.Model(&Comment{}).Where("post_id = ? AND level = 0", postID)
+	query := dao.db.Model(&Comment{}).Where("post_id = ? AND level = 0 AND hidden = ?", postID, false)
 
 	// Count total records for pagination
 	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
-	// Apply pagination and order
+	// Apply pagination and order; id breaks ties between comments created in the same millisecond
 	offset := (page - 1) * pageSize
-	err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&comments).Error
+	err := query.Order("created_at DESC, id DESC").Offset(offset).Limit(pageSize).Find(&comments).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
-	// Get additional data for each comment
-	for i := range comments {
-		// Check if current user liked this comment
-		var count int64
-		dao.db.Model(&CommentLike{}).Where("comment_id = ? AND user_id = ?", comments[i].ID, currentUserID).Count(&count)
-		comments[i].Liked = count > 0
+	if err := dao.fillComments(comments, currentUserID); err != nil {
+		return nil, 0, err
+	}
+	return comments, total, nil
+}
+
+// ListCommentsAfter retrieves the page of top-level comments of a post after
+// a cursor, newest first, with their replies; a nil cursor returns the first
+// page. The returned cursor is nil on the last page.
+func (dao *CommentDAO) ListCommentsAfter(postID int64, cursor *PageCursor, pageSize int, currentUserID int64) ([]Comment, *PageCursor, error) {
+	// Only get top-level comments (level = 0)
+	query := dao.db.Model(&Comment{}).Where("post_id = ? AND level = 0 AND hidden = ?", postID, false)
+
+	var comments []Comment
+	if err := keysetPage(query, cursor, pageSize).Find(&comments).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var next *PageCursor
+	if len(comments) > pageSize {
+		comments = comments[:pageSize]
+		next = &PageCursor{CreatedAt: comments[pageSize-1].CreatedAt, ID: comments[pageSize-1].ID}
+	}
+
+	if err := dao.fillComments(comments, currentUserID); err != nil {
+		return nil, nil, err
+	}
+	return comments, next, nil
+}
+
+// fillComments loads the like state, author and replies of listed comments.
+// Replies are loaded one level at a time, so the number of queries depends on
+// the nesting depth, not on the number of comments.
+func (dao *CommentDAO) fillComments(comments []Comment, currentUserID int64) error {
+	if len(comments) == 0 {
+		return nil
+	}
+
+	// levels[0] holds the listed comments, levels[n] the replies n levels below them
+	levels := [][]Comment{comments}
+	for {
+		var parentIDs []int64
+		for _, parent := range levels[len(levels)-1] {
+			if parent.Level < maxCommentLevel {
+				parentIDs = append(parentIDs, parent.ID)
+			}
+		}
+		if len(parentIDs) == 0 {
+			break
+		}
+
+		var replies []Comment
+		if err := dao.db.Where("parent_id IN ? AND hidden = ?", parentIDs, false).Order("created_at ASC, id ASC").Find(&replies).Error; err != nil {
+			return err
+		}
+		if len(replies) == 0 {
+			break
+		}
+		levels = append(levels, replies)
+	}
 
-		// Get user info
-		userDAO := NewUserProfileDAO(dao.db)
-		userProfile, err := userDAO.GetByID(comments[i].UserID)
-		if err == nil {
-			comments[i].UserInfo = userProfile
+	var all []*Comment
+	for _, level := range levels {
+		for i := range level {
+			all = append(all, &level[i])
 		}
+	}
+	if err := dao.fillCommentDetails(all, currentUserID); err != nil {
+		return err
+	}
 
-		// Get replies
-		replies, err := dao.GetReplies(comments[i].ID, currentUserID)
-		if err != nil {
-			return nil, 0, err
+	// Attach the replies to their parents, deepest level first so every copy is complete
+	for n := len(levels) - 1; n > 0; n-- {
+		byParent := make(map[int64][]Comment)
+		for _, reply := range levels[n] {
+			byParent[*reply.ParentID] = append(byParent[*reply.ParentID], reply)
+		}
+		for i := range levels[n-1] {
+			levels[n-1][i].Replies = byParent[levels[n-1][i].ID]
 		}
-		comments[i].Replies = replies
 	}
+	return nil
+}
 
-	return comments, total, nil
+// fillCommentDetails loads the like state and author of comments with one
+// query each, and the pseudonym seeds with one more if any are anonymous
+func (dao *CommentDAO) fillCommentDetails(comments []*Comment, currentUserID int64) error {
+	if len(comments) == 0 {
+		return nil
+	}
+
+	commentIDs := make([]int64, len(comments))
+	userIDs := make([]int64, 0, len(comments))
+	var anonymousPostIDs []int64
+	for i, comment := range comments {
+		commentIDs[i] = comment.ID
+		if comment.Anonymous {
+			anonymousPostIDs = append(anonymousPostIDs, comment.PostID)
+		} else {
+			userIDs = append(userIDs, comment.UserID)
+		}
+	}
+
+	// Check which comments the current user liked
+	var likedIDs []int64
+	if err := dao.db.Model(&CommentLike{}).Where("user_id = ? AND comment_id IN ?", currentUserID, commentIDs).
+		Pluck("comment_id", &likedIDs).Error; err != nil {
+		return err
+	}
+	liked := make(map[int64]bool, len(likedIDs))
+	for _, id := range likedIDs {
+		liked[id] = true
+	}
+
+	// Get user info
+	authors, err := NewUserProfileDAO(dao.db).GetPublicAuthors(userIDs)
+	if err != nil {
+		return err
+	}
+
+	// Get the pseudonym seeds of the posts anonymous comments belong to
+	seeds := make(map[int64]string)
+	if len(anonymousPostIDs) > 0 {
+		var posts []Post
+		if err := dao.db.Select("id", "pseudonym_seed").Where("id IN ?", uniqueIDs(anonymousPostIDs)).
+			Find(&posts).Error; err != nil {
+			return err
+		}
+		for _, post := range posts {
+			seeds[post.ID] = post.PseudonymSeed
+		}
+	}
+
+	for _, comment := range comments {
+		comment.Liked = liked[comment.ID]
+		comment.IsAuthor = comment.UserID == currentUserID
+		if comment.Anonymous {
+			comment.UserInfo = anonymousAuthor(seeds[comment.PostID], comment.UserID)
+		} else {
+			comment.UserInfo = authors[comment.UserID]
+		}
+	}
+	return nil
 }
 
 // Delete deletes a comment and all its replies
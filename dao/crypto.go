@@ -0,0 +1,186 @@
+package dao
+
+import (
+	"hope_backend/config"
+	"hope_backend/fieldcrypt"
+	"hope_backend/models"
+)
+
+// Encryption contexts, one per kind of value. Revisions share the note
+// context so a note's stored content can be copied into a revision as is.
+const (
+	noteContentContext    = "notes.content"
+	messageContentContext = "messages.content"
+	illnessCauseContext   = "user_profiles.illness_cause"
+	reflectionContext     = "note_reflections.content"
+)
+
+// sealNote encrypts the content of a note about to be written. The returned
+// function puts the plaintext back so callers keep working with the note.
+func sealNote(note *models.Note) (func(), error) {
+	plaintext := note.Content
+	sealed, version, err := fieldcrypt.Encrypt(plaintext, noteContentContext)
+	if err != nil {
+		return nil, err
+	}
+
+	note.Content, note.KeyVersion = sealed, version
+	return func() { note.Content = plaintext }, nil
+}
+
+// openNote decrypts the content of a note read from the database
+func openNote(note *models.Note) error {
+	content, err := fieldcrypt.Decrypt(note.Content, note.KeyVersion, noteContentContext)
+	if err != nil {
+		return err
+	}
+	note.Content = content
+	return nil
+}
+
+// openNotes decrypts the content of notes read from the database
+func openNotes(notes []models.Note) error {
+	for i := range notes {
+		if err := openNote(&notes[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openNoteRevision decrypts the content of a revision read from the database
+func openNoteRevision(revision *models.NoteRevision) error {
+	content, err := fieldcrypt.Decrypt(revision.Content, revision.KeyVersion, noteContentContext)
+	if err != nil {
+		return err
+	}
+	revision.Content = content
+	return nil
+}
+
+// openNoteRevisions decrypts the content of revisions read from the database
+func openNoteRevisions(revisions []models.NoteRevision) error {
+	for i := range revisions {
+		if err := openNoteRevision(&revisions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sealMessage encrypts the content of a message about to be written; the returned function restores the plaintext
+func sealMessage(msg *models.Message) (func(), error) {
+	plaintext := msg.Content
+	sealed, version, err := fieldcrypt.Encrypt(plaintext, messageContentContext)
+	if err != nil {
+		return nil, err
+	}
+
+	msg.Content, msg.KeyVersion = sealed, version
+	return func() { msg.Content = plaintext }, nil
+}
+
+// openMessages decrypts the content of messages read from the database
+func openMessages(messages []models.Message) error {
+	for i := range messages {
+		content, err := fieldcrypt.Decrypt(messages[i].Content, messages[i].KeyVersion, messageContentContext)
+		if err != nil {
+			return err
+		}
+		messages[i].Content = content
+	}
+	return nil
+}
+
+// openProfile decrypts the illness cause of a profile read from the database
+func openProfile(profile *UserProfile) error {
+	illnessCause, err := fieldcrypt.Decrypt(profile.IllnessCause, profile.KeyVersion, illnessCauseContext)
+	if err != nil {
+		return err
+	}
+	profile.IllnessCause = illnessCause
+	return nil
+}
+
+// openReflection decrypts the content of a reflection read from the database
+func openReflection(reflection *models.NoteReflection) error {
+	content, err := fieldcrypt.Decrypt(reflection.Content, reflection.KeyVersion, reflectionContext)
+	if err != nil {
+		return err
+	}
+	reflection.Content = content
+	return nil
+}
+
+// EncryptedColumn is a column whose values are encrypted with fieldcrypt.
+// Each of these tables has a key_version column next to the value.
+type EncryptedColumn struct {
+	Table    string
+	IDColumn string
+	Column   string
+	context  string
+}
+
+// EncryptedColumns lists every encrypted column
+var EncryptedColumns = []EncryptedColumn{
+	{Table: "notes", IDColumn: "note_id", Column: "content", context: noteContentContext},
+	{Table: "note_revisions", IDColumn: "revision_id", Column: "content", context: noteContentContext},
+	{Table: "messages", IDColumn: "id", Column: "content", context: messageContentContext},
+	{Table: "user_profiles", IDColumn: "id", Column: "illness_cause", context: illnessCauseContext},
+	{Table: "note_reflections", IDColumn: "reflection_id", Column: "content", context: reflectionContext},
+}
+
+// ReencryptResult reports one batch of ReencryptBatch
+type ReencryptResult struct {
+	LastID    int64 // Highest row ID looked at, 0 if no rows were left
+	Rewritten int
+	Failed    int // Values that could not be decrypted, usually because their key is no longer configured
+}
+
+// ReencryptBatch rewrites up to limit values of a column that were stored in
+// plaintext or under an older master key, starting after the given row ID.
+// A row is only rewritten if its key version is still the one that was read,
+// so a concurrent edit always wins; the edit is stored with the current key anyway.
+// Timestamps and note versions are left alone, clients see no change.
+func ReencryptBatch(column EncryptedColumn, after int64, limit int) (ReencryptResult, error) {
+	var result ReencryptResult
+	current := fieldcrypt.CurrentVersion()
+
+	var rows []struct {
+		ID         int64
+		Value      string
+		KeyVersion int
+	}
+	err := config.DB.Table(column.Table).
+		Select(column.IDColumn+" AS id", "COALESCE("+column.Column+", '') AS value", "key_version").
+		Where(column.IDColumn+" > ? AND key_version <> ?", after, current).
+		Order(column.IDColumn + " ASC").Limit(limit).Scan(&rows).Error
+	if err != nil {
+		return result, err
+	}
+
+	for _, row := range rows {
+		result.LastID = row.ID
+
+		plaintext, err := fieldcrypt.Decrypt(row.Value, row.KeyVersion, column.context)
+		if err != nil {
+			result.Failed++
+			continue
+		}
+		sealed, version, err := fieldcrypt.Encrypt(plaintext, column.context)
+		if err != nil {
+			return result, err
+		}
+
+		update := config.DB.Table(column.Table).
+			Where(column.IDColumn+" = ? AND key_version = ?", row.ID, row.KeyVersion).
+			UpdateColumns(map[string]interface{}{column.Column: sealed, "key_version": version})
+		if update.Error != nil {
+			return result, update.Error
+		}
+		if update.RowsAffected > 0 {
+			result.Rewritten++
+		}
+	}
+	return result, nil
+}
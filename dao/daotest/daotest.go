@@ -0,0 +1,418 @@
+// Package daotest provides an in-memory stub database for tests of the DAOs
+// and the handlers that use them.
+//
+// The stub keeps rows as column maps and understands the plain statements
+// GORM generates for the DAOs: SELECT (columns, * or count(*)), INSERT,
+// UPDATE and DELETE with WHERE conditions of the form "column = ?",
+// "column IN (?,?)", "column < ?" and "column > ?", combined with AND, OR and
+// parentheses. Joins, ORDER BY and OFFSET are ignored. Transactions are
+// accepted but never rolled back.
+package daotest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Row is a table row by column name
+type Row map[string]driver.Value
+
+// DB holds the tables of a stub database
+type DB struct {
+	mu     sync.Mutex
+	tables map[string][]Row
+	nextID int64
+}
+
+var (
+	tablePattern  = regexp.MustCompile("(?:FROM|INTO|UPDATE) `(\\w+)`")
+	columnPattern = regexp.MustCompile("`(\\w+)`")
+	setPattern    = regexp.MustCompile("SET (.*?) WHERE")
+	limitPattern  = regexp.MustCompile(`LIMIT (\d+)`)
+	// wherePattern splits a WHERE clause into parentheses, AND, OR and conditions
+	wherePattern = regexp.MustCompile("\\(|\\)|\\bAND\\b|\\bOR\\b|(?:`\\w+`\\.)?`?(\\w+)`? (=|<|>|IN) (\\?|\\([?,]+\\)|\\d+)")
+)
+
+// New creates a stub database with the given rows. IDs of inserted rows
+// continue after the highest id among them.
+func New(tables map[string][]Row) *DB {
+	db := &DB{tables: make(map[string][]Row)}
+	for table, rows := range tables {
+		for _, row := range rows {
+			db.tables[table] = append(db.tables[table], row)
+			if id, ok := row["id"].(int64); ok && id > db.nextID {
+				db.nextID = id
+			}
+		}
+	}
+	return db
+}
+
+// Open opens a GORM connection to the stub database
+func (db *DB) Open(t *testing.T) *gorm.DB {
+	t.Helper()
+	gdb, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sql.OpenDB(connector{db}),
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gdb
+}
+
+// Rows returns the rows of a table
+func (db *DB) Rows(table string) []Row {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return append([]Row(nil), db.tables[table]...)
+}
+
+// Get returns the row of a table with the given id, or nil
+func (db *DB) Get(table string, id int64) Row {
+	for _, row := range db.Rows(table) {
+		if row["id"] == id {
+			return row
+		}
+	}
+	return nil
+}
+
+// condition is a parsed WHERE clause
+type condition struct {
+	tokens [][]string
+	args   []driver.Value
+	pos    int
+	arg    int
+}
+
+// match reports whether a row satisfies the clause
+func (c *condition) match(row Row) (bool, error) {
+	c.pos, c.arg = 0, 0
+	ok, err := c.or(row)
+	if err == nil && c.pos != len(c.tokens) {
+		err = errors.New("unexpected " + c.tokens[c.pos][0])
+	}
+	return ok, err
+}
+
+func (c *condition) or(row Row) (bool, error) {
+	ok, err := c.and(row)
+	for err == nil && c.pos < len(c.tokens) && c.tokens[c.pos][0] == "OR" {
+		c.pos++
+		var next bool
+		next, err = c.and(row)
+		ok = ok || next
+	}
+	return ok, err
+}
+
+func (c *condition) and(row Row) (bool, error) {
+	ok, err := c.term(row)
+	for err == nil && c.pos < len(c.tokens) && c.tokens[c.pos][0] == "AND" {
+		c.pos++
+		var next bool
+		next, err = c.term(row)
+		ok = ok && next
+	}
+	return ok, err
+}
+
+func (c *condition) term(row Row) (bool, error) {
+	if c.pos >= len(c.tokens) {
+		return false, errors.New("incomplete condition")
+	}
+	token := c.tokens[c.pos]
+	c.pos++
+
+	if token[0] == "(" {
+		ok, err := c.or(row)
+		if err != nil {
+			return false, err
+		}
+		if c.pos >= len(c.tokens) || c.tokens[c.pos][0] != ")" {
+			return false, errors.New("unbalanced parentheses")
+		}
+		c.pos++
+		return ok, nil
+	}
+	if token[1] == "" {
+		return false, errors.New("unexpected " + token[0])
+	}
+
+	var values []driver.Value
+	if n, err := strconv.ParseInt(token[3], 10, 64); err == nil {
+		values = []driver.Value{n}
+	} else {
+		n := strings.Count(token[3], "?")
+		if c.arg+n > len(c.args) {
+			return false, errors.New("missing arguments")
+		}
+		values = c.args[c.arg : c.arg+n]
+		c.arg += n
+	}
+
+	value := normalize(row[token[1]])
+	switch token[2] {
+	case "<":
+		return compare(value, values[0]) < 0, nil
+	case ">":
+		return compare(value, values[0]) > 0, nil
+	}
+	for _, v := range values {
+		if value == normalize(v) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// normalize maps integer types to int64 so values compare equal
+func normalize(v driver.Value) driver.Value {
+	switch n := v.(type) {
+	case int:
+		return int64(n)
+	case int32:
+		return int64(n)
+	case uint64:
+		return int64(n)
+	}
+	return v
+}
+
+// compare orders two integer values
+func compare(a, b driver.Value) int {
+	x, _ := normalize(a).(int64)
+	y, _ := normalize(b).(int64)
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// where returns the indexes of the rows of a table matching the WHERE clause
+// of a statement. args are the arguments of the clause.
+func (db *DB) where(table, query string, args []driver.Value) ([]int, error) {
+	clause := ""
+	if i := strings.Index(query, " WHERE "); i >= 0 {
+		clause = query[i+len(" WHERE "):]
+		for _, end := range []string{" ORDER BY ", " LIMIT ", " FOR UPDATE"} {
+			if j := strings.Index(clause, end); j >= 0 {
+				clause = clause[:j]
+			}
+		}
+	}
+
+	cond := &condition{tokens: wherePattern.FindAllStringSubmatch(clause, -1), args: args}
+	var matched []int
+	for i, row := range db.tables[table] {
+		if clause != "" {
+			ok, err := cond.match(row)
+			if err != nil {
+				return nil, errors.New(err.Error() + " in: " + query)
+			}
+			if !ok {
+				continue
+			}
+		}
+		matched = append(matched, i)
+	}
+	return matched, nil
+}
+
+// query answers a SELECT
+func (db *DB) query(query string, args []driver.Value) ([]string, [][]driver.Value, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	match := tablePattern.FindStringSubmatch(query)
+	if match == nil {
+		return nil, nil, errors.New("unexpected query: " + query)
+	}
+	matched, err := db.where(match[1], query, args)
+	if err != nil {
+		return nil, nil, err
+	}
+	rows := make([]Row, len(matched))
+	for i, index := range matched {
+		rows[i] = db.tables[match[1]][index]
+	}
+
+	if strings.Contains(query, "count(*)") {
+		return []string{"count(*)"}, [][]driver.Value{{int64(len(rows))}}, nil
+	}
+	if match := limitPattern.FindStringSubmatch(query); match != nil {
+		if limit, _ := strconv.Atoi(match[1]); len(rows) > limit {
+			rows = rows[:limit]
+		}
+	}
+
+	var columns []string
+	for _, column := range columnPattern.FindAllStringSubmatch(query[:strings.Index(query, " FROM ")], -1) {
+		columns = append(columns, column[1])
+	}
+	if len(columns) == 0 {
+		seen := make(map[string]bool)
+		for _, row := range rows {
+			for column := range row {
+				if !seen[column] {
+					seen[column] = true
+					columns = append(columns, column)
+				}
+			}
+		}
+		sort.Strings(columns)
+	}
+
+	values := make([][]driver.Value, 0, len(rows))
+	for _, row := range rows {
+		value := make([]driver.Value, len(columns))
+		for i, column := range columns {
+			value[i] = row[column]
+		}
+		values = append(values, value)
+	}
+	return columns, values, nil
+}
+
+// exec runs an INSERT, UPDATE or DELETE
+func (db *DB) exec(query string, args []driver.Value) (driver.Result, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	match := tablePattern.FindStringSubmatch(query)
+	if match == nil {
+		return nil, errors.New("unexpected statement: " + query)
+	}
+	table := match[1]
+
+	switch {
+	case strings.HasPrefix(query, "INSERT"):
+		columns := columnPattern.FindAllStringSubmatch(query[len(match[0]):strings.Index(query, "VALUES")], -1)
+		if len(args)%len(columns) != 0 {
+			return nil, errors.New("unexpected arguments: " + query)
+		}
+		var first int64
+		for len(args) > 0 {
+			row := Row{}
+			for i, column := range columns {
+				row[column[1]] = args[i]
+			}
+			args = args[len(columns):]
+			if row["id"] == nil {
+				db.nextID++
+				row["id"] = db.nextID
+			}
+			if first == 0 {
+				first, _ = row["id"].(int64)
+			}
+			db.tables[table] = append(db.tables[table], row)
+		}
+		return result{id: first, rows: 1}, nil
+
+	case strings.HasPrefix(query, "UPDATE"):
+		set := columnPattern.FindAllStringSubmatch(setPattern.FindStringSubmatch(query)[1], -1)
+		matched, err := db.where(table, query, args[len(set):])
+		if err != nil {
+			return nil, err
+		}
+		for _, index := range matched {
+			for i, column := range set {
+				db.tables[table][index][column[1]] = args[i]
+			}
+		}
+		return result{rows: int64(len(matched))}, nil
+
+	case strings.HasPrefix(query, "DELETE"):
+		matched, err := db.where(table, query, args)
+		if err != nil {
+			return nil, err
+		}
+		for i := len(matched) - 1; i >= 0; i-- {
+			index := matched[i]
+			db.tables[table] = append(db.tables[table][:index], db.tables[table][index+1:]...)
+		}
+		return result{rows: int64(len(matched))}, nil
+	}
+	return nil, errors.New("unexpected statement: " + query)
+}
+
+// connector, conn, tx and rows implement a database/sql driver over a DB
+type connector struct{ db *DB }
+
+func (c connector) Connect(context.Context) (driver.Conn, error) { return conn{c.db}, nil }
+func (c connector) Driver() driver.Driver                        { return stubDriver{c.db} }
+
+type stubDriver struct{ db *DB }
+
+func (d stubDriver) Open(string) (driver.Conn, error) { return conn{d.db}, nil }
+
+type conn struct{ db *DB }
+
+func (c conn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c conn) Close() error                        { return nil }
+func (c conn) Begin() (driver.Tx, error)           { return tx{}, nil }
+
+func (c conn) QueryContext(_ context.Context, query string, named []driver.NamedValue) (driver.Rows, error) {
+	columns, values, err := c.db.query(query, namedValues(named))
+	if err != nil {
+		return nil, err
+	}
+	return &rows{columns: columns, values: values}, nil
+}
+
+func (c conn) ExecContext(_ context.Context, query string, named []driver.NamedValue) (driver.Result, error) {
+	return c.db.exec(query, namedValues(named))
+}
+
+type tx struct{}
+
+func (tx) Commit() error   { return nil }
+func (tx) Rollback() error { return nil }
+
+type result struct{ id, rows int64 }
+
+func (r result) LastInsertId() (int64, error) { return r.id, nil }
+func (r result) RowsAffected() (int64, error) { return r.rows, nil }
+
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *rows) Columns() []string { return r.columns }
+func (r *rows) Close() error      { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+// namedValues drops the names of statement arguments
+func namedValues(named []driver.NamedValue) []driver.Value {
+	args := make([]driver.Value, len(named))
+	for i, arg := range named {
+		args[i] = arg.Value
+	}
+	return args
+}
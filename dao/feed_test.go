@@ -0,0 +1,323 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// The listing tests run the DAOs against a stub database that answers the
+// few queries they make from in-memory rows and counts every statement, so
+// they can check that a page costs the same number of queries at any size.
+
+const feedViewerID = 1000
+
+// feedFixture holds the rows of the stub database
+type feedFixture struct {
+	posts      []Post
+	images     []PostImage
+	postLikes  []PostLike
+	comments   []Comment
+	commLikes  []CommentLike
+	profiles   []UserProfile
+	statements int
+}
+
+var limitPattern = regexp.MustCompile(`LIMIT (\d+)`)
+
+// newFeedFixture creates posts with two images each, written by five users
+// whose profiles hold private details. The viewer liked every other post.
+// Each post has top-level comments with a reply chain down to the deepest
+// level, and the viewer liked every reply.
+func newFeedFixture(posts, commentsPerPost int) *feedFixture {
+	f := &feedFixture{}
+	for u := int64(1); u <= 5; u++ {
+		f.profiles = append(f.profiles, UserProfile{
+			ID: u, UserNickname: "user" + strconv.FormatInt(u, 10), UserAvatar: "avatar.png", Role: "user",
+			PatientName: privatePatientName, IllnessCause: privateIllnessCause, MobileNumber: privateMobileNumber,
+		})
+	}
+
+	commentID := int64(0)
+	for i := 1; i <= posts; i++ {
+		post := Post{ID: int64(i), UserID: int64(i%5 + 1), Content: "post", CreatedAt: int64(1000 + i)}
+		f.posts = append([]Post{post}, f.posts...) // Newest first, as the feed is ordered
+		f.images = append(f.images,
+			PostImage{ID: int64(2 * i), PostID: post.ID, DisplayOrder: 0},
+			PostImage{ID: int64(2*i + 1), PostID: post.ID, DisplayOrder: 1})
+		if i%2 == 0 {
+			f.postLikes = append(f.postLikes, PostLike{PostID: post.ID, UserID: feedViewerID})
+		}
+
+		for c := 0; c < commentsPerPost; c++ {
+			var parentID *int64
+			for level := 0; level <= maxCommentLevel; level++ {
+				commentID++
+				f.comments = append(f.comments, Comment{
+					ID: commentID, PostID: post.ID, UserID: commentID%5 + 1, ParentID: parentID,
+					Level: level, Content: "comment", CreatedAt: 5000 - commentID,
+				})
+				if level > 0 {
+					f.commLikes = append(f.commLikes, CommentLike{CommentID: commentID, UserID: feedViewerID})
+				}
+				id := commentID
+				parentID = &id
+			}
+		}
+	}
+	return f
+}
+
+// db opens a GORM connection to the stub database
+func (f *feedFixture) db(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sql.OpenDB(feedConnector{f}),
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// countStatements returns how many statements fn sent to the database
+func (f *feedFixture) countStatements(fn func()) int {
+	f.statements = 0
+	fn()
+	return f.statements
+}
+
+// query answers a SELECT from the fixture rows
+func (f *feedFixture) query(query string, args []driver.Value) ([]string, [][]driver.Value, error) {
+	f.statements++
+
+	limit := -1
+	if match := limitPattern.FindStringSubmatch(query); match != nil {
+		limit, _ = strconv.Atoi(match[1])
+	}
+	ids := make(map[int64]bool)
+	for _, arg := range args {
+		if id, ok := arg.(int64); ok {
+			ids[id] = true
+		}
+	}
+
+	var columns []string
+	var rows [][]driver.Value
+	switch {
+	case strings.Contains(query, "count(*)"):
+		return []string{"count(*)"}, [][]driver.Value{{int64(len(f.posts))}}, nil
+
+	case strings.Contains(query, "FROM `posts`"):
+		columns = []string{"id", "user_id", "content", "created_at"}
+		for _, p := range f.posts {
+			rows = append(rows, []driver.Value{p.ID, p.UserID, p.Content, p.CreatedAt})
+		}
+
+	case strings.Contains(query, "FROM `post_images`"):
+		columns = []string{"id", "post_id", "display_order"}
+		for _, img := range f.images {
+			if ids[img.PostID] {
+				rows = append(rows, []driver.Value{img.ID, img.PostID, int64(img.DisplayOrder)})
+			}
+		}
+
+	case strings.Contains(query, "FROM `post_likes`"):
+		columns = []string{"post_id"}
+		for _, like := range f.postLikes {
+			if ids[like.PostID] && like.UserID == args[0].(int64) {
+				rows = append(rows, []driver.Value{like.PostID})
+			}
+		}
+
+	case strings.Contains(query, "FROM `comment_likes`"):
+		columns = []string{"comment_id"}
+		for _, like := range f.commLikes {
+			if ids[like.CommentID] && like.UserID == args[0].(int64) {
+				rows = append(rows, []driver.Value{like.CommentID})
+			}
+		}
+
+	case strings.Contains(query, "FROM `comments`"):
+		columns = []string{"id", "post_id", "user_id", "parent_id", "level", "content", "created_at"}
+		replies := strings.Contains(query, "parent_id IN")
+		for _, c := range f.comments {
+			var parentID driver.Value
+			if c.ParentID != nil {
+				parentID = *c.ParentID
+			}
+			if (replies && c.ParentID != nil && ids[*c.ParentID]) || (!replies && c.Level == 0 && c.PostID == args[0].(int64)) {
+				rows = append(rows, []driver.Value{c.ID, c.PostID, c.UserID, parentID, int64(c.Level), c.Content, c.CreatedAt})
+			}
+		}
+
+	case strings.Contains(query, "FROM `user_profiles`"):
+		// Every column is returned whatever the query selects, so a leak would show
+		columns = []string{"id", "user_nickname", "user_avatar", "role", "patient_name", "illness_cause", "mobile_number", "key_version"}
+		for _, p := range f.profiles {
+			if ids[p.ID] {
+				rows = append(rows, []driver.Value{p.ID, p.UserNickname, p.UserAvatar, p.Role, p.PatientName, p.IllnessCause, p.MobileNumber, int64(0)})
+			}
+		}
+
+	default:
+		return nil, nil, errors.New("unexpected query: " + query)
+	}
+
+	if limit >= 0 && len(rows) > limit {
+		rows = rows[:limit]
+	}
+	return columns, rows, nil
+}
+
+func TestListPostsQueryCountIsConstant(t *testing.T) {
+	f := newFeedFixture(20, 0)
+	postDAO := NewPostDAO(f.db(t))
+
+	for _, pageSize := range []int{2, 10} {
+		var posts []Post
+		var err error
+		statements := f.countStatements(func() {
+			posts, _, err = postDAO.ListPostsAfter(nil, pageSize, 0, feedViewerID)
+		})
+		if err != nil {
+			t.Fatalf("page size %d: %v", pageSize, err)
+		}
+		// Posts, images, likes and authors
+		if statements != 4 {
+			t.Errorf("page size %d: %d queries, want 4", pageSize, statements)
+		}
+		if len(posts) != pageSize {
+			t.Fatalf("page size %d: got %d posts", pageSize, len(posts))
+		}
+
+		for _, post := range posts {
+			if len(post.Images) != 2 || post.Images[0].PostID != post.ID {
+				t.Errorf("post %d: images %+v", post.ID, post.Images)
+			}
+			if want := post.ID%2 == 0; post.Liked != want {
+				t.Errorf("post %d: liked %v, want %v", post.ID, post.Liked, want)
+			}
+			if post.UserInfo == nil || post.UserInfo.ID != post.UserID {
+				t.Errorf("post %d: author %+v, want user %d", post.ID, post.UserInfo, post.UserID)
+			}
+		}
+
+		statements = f.countStatements(func() {
+			_, _, err = postDAO.ListPosts(1, pageSize, 0, feedViewerID)
+		})
+		if err != nil {
+			t.Fatalf("page size %d: %v", pageSize, err)
+		}
+		// The offset mode counts the posts as well
+		if statements != 5 {
+			t.Errorf("page size %d, offset mode: %d queries, want 5", pageSize, statements)
+		}
+	}
+}
+
+func TestListCommentsQueryCountIsConstant(t *testing.T) {
+	f := newFeedFixture(1, 10)
+	commentDAO := NewCommentDAO(f.db(t))
+
+	for _, pageSize := range []int{2, 5} {
+		var comments []Comment
+		var err error
+		statements := f.countStatements(func() {
+			comments, _, err = commentDAO.ListCommentsAfter(1, nil, pageSize, feedViewerID)
+		})
+		if err != nil {
+			t.Fatalf("page size %d: %v", pageSize, err)
+		}
+		// Comments, one query per reply level, likes and authors
+		if want := 3 + maxCommentLevel; statements != want {
+			t.Errorf("page size %d: %d queries, want %d", pageSize, statements, want)
+		}
+		if len(comments) != pageSize {
+			t.Fatalf("page size %d: got %d comments", pageSize, len(comments))
+		}
+
+		for _, comment := range comments {
+			depth := 0
+			for c := comment; ; c = c.Replies[0] {
+				if c.UserInfo == nil || c.UserInfo.ID != c.UserID {
+					t.Errorf("comment %d: author %+v, want user %d", c.ID, c.UserInfo, c.UserID)
+				}
+				if want := c.Level > 0; c.Liked != want {
+					t.Errorf("comment %d: liked %v, want %v", c.ID, c.Liked, want)
+				}
+				if len(c.Replies) == 0 {
+					break
+				}
+				if len(c.Replies) != 1 || *c.Replies[0].ParentID != c.ID {
+					t.Fatalf("comment %d: replies %+v", c.ID, c.Replies)
+				}
+				depth++
+			}
+			if depth != maxCommentLevel {
+				t.Errorf("comment %d: %d reply levels, want %d", comment.ID, depth, maxCommentLevel)
+			}
+		}
+	}
+}
+
+// feedConnector, feedConn and feedRows implement a database/sql driver over a feedFixture
+type feedConnector struct{ f *feedFixture }
+
+func (c feedConnector) Connect(context.Context) (driver.Conn, error) { return feedConn{c.f}, nil }
+func (c feedConnector) Driver() driver.Driver                        { return feedDriver{c.f} }
+
+type feedDriver struct{ f *feedFixture }
+
+func (d feedDriver) Open(string) (driver.Conn, error) { return feedConn{d.f}, nil }
+
+type feedConn struct{ f *feedFixture }
+
+func (c feedConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c feedConn) Close() error                        { return nil }
+func (c feedConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c feedConn) QueryContext(_ context.Context, query string, named []driver.NamedValue) (driver.Rows, error) {
+	args := make([]driver.Value, len(named))
+	for i, arg := range named {
+		args[i] = arg.Value
+	}
+	columns, rows, err := c.f.query(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &feedRows{columns: columns, rows: rows}, nil
+}
+
+func (c feedConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	c.f.statements++
+	return driver.RowsAffected(1), nil
+}
+
+type feedRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *feedRows) Columns() []string { return r.columns }
+func (r *feedRows) Close() error      { return nil }
+
+func (r *feedRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
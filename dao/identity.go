@@ -0,0 +1,87 @@
+package dao
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserIdentity represents the user_identities table structure.
+// It links an account at an external identity provider to a user profile.
+type UserIdentity struct {
+	ID        int64  `json:"id" gorm:"primaryKey"`
+	UserID    int64  `json:"user_id" gorm:"index"`
+	Provider  string `json:"provider"`
+	Subject   string `json:"-"` // Provider's user ID
+	Nickname  string `json:"nickname"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// TableName specifies the table name for GORM
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
+
+// IdentityDAO handles database operations for linked identities
+type IdentityDAO struct {
+	db *gorm.DB
+}
+
+// NewIdentityDAO creates a new IdentityDAO
+func NewIdentityDAO(db *gorm.DB) *IdentityDAO {
+	return &IdentityDAO{db: db}
+}
+
+// GetByProviderSubject retrieves the identity linked to an external account
+func (dao *IdentityDAO) GetByProviderSubject(provider, subject string) (*UserIdentity, error) {
+	var identity UserIdentity
+	err := dao.db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("identity not found")
+		}
+		return nil, err
+	}
+
+	return &identity, nil
+}
+
+// ListByUser retrieves all identities linked to a user
+func (dao *IdentityDAO) ListByUser(userID int64) ([]UserIdentity, error) {
+	var identities []UserIdentity
+	err := dao.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&identities).Error
+	return identities, err
+}
+
+// Link connects an external account to a user.
+// A user can link at most one account per provider.
+func (dao *IdentityDAO) Link(identity *UserIdentity) error {
+	var count int64
+	if err := dao.db.Model(&UserIdentity{}).
+		Where("(provider = ? AND subject = ?) OR (provider = ? AND user_id = ?)",
+			identity.Provider, identity.Subject, identity.Provider, identity.UserID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("identity already linked")
+	}
+
+	identity.CreatedAt = time.Now().UnixMilli()
+	return dao.db.Create(identity).Error
+}
+
+// Unlink removes the link between a user and a provider
+func (dao *IdentityDAO) Unlink(userID int64, provider string) error {
+	result := dao.db.Where("user_id = ? AND provider = ?", userID, provider).Delete(&UserIdentity{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("identity not found")
+	}
+
+	return nil
+}
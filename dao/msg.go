@@ -7,6 +7,12 @@ import (
 
 // CreateMessage inserts a new message into the database
 func CreateMessage(msg *models.Message) error {
+	restore, err := sealMessage(msg)
+	if err != nil {
+		return err
+	}
+	defer restore()
+
 	return config.DB.Create(msg).Error
 }
 
@@ -20,6 +26,8 @@ func GetMessages(chatID string, lastID uint, pageSize int) ([]models.Message, er
 		query = query.Where("id > ?", lastID)
 	}
 
-	err := query.Order("id").Limit(pageSize).Find(&messages).Error
-	return messages, err
+	if err := query.Order("id").Limit(pageSize).Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	return messages, openMessages(messages)
 }
@@ -1,69 +1,388 @@
 package dao
 
 import (
+	"errors"
+	"reflect"
+	"time"
+
 	"hope_backend/config"
 	"hope_backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CreateNote inserts a new note into the database
 func CreateNote(note *models.Note) error {
-	return config.DB.Create(note).Error
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		seq, err := nextSyncSeq(tx, note.UserID)
+		if err != nil {
+			return err
+		}
+
+		restore, err := sealNote(note)
+		if err != nil {
+			return err
+		}
+		defer restore()
+
+		note.Version = 1
+		note.SyncSeq = seq
+		note.Attachments = []models.NoteAttachment{}
+		return tx.Create(note).Error
+	})
 }
 
-// GetNoteByID retrieves a note by its ID
+// GetNoteByID retrieves a note by its ID, ignoring notes in the trash
 func GetNoteByID(noteID int) (*models.Note, error) {
 	var note models.Note
-	err := config.DB.Where("note_id = ?", noteID).First(&note).Error
+	err := config.DB.Where("note_id = ? AND deleted_at = 0", noteID).First(&note).Error
 	if err != nil {
 		return nil, err
 	}
+	if err := loadNote(&note); err != nil {
+		return nil, err
+	}
 	return &note, nil
 }
 
 // GetNoteByUserAndDate retrieves a note by user ID and date
-func GetNoteByUserAndDate(userID int64, noteDate string) (*models.Note, error) {
+func GetNoteByUserAndDate(userID int64, noteDate models.Date) (*models.Note, error) {
 	var note models.Note
-	err := config.DB.Where("user_id = ? AND note_date = ?", userID, noteDate).First(&note).Error
+	err := config.DB.Where("user_id = ? AND note_date = ? AND deleted_at = 0", userID, noteDate).First(&note).Error
 	if err != nil {
 		return nil, err
 	}
+	if err := loadNote(&note); err != nil {
+		return nil, err
+	}
 	return &note, nil
 }
 
 // GetNotesByUserID retrieves all notes for a specific user
 func GetNotesByUserID(userID int64) ([]models.Note, error) {
 	var notes []models.Note
-	err := config.DB.Where("user_id = ?", userID).Order("note_date DESC").Find(&notes).Error
-	return notes, err
+	if err := config.DB.Where("user_id = ? AND deleted_at = 0", userID).Order("note_date DESC").Find(&notes).Error; err != nil {
+		return nil, err
+	}
+	return notes, loadNotes(notes)
 }
 
-// GetNotesByDateRange retrieves notes for a user within a date range
-func GetNotesByDateRange(userID int64, startDate, endDate string) ([]models.Note, error) {
+// GetNotesByDateRange retrieves notes for a user within a date range (both ends inclusive)
+func GetNotesByDateRange(userID int64, startDate, endDate models.Date) ([]models.Note, error) {
 	var notes []models.Note
-	err := config.DB.Where("user_id = ? AND note_date BETWEEN ? AND ?", userID, startDate, endDate).
+	err := config.DB.Where("user_id = ? AND deleted_at = 0 AND note_date BETWEEN ? AND ?", userID, startDate, endDate).
 		Order("note_date ASC").Find(&notes).Error
-	return notes, err
+	if err != nil {
+		return nil, err
+	}
+	return notes, loadNotes(notes)
 }
 
-// UpdateNote modifies an existing note
+// UpdateNote saves the current version of a note as a revision, then writes
+// the new content and check-in fields. Nothing is saved if nothing changed.
 func UpdateNote(note *models.Note) error {
-	return config.DB.Model(note).Where("note_id = ? AND user_id = ?", note.NoteID, note.UserID).
-		Update("content", note.Content).Error
+	return updateNote(note, 0)
+}
+
+// UpdateNoteAtVersion is UpdateNote for clients that edited a known version.
+// It fails with "note version conflict" if the note changed in the meantime.
+func UpdateNoteAtVersion(note *models.Note, baseVersion int) error {
+	if baseVersion < 1 {
+		return errors.New("note version conflict")
+	}
+	return updateNote(note, baseVersion)
+}
+
+// errNoteUnchanged rolls back an update that would not change anything
+var errNoteUnchanged = errors.New("note unchanged")
+
+// updateNote implements UpdateNote; a baseVersion of 0 skips the version check
+func updateNote(note *models.Note, baseVersion int) error {
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Take the user's sync counter first so concurrent writes of one user are serialised
+		seq, err := nextSyncSeq(tx, note.UserID)
+		if err != nil {
+			return err
+		}
+
+		var current models.Note
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("note_id = ? AND user_id = ? AND deleted_at = 0", note.NoteID, note.UserID).
+			First(&current).Error
+		if err != nil {
+			return err
+		}
+
+		if baseVersion > 0 && current.Version != baseVersion {
+			return errors.New("note version conflict")
+		}
+
+		// The revision keeps the stored, possibly encrypted, content; compare on plaintext
+		revision := models.NewNoteRevision(&current, time.Now().Unix())
+		if err := openNote(&current); err != nil {
+			return err
+		}
+
+		if sameNoteContent(&current, note) {
+			note.UpdatedAt = current.UpdatedAt
+			note.Version = current.Version
+			note.SyncSeq = current.SyncSeq
+			return errNoteUnchanged
+		}
+
+		if err := tx.Create(revision).Error; err != nil {
+			return err
+		}
+
+		restore, err := sealNote(note)
+		if err != nil {
+			return err
+		}
+		defer restore()
+
+		note.Version = current.Version + 1
+		note.SyncSeq = seq
+
+		// Select the columns explicitly so cleared check-in fields are written as NULL
+		return tx.Model(note).Where("note_id = ? AND user_id = ?", note.NoteID, note.UserID).
+			Select("content", "key_version", "prompt_id", "patient_mood", "caregiver_mood", "sleep_hours", "emotion_tags", "updated_at", "version", "sync_seq").
+			Updates(note).Error
+	})
+	if errors.Is(err, errNoteUnchanged) {
+		return nil
+	}
+	return err
+}
+
+// sameNoteContent reports whether two versions of a note have identical user-visible fields
+func sameNoteContent(a, b *models.Note) bool {
+	return a.Content == b.Content &&
+		reflect.DeepEqual(a.PromptID, b.PromptID) &&
+		reflect.DeepEqual(a.PatientMood, b.PatientMood) &&
+		reflect.DeepEqual(a.CaregiverMood, b.CaregiverMood) &&
+		reflect.DeepEqual(a.SleepHours, b.SleepHours) &&
+		len(a.EmotionTags) == len(b.EmotionTags) &&
+		(len(a.EmotionTags) == 0 || reflect.DeepEqual(a.EmotionTags, b.EmotionTags))
 }
 
-// DeleteNote removes a note from the database
+// DeleteNote moves a note to the trash, where it can be restored until it is purged.
+// It fails with gorm.ErrRecordNotFound if the user has no such note outside the trash.
 func DeleteNote(noteID int, userID int64) error {
-	return config.DB.Where("note_id = ? AND user_id = ?", noteID, userID).Delete(&models.Note{}).Error
+	return deleteNote(noteID, userID, 0)
+}
+
+// DeleteNoteAtVersion is DeleteNote for clients that deleted a known version.
+// It fails with "note version conflict" if the note changed in the meantime.
+func DeleteNoteAtVersion(noteID int, userID int64, baseVersion int) error {
+	if baseVersion < 1 {
+		return errors.New("note version conflict")
+	}
+	return deleteNote(noteID, userID, baseVersion)
+}
+
+// deleteNote implements DeleteNote; a baseVersion of 0 skips the version check
+func deleteNote(noteID int, userID int64, baseVersion int) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		seq, err := nextSyncSeq(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		query := tx.Model(&models.Note{}).Where("note_id = ? AND user_id = ? AND deleted_at = 0", noteID, userID)
+		if baseVersion > 0 {
+			query = query.Where("version = ?", baseVersion)
+		}
+		result := query.Updates(map[string]interface{}{
+			"deleted_at": time.Now().Unix(),
+			"version":    gorm.Expr("version + 1"),
+			"sync_seq":   seq,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// Returning an error rolls back the sync sequence number taken above
+			if baseVersion > 0 {
+				return errors.New("note version conflict")
+			}
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
 }
 
 // GetNotesByMonth retrieves all notes for a user for a specific month
-func GetNotesByMonth(userID int64, year, month string) ([]models.Note, error) {
+func GetNotesByMonth(userID int64, year int, month time.Month) ([]models.Note, error) {
+	first := models.NewDate(year, month, 1)
+	last := models.Date{Time: first.AddDate(0, 1, -1)}
+
+	return GetNotesByDateRange(userID, first, last)
+}
+
+// GetTrashedNotes retrieves the notes a user moved to the trash after the given time, newest first
+func GetTrashedNotes(userID int64, deletedAfter int64) ([]models.Note, error) {
 	var notes []models.Note
+	err := config.DB.Where("user_id = ? AND deleted_at > ?", userID, deletedAfter).
+		Order("deleted_at DESC").Find(&notes).Error
+	if err != nil {
+		return nil, err
+	}
+	return notes, loadNotes(notes)
+}
 
-	// Construct date pattern for the specified month (e.g., "2023.1.%")
-	datePattern := year + "." + month + ".%"
+// RestoreNote takes a note out of the trash. It fails if the user has
+// written a new note for the same date in the meantime.
+func RestoreNote(noteID int, userID int64, deletedAfter int64) (*models.Note, error) {
+	var note models.Note
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		seq, err := nextSyncSeq(tx, userID)
+		if err != nil {
+			return err
+		}
 
-	err := config.DB.Where("user_id = ? AND note_date LIKE ?", userID, datePattern).
-		Order("note_date ASC").Find(&notes).Error
-	return notes, err
+		err = tx.Where("note_id = ? AND user_id = ? AND deleted_at > ?", noteID, userID, deletedAfter).First(&note).Error
+		if err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&models.Note{}).
+			Where("user_id = ? AND note_date = ? AND deleted_at = 0", userID, note.NoteDate).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errors.New("a note already exists for this date")
+		}
+
+		note.DeletedAt = 0
+		note.Version++
+		note.SyncSeq = seq
+		return tx.Model(&note).Updates(map[string]interface{}{
+			"deleted_at": 0,
+			"version":    note.Version,
+			"sync_seq":   seq,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := loadNote(&note); err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// PurgeNote permanently deletes a note in the trash together with its revisions
+// and attachments, leaving a tombstone for clients that have not synced the
+// deletion yet. It returns the attachment files that should be removed from disk.
+func PurgeNote(noteID int, userID int64) ([]string, error) {
+	var files []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		seq, err := nextSyncSeq(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		var note models.Note
+		err = tx.Where("note_id = ? AND user_id = ? AND deleted_at > 0", noteID, userID).First(&note).Error
+		if err != nil {
+			return err
+		}
+		files, err = purgeNote(tx, &note, seq)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// PurgeExpiredTrash permanently deletes notes that were moved to the trash
+// before the cutoff, together with their revisions and attachments. It returns
+// the number of notes purged and the attachment files to remove from disk.
+func PurgeExpiredTrash(deletedBefore int64) (int64, []string, error) {
+	var notes []models.Note
+	if err := config.DB.Where("deleted_at > 0 AND deleted_at < ?", deletedBefore).Find(&notes).Error; err != nil {
+		return 0, nil, err
+	}
+
+	var purged int64
+	var files []string
+	for i := range notes {
+		if err := config.DB.Transaction(func(tx *gorm.DB) error {
+			seq, err := nextSyncSeq(tx, notes[i].UserID)
+			if err != nil {
+				return err
+			}
+			noteFiles, err := purgeNote(tx, &notes[i], seq)
+			if err != nil {
+				return err
+			}
+			files = append(files, noteFiles...)
+			return nil
+		}); err != nil {
+			return purged, files, err
+		}
+		purged++
+	}
+	return purged, files, nil
+}
+
+// purgeNote deletes a note with its revisions and attachments, records a
+// tombstone and returns the attachment files
+func purgeNote(tx *gorm.DB, note *models.Note, seq int64) ([]string, error) {
+	if err := tx.Create(&models.NoteTombstone{
+		UserID:    note.UserID,
+		NoteID:    note.NoteID,
+		ClientID:  note.ClientID,
+		SyncSeq:   seq,
+		DeletedAt: time.Now().Unix(),
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	var files []string
+	if err := tx.Model(&models.NoteAttachment{}).Where("note_id = ?", note.NoteID).Pluck("file_path", &files).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("note_id = ?", note.NoteID).Delete(&models.NoteAttachment{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("note_id = ?", note.NoteID).Delete(&models.NoteRevision{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("note_id = ?", note.NoteID).Delete(&models.NoteReflection{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("note_id = ?", note.NoteID).Delete(&models.Note{}).Error; err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// GetNoteRevisions retrieves the saved versions of a note, newest first
+func GetNoteRevisions(noteID int, userID int64) ([]models.NoteRevision, error) {
+	var revisions []models.NoteRevision
+	err := config.DB.Where("note_id = ? AND user_id = ?", noteID, userID).
+		Order("revision_id DESC").Find(&revisions).Error
+	if err != nil {
+		return nil, err
+	}
+	return revisions, openNoteRevisions(revisions)
+}
+
+// GetNoteRevision retrieves one saved version of a note
+func GetNoteRevision(noteID int, revisionID int64, userID int64) (*models.NoteRevision, error) {
+	var revision models.NoteRevision
+	err := config.DB.Where("revision_id = ? AND note_id = ? AND user_id = ?", revisionID, noteID, userID).
+		First(&revision).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := openNoteRevision(&revision); err != nil {
+		return nil, err
+	}
+	return &revision, nil
 }
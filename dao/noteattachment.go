@@ -0,0 +1,160 @@
+package dao
+
+import (
+	"errors"
+	"time"
+
+	"hope_backend/config"
+	"hope_backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateNoteAttachment attaches a stored photo to a live note of the user. It
+// fails with "too many attachments" if the note already has limit photos.
+// The note's version and sync position move on so other devices fetch the photo.
+func CreateNoteAttachment(attachment *models.NoteAttachment, limit int) error {
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		seq, err := nextSyncSeq(tx, attachment.UserID)
+		if err != nil {
+			return err
+		}
+
+		var note models.Note
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("note_id = ? AND user_id = ? AND deleted_at = 0", attachment.NoteID, attachment.UserID).
+			First(&note).Error
+		if err != nil {
+			return err
+		}
+
+		var existing struct {
+			Count    int
+			MaxOrder int
+		}
+		err = tx.Model(&models.NoteAttachment{}).Where("note_id = ?", note.NoteID).
+			Select("COUNT(*) AS count, COALESCE(MAX(display_order), -1) AS max_order").Scan(&existing).Error
+		if err != nil {
+			return err
+		}
+		if existing.Count >= limit {
+			return errors.New("too many attachments")
+		}
+
+		attachment.DisplayOrder = existing.MaxOrder + 1
+		attachment.CreatedAt = time.Now().Unix()
+		if err := tx.Create(attachment).Error; err != nil {
+			return err
+		}
+		return touchNote(tx, &note, seq)
+	})
+	if err != nil {
+		return err
+	}
+
+	attachment.SetURLs()
+	return nil
+}
+
+// GetNoteAttachment retrieves an attachment by ID, including attachments of notes in the trash
+func GetNoteAttachment(attachmentID int64) (*models.NoteAttachment, error) {
+	var attachment models.NoteAttachment
+	err := config.DB.Where("attachment_id = ?", attachmentID).First(&attachment).Error
+	if err != nil {
+		return nil, err
+	}
+	attachment.SetURLs()
+	return &attachment, nil
+}
+
+// DeleteNoteAttachment removes a photo from a live note of the user and
+// returns it, so the caller can delete the files
+func DeleteNoteAttachment(attachmentID int64, noteID int, userID int64) (*models.NoteAttachment, error) {
+	var attachment models.NoteAttachment
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		seq, err := nextSyncSeq(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		var note models.Note
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("note_id = ? AND user_id = ? AND deleted_at = 0", noteID, userID).
+			First(&note).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("attachment_id = ? AND note_id = ?", attachmentID, noteID).First(&attachment).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&attachment).Error; err != nil {
+			return err
+		}
+		return touchNote(tx, &note, seq)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// touchNote records a change to a note that is not part of its revisions, such as a new photo
+func touchNote(tx *gorm.DB, note *models.Note, seq int64) error {
+	return tx.Model(note).UpdateColumns(map[string]interface{}{
+		"updated_at": time.Now().Unix(),
+		"version":    gorm.Expr("version + 1"),
+		"sync_seq":   seq,
+	}).Error
+}
+
+// loadNoteAttachments sets the attachments of notes, in display order
+func loadNoteAttachments(notes []models.Note) error {
+	if len(notes) == 0 {
+		return nil
+	}
+
+	noteIDs := make([]int, len(notes))
+	for i := range notes {
+		noteIDs[i] = notes[i].NoteID
+	}
+
+	var attachments []models.NoteAttachment
+	err := config.DB.Where("note_id IN ?", noteIDs).Order("note_id, display_order, attachment_id").Find(&attachments).Error
+	if err != nil {
+		return err
+	}
+
+	byNote := make(map[int][]models.NoteAttachment)
+	for _, attachment := range attachments {
+		attachment.SetURLs()
+		byNote[attachment.NoteID] = append(byNote[attachment.NoteID], attachment)
+	}
+	for i := range notes {
+		notes[i].Attachments = byNote[notes[i].NoteID]
+		if notes[i].Attachments == nil {
+			notes[i].Attachments = []models.NoteAttachment{}
+		}
+	}
+	return nil
+}
+
+// loadNote decrypts a note read from the database and loads its attachments
+func loadNote(note *models.Note) error {
+	notes := []models.Note{*note}
+	if err := loadNotes(notes); err != nil {
+		return err
+	}
+	*note = notes[0]
+	return nil
+}
+
+// loadNotes decrypts notes read from the database and loads their attachments
+func loadNotes(notes []models.Note) error {
+	if err := openNotes(notes); err != nil {
+		return err
+	}
+	return loadNoteAttachments(notes)
+}
@@ -0,0 +1,87 @@
+package dao
+
+import (
+	"errors"
+
+	"hope_backend/config"
+	"hope_backend/models"
+
+	"gorm.io/gorm"
+)
+
+// CreateNoteImportJob inserts a pending import job
+func CreateNoteImportJob(job *models.NoteImportJob) error {
+	job.Status = models.ImportStatusPending
+	return config.DB.Create(job).Error
+}
+
+// GetNoteImportJob retrieves an import job of a user
+func GetNoteImportJob(jobID int64, userID int64) (*models.NoteImportJob, error) {
+	var job models.NoteImportJob
+	if err := config.DB.Where("job_id = ? AND user_id = ?", jobID, userID).First(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetNoteImportJobs retrieves the latest import jobs of a user, newest first
+func GetNoteImportJobs(userID int64, limit int) ([]models.NoteImportJob, error) {
+	var jobs []models.NoteImportJob
+	err := config.DB.Where("user_id = ?", userID).Order("job_id DESC").Limit(limit).Find(&jobs).Error
+	return jobs, err
+}
+
+// HasActiveNoteImport reports whether a user has an import that has not finished yet
+func HasActiveNoteImport(userID int64) (bool, error) {
+	var count int64
+	err := config.DB.Model(&models.NoteImportJob{}).
+		Where("user_id = ? AND status IN ?", userID, []string{models.ImportStatusPending, models.ImportStatusRunning}).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ClaimNoteImportJob marks the oldest pending job as running and returns it.
+// Running jobs without progress since staleBefore were cut off, usually by a
+// restart, and are claimed again. It returns nil if there is nothing to do.
+func ClaimNoteImportJob(staleBefore, now int64) (*models.NoteImportJob, error) {
+	for {
+		var job models.NoteImportJob
+		err := config.DB.Where("status = ? OR (status = ? AND updated_at < ?)",
+			models.ImportStatusPending, models.ImportStatusRunning, staleBefore).
+			Order("job_id ASC").First(&job).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		startedAt := job.StartedAt
+		if startedAt == 0 {
+			startedAt = now
+		}
+
+		// Only one worker wins a job; the others look for the next one
+		result := config.DB.Model(&models.NoteImportJob{}).
+			Where("job_id = ? AND status = ? AND updated_at = ?", job.JobID, job.Status, job.UpdatedAt).
+			Updates(map[string]interface{}{
+				"status":     models.ImportStatusRunning,
+				"started_at": startedAt,
+				"updated_at": now,
+			})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected > 0 {
+			job.Status, job.StartedAt, job.UpdatedAt = models.ImportStatusRunning, startedAt, now
+			return &job, nil
+		}
+	}
+}
+
+// SaveNoteImportJob writes the status, counters and errors of a job
+func SaveNoteImportJob(job *models.NoteImportJob) error {
+	return config.DB.Model(job).
+		Select("status", "total", "processed", "created", "merged", "skipped", "failed", "errors", "message", "updated_at", "finished_at").
+		Updates(job).Error
+}
@@ -0,0 +1,40 @@
+package dao
+
+import (
+	"hope_backend/config"
+	"hope_backend/fieldcrypt"
+	"hope_backend/models"
+
+	"gorm.io/gorm"
+)
+
+// SaveNoteReflection stores the reflection of a note, replacing any earlier one
+func SaveNoteReflection(reflection *models.NoteReflection) error {
+	plaintext := reflection.Content
+	sealed, version, err := fieldcrypt.Encrypt(plaintext, reflectionContext)
+	if err != nil {
+		return err
+	}
+	reflection.Content, reflection.KeyVersion = sealed, version
+	defer func() { reflection.Content = plaintext }()
+
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("note_id = ?", reflection.NoteID).Delete(&models.NoteReflection{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(reflection).Error
+	})
+}
+
+// GetNoteReflection retrieves the reflection of a user's note
+func GetNoteReflection(noteID int, userID int64) (*models.NoteReflection, error) {
+	var reflection models.NoteReflection
+	err := config.DB.Where("note_id = ? AND user_id = ?", noteID, userID).First(&reflection).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := openReflection(&reflection); err != nil {
+		return nil, err
+	}
+	return &reflection, nil
+}
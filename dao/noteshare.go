@@ -0,0 +1,93 @@
+package dao
+
+import (
+	"hope_backend/config"
+	"hope_backend/models"
+
+	"gorm.io/gorm"
+)
+
+// CreateNoteShare inserts a new share
+func CreateNoteShare(share *models.NoteShare) error {
+	return config.DB.Create(share).Error
+}
+
+// GetNoteSharesByOwner retrieves the shares a user created, newest first, including revoked and expired ones
+func GetNoteSharesByOwner(ownerID int64) ([]models.NoteShare, error) {
+	var shares []models.NoteShare
+	err := config.DB.Where("owner_id = ?", ownerID).Order("share_id DESC").Find(&shares).Error
+	return shares, err
+}
+
+// GetActiveNoteSharesForRecipient retrieves the shares that currently give a
+// user access to notes of others. An ownerID of 0 returns shares from every owner.
+func GetActiveNoteSharesForRecipient(recipientID, ownerID int64, now int64) ([]models.NoteShare, error) {
+	query := config.DB.Where("recipient_id = ? AND revoked_at = 0 AND (expires_at = 0 OR expires_at > ?)", recipientID, now)
+	if ownerID > 0 {
+		query = query.Where("owner_id = ?", ownerID)
+	}
+
+	var shares []models.NoteShare
+	err := query.Order("share_id DESC").Find(&shares).Error
+	return shares, err
+}
+
+// ClaimPendingNoteShares gives a user the shares that were addressed to their
+// mobile number before any account had it
+func ClaimPendingNoteShares(userID int64, mobileNumber string) error {
+	return config.DB.Model(&models.NoteShare{}).
+		Where("recipient_mobile = ? AND recipient_id IS NULL AND owner_id <> ?", mobileNumber, userID).
+		Update("recipient_id", userID).Error
+}
+
+// GetActiveNoteShareByTokenHash retrieves the link share for a token, if it is still active
+func GetActiveNoteShareByTokenHash(tokenHash string, now int64) (*models.NoteShare, error) {
+	var share models.NoteShare
+	err := config.DB.Where("token_hash = ? AND revoked_at = 0 AND (expires_at = 0 OR expires_at > ?)", tokenHash, now).
+		First(&share).Error
+	if err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// RevokeNoteShare ends a share of the owner; it fails with gorm.ErrRecordNotFound if there is no active share
+func RevokeNoteShare(shareID, ownerID int64, now int64) error {
+	result := config.DB.Model(&models.NoteShare{}).
+		Where("share_id = ? AND owner_id = ? AND revoked_at = 0", shareID, ownerID).
+		Update("revoked_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// CountLiveNotes returns how many of the given notes exist, belong to the user and are not in the trash
+func CountLiveNotes(userID int64, noteIDs []int) (int64, error) {
+	var count int64
+	err := config.DB.Model(&models.Note{}).
+		Where("user_id = ? AND deleted_at = 0 AND note_id IN ?", userID, noteIDs).
+		Count(&count).Error
+	return count, err
+}
+
+// GetSharedNotes retrieves the notes a share gives access to, oldest first
+func GetSharedNotes(share *models.NoteShare) ([]models.Note, error) {
+	query := config.DB.Where("user_id = ? AND deleted_at = 0", share.OwnerID)
+	if share.StartDate != nil && share.EndDate != nil {
+		query = query.Where("note_date BETWEEN ? AND ?", *share.StartDate, *share.EndDate)
+	} else if len(share.NoteIDs) > 0 {
+		query = query.Where("note_id IN ?", share.NoteIDs)
+	} else {
+		return []models.Note{}, nil
+	}
+
+	var notes []models.Note
+	if err := query.Order("note_date ASC").Find(&notes).Error; err != nil {
+		return nil, err
+	}
+	return notes, loadNotes(notes)
+}
@@ -0,0 +1,72 @@
+package dao
+
+import (
+	"hope_backend/config"
+	"hope_backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// nextSyncSeq takes the next value of the user's change counter. The counter
+// row stays locked until the transaction ends, so callers take it before any
+// other lock to keep the lock order the same for every note write.
+func nextSyncSeq(tx *gorm.DB, userID int64) (int64, error) {
+	err := tx.Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]interface{}{"seq": gorm.Expr("seq + 1")}),
+	}).Create(&models.NoteSyncState{UserID: userID, Seq: 1}).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var state models.NoteSyncState
+	if err := tx.Where("user_id = ?", userID).First(&state).Error; err != nil {
+		return 0, err
+	}
+	return state.Seq, nil
+}
+
+// GetNoteForSync retrieves a note of the user by ID, including notes in the trash
+func GetNoteForSync(noteID int, userID int64) (*models.Note, error) {
+	var note models.Note
+	err := config.DB.Where("note_id = ? AND user_id = ?", noteID, userID).First(&note).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := loadNote(&note); err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// GetNoteByClientID retrieves the note a client created with the given ID, including notes in the trash
+func GetNoteByClientID(userID int64, clientID string) (*models.Note, error) {
+	var note models.Note
+	err := config.DB.Where("user_id = ? AND client_id = ?", userID, clientID).First(&note).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := loadNote(&note); err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// GetNoteChanges retrieves up to limit notes, live or trashed, that changed after the cursor, oldest change first
+func GetNoteChanges(userID int64, after int64, limit int) ([]models.Note, error) {
+	var notes []models.Note
+	err := config.DB.Where("user_id = ? AND sync_seq > ?", userID, after).
+		Order("sync_seq ASC").Limit(limit).Find(&notes).Error
+	if err != nil {
+		return nil, err
+	}
+	return notes, loadNotes(notes)
+}
+
+// GetNoteTombstones retrieves up to limit permanently deleted notes recorded after the cursor, oldest first
+func GetNoteTombstones(userID int64, after int64, limit int) ([]models.NoteTombstone, error) {
+	var tombstones []models.NoteTombstone
+	err := config.DB.Where("user_id = ? AND sync_seq > ?", userID, after).
+		Order("sync_seq ASC").Limit(limit).Find(&tombstones).Error
+	return tombstones, err
+}
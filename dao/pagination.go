@@ -0,0 +1,59 @@
+package dao
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// PageCursor marks the last row of a page in a list ordered by created_at and
+// id, newest first. Unlike an offset it stays valid when rows are added, so
+// scrolling clients see no duplicates or gaps.
+type PageCursor struct {
+	CreatedAt int64
+	ID        int64
+}
+
+// String encodes the cursor for clients, which pass it back unchanged
+func (c *PageCursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%d", c.CreatedAt, c.ID)))
+}
+
+// ParsePageCursor decodes a cursor created by PageCursor.String
+func ParsePageCursor(value string) (*PageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	var cursor PageCursor
+	if _, err := fmt.Sscanf(string(data), "%d:%d", &cursor.CreatedAt, &cursor.ID); err != nil || cursor.ID < 1 {
+		return nil, errors.New("invalid cursor")
+	}
+	return &cursor, nil
+}
+
+// keysetPage limits a query to the page after the cursor, newest first. A nil
+// cursor starts at the newest row. One row more than the page is fetched so
+// the caller can tell whether another page follows.
+func keysetPage(query *gorm.DB, cursor *PageCursor, pageSize int) *gorm.DB {
+	if cursor != nil {
+		query = query.Where("(created_at < ? OR (created_at = ? AND id < ?))", cursor.CreatedAt, cursor.CreatedAt, cursor.ID)
+	}
+	return query.Order("created_at DESC, id DESC").Limit(pageSize + 1)
+}
+
+// uniqueIDs returns the IDs without duplicates, for IN (...) queries
+func uniqueIDs(ids []int64) []int64 {
+	seen := make(map[int64]bool, len(ids))
+	unique := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -1,6 +1,8 @@
 package dao
 
 import (
+	"crypto/rand"
+	"encoding/json"
 	"errors"
 	"time"
 
@@ -9,18 +11,22 @@ import (
 
 // Post represents the posts table structure
 type Post struct {
-	ID           int64  `json:"id" gorm:"primaryKey"`
-	UserID       int64  `json:"user_id"`
-	Content      string `json:"content"`
-	ViewCount    int    `json:"view_count" gorm:"default:0"`
-	LikeCount    int    `json:"like_count" gorm:"default:0"`
-	CommentCount int    `json:"comment_count" gorm:"default:0"`
-	CreatedAt    int64  `json:"created_at"`
-	UpdatedAt    int64  `json:"updated_at"`
+	ID            int64  `json:"id" gorm:"primaryKey"`
+	UserID        int64  `json:"user_id"`
+	Content       string `json:"content"`
+	ViewCount     int    `json:"view_count" gorm:"default:0"`
+	LikeCount     int    `json:"like_count" gorm:"default:0"`
+	CommentCount  int    `json:"comment_count" gorm:"default:0"`
+	Anonymous     bool   `json:"anonymous" gorm:"default:false"` // Shown under a pseudonym, user_id is kept for moderation
+	PseudonymSeed string `json:"-"`                              // Secret the pseudonyms of anonymous authors in the post are derived from
+	Hidden        bool   `json:"hidden" gorm:"default:false"`    // Hidden from listings after reports, see ReportDAO
+	CreatedAt     int64  `json:"created_at"`
+	UpdatedAt     int64  `json:"updated_at"`
 	// Virtual fields, not stored in database
-	Images   []PostImage  `json:"images" gorm:"-"`
-	Liked    bool         `json:"liked" gorm:"-"`
-	UserInfo *UserProfile `json:"user_info,omitempty" gorm:"-"`
+	Images   []PostImage   `json:"images" gorm:"-"`
+	Liked    bool          `json:"liked" gorm:"-"`
+	IsAuthor bool          `json:"is_author" gorm:"-"` // Whether the current user wrote the post
+	UserInfo *PublicAuthor `json:"user_info,omitempty" gorm:"-"`
 }
 
 // TableName specifies the table name for GORM
@@ -28,6 +34,19 @@ func (Post) TableName() string {
 	return "posts"
 }
 
+// MarshalJSON leaves out the user ID of anonymous posts
+func (p Post) MarshalJSON() ([]byte, error) {
+	type post Post
+	view := struct {
+		post
+		UserID *int64 `json:"user_id,omitempty"`
+	}{post: post(p)}
+	if !p.Anonymous {
+		view.UserID = &p.UserID
+	}
+	return json.Marshal(view)
+}
+
 // PostImage represents the post_images table structure
 type PostImage struct {
 	ID           int64  `json:"id" gorm:"primaryKey"`
@@ -71,6 +90,11 @@ func (dao *PostDAO) Create(post *Post, imagePaths []string) (int64, error) {
 	now := time.Now().UnixMilli()
 	post.CreatedAt = now
 	post.UpdatedAt = now
+	post.PseudonymSeed = rand.Text()
+
+	if err := checkCommunityBan(dao.db, post.UserID); err != nil {
+		return 0, err
+	}
 
 	// Start a transaction
 	tx := dao.db.Begin()
@@ -124,25 +148,12 @@ func (dao *PostDAO) GetByID(id int64, currentUserID int64) (*Post, error) {
 		return nil, err
 	}
 
-	// Get post images
-	var images []PostImage
-	err = dao.db.Where("post_id = ?", id).Order("display_order").Find(&images).Error
-	if err != nil {
+	// Get images, like state and author
+	posts := []Post{post}
+	if err := dao.fillPosts(posts, currentUserID); err != nil {
 		return nil, err
 	}
-	post.Images = images
-
-	// Check if current user liked this post
-	var count int64
-	dao.db.Model(&PostLike{}).Where("post_id = ? AND user_id = ?", id, currentUserID).Count(&count)
-	post.Liked = count > 0
-
-	// Get user info
-	userDAO := NewUserProfileDAO(dao.db)
-	userProfile, err := userDAO.GetByID(post.UserID)
-	if err == nil {
-		post.UserInfo = userProfile
-	}
+	post = posts[0]
 
 	// Increment view count
 	dao.db.Model(&post).UpdateColumn("view_count", gorm.Expr("view_count + ?", 1))
@@ -155,48 +166,124 @@ func (dao *PostDAO) ListPosts(page, pageSize int, userID int64, currentUserID in
 	var posts []Post
 	var total int64
 
-	query := dao.db.Model(&Post{})
+	query := dao.db.Model(&Post{}).Where("hidden = ?", false)
 
 	// Filter by user ID if provided
-	if userID > 0 {
-		query = query.Where("user_id = ?", userID)
-	}
+	query = filterByAuthor(query, userID, currentUserID)
 
 	// Count total records for pagination
 	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
-	// Apply pagination and order
+	// Apply pagination and order; id breaks ties between posts created in the same millisecond
 	offset := (page - 1) * pageSize
-	err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&posts).Error
+	err := query.Order("created_at DESC, id DESC").Offset(offset).Limit(pageSize).Find(&posts).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
-	// Get images for each post
+	if err := dao.fillPosts(posts, currentUserID); err != nil {
+		return nil, 0, err
+	}
+	return posts, total, nil
+}
+
+// ListPostsAfter retrieves the page of posts after a cursor, newest first; a
+// nil cursor returns the first page. The returned cursor is nil on the last page.
+func (dao *PostDAO) ListPostsAfter(cursor *PageCursor, pageSize int, userID int64, currentUserID int64) ([]Post, *PageCursor, error) {
+	query := dao.db.Model(&Post{}).Where("hidden = ?", false)
+
+	// Filter by user ID if provided
+	query = filterByAuthor(query, userID, currentUserID)
+
+	var posts []Post
+	if err := keysetPage(query, cursor, pageSize).Find(&posts).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var next *PageCursor
+	if len(posts) > pageSize {
+		posts = posts[:pageSize]
+		next = &PageCursor{CreatedAt: posts[pageSize-1].CreatedAt, ID: posts[pageSize-1].ID}
+	}
+
+	if err := dao.fillPosts(posts, currentUserID); err != nil {
+		return nil, nil, err
+	}
+	return posts, next, nil
+}
+
+// filterByAuthor limits a listing to the posts of a user if userID is set.
+// Anonymous posts only show up when users list their own posts.
+func filterByAuthor(query *gorm.DB, userID int64, currentUserID int64) *gorm.DB {
+	if userID <= 0 {
+		return query
+	}
+	query = query.Where("user_id = ?", userID)
+	if userID != currentUserID {
+		query = query.Where("anonymous = ?", false)
+	}
+	return query
+}
+
+// fillPosts loads the images, like state and author of listed posts with
+// one query each, however many posts there are
+func (dao *PostDAO) fillPosts(posts []Post, currentUserID int64) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	postIDs := make([]int64, len(posts))
+	userIDs := make([]int64, 0, len(posts))
 	for i := range posts {
-		var images []PostImage
-		err = dao.db.Where("post_id = ?", posts[i].ID).Order("display_order").Find(&images).Error
-		if err != nil {
-			return nil, 0, err
-		}
-		posts[i].Images = images
-
-		// Check if current user liked this post
-		var count int64
-		dao.db.Model(&PostLike{}).Where("post_id = ? AND user_id = ?", posts[i].ID, currentUserID).Count(&count)
-		posts[i].Liked = count > 0
-
-		// Get user info
-		userDAO := NewUserProfileDAO(dao.db)
-		userProfile, err := userDAO.GetByID(posts[i].UserID)
-		if err == nil {
-			posts[i].UserInfo = userProfile
+		postIDs[i] = posts[i].ID
+		if !posts[i].Anonymous {
+			userIDs = append(userIDs, posts[i].UserID)
 		}
 	}
 
-	return posts, total, nil
+	// Get images of all posts
+	var images []PostImage
+	if err := dao.db.Where("post_id IN ?", postIDs).Order("post_id, display_order").Find(&images).Error; err != nil {
+		return err
+	}
+	imagesByPost := make(map[int64][]PostImage)
+	for _, image := range images {
+		imagesByPost[image.PostID] = append(imagesByPost[image.PostID], image)
+	}
+
+	// Check which posts the current user liked
+	var likedIDs []int64
+	if err := dao.db.Model(&PostLike{}).Where("user_id = ? AND post_id IN ?", currentUserID, postIDs).
+		Pluck("post_id", &likedIDs).Error; err != nil {
+		return err
+	}
+	liked := make(map[int64]bool, len(likedIDs))
+	for _, id := range likedIDs {
+		liked[id] = true
+	}
+
+	// Get user info
+	authors, err := NewUserProfileDAO(dao.db).GetPublicAuthors(userIDs)
+	if err != nil {
+		return err
+	}
+
+	for i := range posts {
+		posts[i].Images = imagesByPost[posts[i].ID]
+		if posts[i].Images == nil {
+			posts[i].Images = []PostImage{}
+		}
+		posts[i].Liked = liked[posts[i].ID]
+		posts[i].IsAuthor = posts[i].UserID == currentUserID
+		if posts[i].Anonymous {
+			posts[i].UserInfo = anonymousAuthor(posts[i].PseudonymSeed, posts[i].UserID)
+		} else {
+			posts[i].UserInfo = authors[posts[i].UserID]
+		}
+	}
+	return nil
 }
 
 // Update updates an existing post
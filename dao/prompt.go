@@ -0,0 +1,94 @@
+package dao
+
+import (
+	"hope_backend/config"
+	"hope_backend/models"
+
+	"gorm.io/gorm"
+)
+
+// GetPrompts retrieves the active prompts of a locale, optionally limited to one category
+func GetPrompts(locale, category string) ([]models.JournalPrompt, error) {
+	query := config.DB.Where("active = ? AND locale = ?", true, locale)
+	if category != "" {
+		query = query.Where("category = ?", category)
+	}
+
+	var prompts []models.JournalPrompt
+	err := query.Order("prompt_id ASC").Find(&prompts).Error
+	return prompts, err
+}
+
+// GetPromptByID retrieves a prompt, including retired ones
+func GetPromptByID(promptID int) (*models.JournalPrompt, error) {
+	var prompt models.JournalPrompt
+	err := config.DB.Where("prompt_id = ?", promptID).First(&prompt).Error
+	if err != nil {
+		return nil, err
+	}
+	return &prompt, nil
+}
+
+// GetPromptLocales lists the locales that have active prompts
+func GetPromptLocales() ([]string, error) {
+	var locales []string
+	err := config.DB.Model(&models.JournalPrompt{}).Where("active = ?", true).
+		Distinct().Order("locale").Pluck("locale", &locales).Error
+	return locales, err
+}
+
+// GetAnsweredPromptIDs retrieves the prompts a user answered in notes dated from start up to, but excluding, end
+func GetAnsweredPromptIDs(userID int64, start, end models.Date) ([]int, error) {
+	var promptIDs []int
+	err := config.DB.Model(&models.Note{}).
+		Where("user_id = ? AND deleted_at = 0 AND prompt_id IS NOT NULL AND note_date >= ? AND note_date < ?", userID, start, end).
+		Pluck("prompt_id", &promptIDs).Error
+	return promptIDs, err
+}
+
+// GetNoteTemplates retrieves the templates of a user, most recently changed first
+func GetNoteTemplates(userID int64) ([]models.NoteTemplate, error) {
+	var templates []models.NoteTemplate
+	err := config.DB.Where("user_id = ?", userID).Order("updated_at DESC").Find(&templates).Error
+	return templates, err
+}
+
+// GetNoteTemplate retrieves one template of a user
+func GetNoteTemplate(templateID int, userID int64) (*models.NoteTemplate, error) {
+	var template models.NoteTemplate
+	err := config.DB.Where("template_id = ? AND user_id = ?", templateID, userID).First(&template).Error
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// CountNoteTemplates returns how many templates a user has saved
+func CountNoteTemplates(userID int64) (int64, error) {
+	var count int64
+	err := config.DB.Model(&models.NoteTemplate{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// CreateNoteTemplate inserts a new template
+func CreateNoteTemplate(template *models.NoteTemplate) error {
+	return config.DB.Create(template).Error
+}
+
+// UpdateNoteTemplate saves the name, content and prompt of a template
+func UpdateNoteTemplate(template *models.NoteTemplate) error {
+	return config.DB.Model(template).Where("template_id = ? AND user_id = ?", template.TemplateID, template.UserID).
+		Select("name", "content", "prompt_id", "updated_at").Updates(template).Error
+}
+
+// DeleteNoteTemplate removes a template of a user
+func DeleteNoteTemplate(templateID int, userID int64) error {
+	result := config.DB.Where("template_id = ? AND user_id = ?", templateID, userID).Delete(&models.NoteTemplate{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
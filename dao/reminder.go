@@ -0,0 +1,138 @@
+package dao
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReminderSetting represents the reminder_settings table structure.
+// It holds when a user wants to be reminded to write the day's note.
+type ReminderSetting struct {
+	UserID     int64  `json:"-" gorm:"primaryKey;autoIncrement:false"`
+	Enabled    bool   `json:"enabled"`
+	TimeOfDay  string `json:"time_of_day"`                     // "HH:MM" in Timezone
+	Timezone   string `json:"timezone"`                        // IANA name, empty to follow the profile timezone
+	Weekdays   []int  `json:"weekdays" gorm:"serializer:json"` // 0 = Sunday ... 6 = Saturday
+	LastSentAt int64  `json:"last_sent_at"`                    // Last reminder enqueued, 0 if never
+	CreatedAt  int64  `json:"created_at"`
+	UpdatedAt  int64  `json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (ReminderSetting) TableName() string {
+	return "reminder_settings"
+}
+
+// PushDevice represents the push_devices table structure.
+// One row is stored for every app install that allowed notifications.
+type PushDevice struct {
+	ID        int64  `json:"id" gorm:"primaryKey"`
+	UserID    int64  `json:"-" gorm:"index"`
+	Platform  string `json:"platform"` // ios or android
+	Token     string `json:"-" gorm:"uniqueIndex"`
+	CreatedAt int64  `json:"created_at"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (PushDevice) TableName() string {
+	return "push_devices"
+}
+
+// ReminderDAO handles database operations for reminder settings and push devices
+type ReminderDAO struct {
+	db *gorm.DB
+}
+
+// NewReminderDAO creates a new ReminderDAO
+func NewReminderDAO(db *gorm.DB) *ReminderDAO {
+	return &ReminderDAO{db: db}
+}
+
+// GetSetting retrieves the reminder setting of a user
+func (dao *ReminderDAO) GetSetting(userID int64) (*ReminderSetting, error) {
+	var setting ReminderSetting
+	err := dao.db.Where("user_id = ?", userID).First(&setting).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("reminder setting not found")
+		}
+		return nil, err
+	}
+	return &setting, nil
+}
+
+// SaveSetting creates or replaces the reminder setting of a user
+func (dao *ReminderDAO) SaveSetting(setting *ReminderSetting) error {
+	now := time.Now().UnixMilli()
+	if setting.CreatedAt == 0 {
+		setting.CreatedAt = now
+	}
+	setting.UpdatedAt = now
+
+	return dao.db.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "time_of_day", "timezone", "weekdays", "updated_at"}),
+	}).Create(setting).Error
+}
+
+// ListEnabled retrieves all enabled reminder settings
+func (dao *ReminderDAO) ListEnabled() ([]ReminderSetting, error) {
+	var settings []ReminderSetting
+	err := dao.db.Where("enabled = ?", true).Find(&settings).Error
+	return settings, err
+}
+
+// MarkSent records that a reminder was enqueued for a user
+func (dao *ReminderDAO) MarkSent(userID int64, sentAt int64) error {
+	return dao.db.Model(&ReminderSetting{}).Where("user_id = ?", userID).
+		Update("last_sent_at", sentAt).Error
+}
+
+// RegisterDevice stores a push token for a user. A token that was registered
+// by another account on the same device moves to this user.
+func (dao *ReminderDAO) RegisterDevice(userID int64, platform, token string) (*PushDevice, error) {
+	now := time.Now().UnixMilli()
+	device := &PushDevice{
+		UserID:    userID,
+		Platform:  platform,
+		Token:     token,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	err := dao.db.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"user_id", "platform", "updated_at"}),
+	}).Create(device).Error
+	if err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+// ListDevices retrieves the push devices of a user
+func (dao *ReminderDAO) ListDevices(userID int64) ([]PushDevice, error) {
+	var devices []PushDevice
+	err := dao.db.Where("user_id = ?", userID).Order("id ASC").Find(&devices).Error
+	return devices, err
+}
+
+// UnregisterDevice removes a push token of a user
+func (dao *ReminderDAO) UnregisterDevice(userID int64, token string) error {
+	result := dao.db.Where("user_id = ? AND token = ?", userID, token).Delete(&PushDevice{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("push device not found")
+	}
+	return nil
+}
+
+// DeleteDeviceByToken removes a push token regardless of its owner, used when
+// the push service reports it as invalid
+func (dao *ReminderDAO) DeleteDeviceByToken(token string) error {
+	return dao.db.Where("token = ?", token).Delete(&PushDevice{}).Error
+}
@@ -0,0 +1,327 @@
+package dao
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Kinds of content that can be reported
+const (
+	ReportTargetPost    = "post"
+	ReportTargetComment = "comment"
+)
+
+// Report reason codes
+const (
+	ReportReasonSpam           = "spam"
+	ReportReasonHarassment     = "harassment"
+	ReportReasonHate           = "hate"
+	ReportReasonSelfHarm       = "self_harm"
+	ReportReasonSexual         = "sexual"
+	ReportReasonMisinformation = "misinformation"
+	ReportReasonPrivacy        = "privacy" // Shares someone's personal details
+	ReportReasonOther          = "other"
+)
+
+// Report states. Open reports wait in the queue until a moderator claims them.
+const (
+	ReportStatusOpen     = "open"
+	ReportStatusClaimed  = "claimed"
+	ReportStatusResolved = "resolved"
+)
+
+// Moderation actions. Dismiss restores hidden content; hide, warn and ban
+// leave it hidden; delete removes it.
+const (
+	ModerationDismiss  = "dismiss"
+	ModerationHide     = "hide"
+	ModerationDelete   = "delete"
+	ModerationWarn     = "warn"
+	ModerationBan      = "ban"
+	ModerationAutoHide = "auto_hide" // Taken by the system when a report threshold is reached
+)
+
+// AutoHideReportThreshold is how many users must report a post or comment
+// before it is hidden until a moderator decides
+const AutoHideReportThreshold = 3
+
+// Report represents the reports table structure
+type Report struct {
+	ID              int64  `json:"id" gorm:"primaryKey"`
+	TargetType      string `json:"target_type"`
+	TargetID        int64  `json:"target_id"`
+	TargetUserID    int64  `json:"target_user_id"` // Author of the content, also for anonymous content
+	ReporterID      int64  `json:"reporter_id"`
+	Reason          string `json:"reason"`
+	Detail          string `json:"detail"`
+	ContentSnapshot string `json:"content_snapshot"` // Content when it was reported, in case it is edited or deleted
+	Status          string `json:"status"`
+	ClaimedBy       int64  `json:"claimed_by"`
+	ClaimedAt       int64  `json:"claimed_at"`
+	Resolution      string `json:"resolution"` // Moderation action the report was resolved with
+	ResolvedBy      int64  `json:"resolved_by"`
+	ResolvedAt      int64  `json:"resolved_at"`
+	CreatedAt       int64  `json:"created_at"`
+	UpdatedAt       int64  `json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (Report) TableName() string {
+	return "reports"
+}
+
+// ModerationDecision records one moderation action. Rows are only ever inserted.
+type ModerationDecision struct {
+	ID           int64  `json:"id" gorm:"primaryKey"`
+	ReportID     int64  `json:"report_id"` // Report the decision resolved, or that triggered it
+	TargetType   string `json:"target_type"`
+	TargetID     int64  `json:"target_id"`
+	TargetUserID int64  `json:"target_user_id"`
+	ModeratorID  int64  `json:"moderator_id"` // 0 for decisions taken by the system
+	Action       string `json:"action"`
+	Note         string `json:"note"`
+	BanUntil     int64  `json:"ban_until,omitempty"` // For bans, milliseconds since epoch
+	Reports      int    `json:"reports"`             // Number of reports the decision resolved
+	CreatedAt    int64  `json:"created_at"`
+}
+
+// TableName specifies the table name for GORM
+func (ModerationDecision) TableName() string {
+	return "moderation_decisions"
+}
+
+// ReportDAO handles database operations for reports and moderation decisions
+type ReportDAO struct {
+	db *gorm.DB
+}
+
+// NewReportDAO creates a new ReportDAO
+func NewReportDAO(db *gorm.DB) *ReportDAO {
+	return &ReportDAO{db: db}
+}
+
+// IsValidReportReason checks if the reason is one of the reason codes
+func IsValidReportReason(reason string) bool {
+	switch reason {
+	case ReportReasonSpam, ReportReasonHarassment, ReportReasonHate, ReportReasonSelfHarm,
+		ReportReasonSexual, ReportReasonMisinformation, ReportReasonPrivacy, ReportReasonOther:
+		return true
+	}
+	return false
+}
+
+// contentTable returns the table of a report target
+func contentTable(targetType string) string {
+	if targetType == ReportTargetComment {
+		return "comments"
+	}
+	return "posts"
+}
+
+// Create files a report. The author, content snapshot and status are taken
+// from the reported content. Once AutoHideReportThreshold users have open
+// reports on it, the content is hidden and a decision is recorded.
+func (dao *ReportDAO) Create(report *Report) error {
+	now := time.Now().UnixMilli()
+
+	return dao.db.Transaction(func(tx *gorm.DB) error {
+		var content struct {
+			UserID  int64
+			Content string
+			Hidden  bool
+		}
+		result := tx.Table(contentTable(report.TargetType)).Select("user_id", "content", "hidden").
+			Where("id = ?", report.TargetID).Take(&content)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return errors.New(report.TargetType + " not found")
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		if content.UserID == report.ReporterID {
+			return errors.New("cannot report your own content")
+		}
+
+		// One report per user and content
+		var count int64
+		if err := tx.Model(&Report{}).
+			Where("target_type = ? AND target_id = ? AND reporter_id = ?", report.TargetType, report.TargetID, report.ReporterID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errors.New("already reported")
+		}
+
+		report.TargetUserID = content.UserID
+		report.ContentSnapshot = content.Content
+		report.Status = ReportStatusOpen
+		report.CreatedAt = now
+		report.UpdatedAt = now
+		if err := tx.Create(report).Error; err != nil {
+			return err
+		}
+
+		if content.Hidden {
+			return nil
+		}
+		var pending int64
+		if err := tx.Model(&Report{}).
+			Where("target_type = ? AND target_id = ? AND status IN ?", report.TargetType, report.TargetID,
+				[]string{ReportStatusOpen, ReportStatusClaimed}).
+			Count(&pending).Error; err != nil {
+			return err
+		}
+		if pending < AutoHideReportThreshold {
+			return nil
+		}
+
+		if err := setContentHidden(tx, report.TargetType, report.TargetID, true); err != nil {
+			return err
+		}
+		return tx.Create(&ModerationDecision{
+			ReportID:     report.ID,
+			TargetType:   report.TargetType,
+			TargetID:     report.TargetID,
+			TargetUserID: report.TargetUserID,
+			Action:       ModerationAutoHide,
+			Reports:      int(pending),
+			CreatedAt:    now,
+		}).Error
+	})
+}
+
+// GetByID retrieves a report by its ID
+func (dao *ReportDAO) GetByID(id int64) (*Report, error) {
+	var report Report
+	if err := dao.db.First(&report, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("report not found")
+		}
+		return nil, err
+	}
+	return &report, nil
+}
+
+// List retrieves reports with the given statuses, oldest first so the queue
+// is worked in order
+func (dao *ReportDAO) List(statuses []string, page, pageSize int) ([]Report, int64, error) {
+	var reports []Report
+	var total int64
+
+	query := dao.db.Model(&Report{}).Where("status IN ?", statuses)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Order("created_at ASC, id ASC").Offset(offset).Limit(pageSize).Find(&reports).Error
+	return reports, total, err
+}
+
+// Claim assigns a report to a moderator. Reports claimed by someone else can
+// be taken over once their claim is older than staleBefore.
+func (dao *ReportDAO) Claim(id, moderatorID, staleBefore int64) (*Report, error) {
+	now := time.Now().UnixMilli()
+	result := dao.db.Model(&Report{}).
+		Where("id = ? AND (status = ? OR (status = ? AND (claimed_by = ? OR claimed_at < ?)))",
+			id, ReportStatusOpen, ReportStatusClaimed, moderatorID, staleBefore).
+		Updates(map[string]interface{}{
+			"status":     ReportStatusClaimed,
+			"claimed_by": moderatorID,
+			"claimed_at": now,
+			"updated_at": now,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	report, err := dao.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		if report.Status == ReportStatusResolved {
+			return nil, errors.New("report already resolved")
+		}
+		return nil, errors.New("report claimed by another moderator")
+	}
+	return report, nil
+}
+
+// Resolve closes every pending report on the content of a report with the
+// moderator's decision and records it. The action itself is carried out by
+// the caller beforehand.
+func (dao *ReportDAO) Resolve(report *Report, decision *ModerationDecision) error {
+	now := time.Now().UnixMilli()
+
+	return dao.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Report{}).
+			Where("target_type = ? AND target_id = ? AND status IN ?", report.TargetType, report.TargetID,
+				[]string{ReportStatusOpen, ReportStatusClaimed}).
+			Updates(map[string]interface{}{
+				"status":      ReportStatusResolved,
+				"resolution":  decision.Action,
+				"resolved_by": decision.ModeratorID,
+				"resolved_at": now,
+				"updated_at":  now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		decision.ReportID = report.ID
+		decision.TargetType = report.TargetType
+		decision.TargetID = report.TargetID
+		decision.TargetUserID = report.TargetUserID
+		decision.Reports = int(result.RowsAffected)
+		decision.CreatedAt = now
+		return tx.Create(decision).Error
+	})
+}
+
+// SetHidden hides reported content from community listings, or shows it again
+func (dao *ReportDAO) SetHidden(targetType string, targetID int64, hidden bool) error {
+	return setContentHidden(dao.db, targetType, targetID, hidden)
+}
+
+// setContentHidden sets the hidden flag of a post or comment
+func setContentHidden(db *gorm.DB, targetType string, targetID int64, hidden bool) error {
+	return db.Table(contentTable(targetType)).Where("id = ?", targetID).
+		UpdateColumn("hidden", hidden).Error
+}
+
+// ListDecisions retrieves moderation decisions, newest first, optionally only
+// those about one user's content
+func (dao *ReportDAO) ListDecisions(targetUserID int64, page, pageSize int) ([]ModerationDecision, int64, error) {
+	var decisions []ModerationDecision
+	var total int64
+
+	query := dao.db.Model(&ModerationDecision{})
+	if targetUserID > 0 {
+		query = query.Where("target_user_id = ?", targetUserID)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&decisions).Error
+	return decisions, total, err
+}
+
+// checkCommunityBan returns an error if the user is banned from posting in the community
+func checkCommunityBan(db *gorm.DB, userID int64) error {
+	var count int64
+	if err := db.Model(&UserProfile{}).
+		Where("id = ? AND community_banned_until > ?", userID, time.Now().UnixMilli()).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("banned from the community")
+	}
+	return nil
+}
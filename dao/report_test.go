@@ -0,0 +1,120 @@
+package dao
+
+import (
+	"testing"
+
+	"hope_backend/dao/daotest"
+)
+
+// newReportFixture creates a stub database with a post by user 1 and a
+// comment on it by user 2
+func newReportFixture() *daotest.DB {
+	return daotest.New(map[string][]daotest.Row{
+		"posts":    {{"id": int64(1), "user_id": int64(1), "content": "post", "hidden": false}},
+		"comments": {{"id": int64(1), "post_id": int64(1), "user_id": int64(2), "content": "comment", "hidden": false}},
+	})
+}
+
+// hidden returns the hidden flag of a post or comment
+func hidden(f *daotest.DB, table string, id int64) bool {
+	return f.Get(table, id)["hidden"] == true
+}
+
+func TestReportAutoHideThreshold(t *testing.T) {
+	f := newReportFixture()
+	reportDAO := NewReportDAO(f.Open(t))
+
+	report := func(reporterID int64) error {
+		return reportDAO.Create(&Report{TargetType: ReportTargetPost, TargetID: 1, ReporterID: reporterID, Reason: ReportReasonSpam})
+	}
+
+	if err := report(1); err == nil || err.Error() != "cannot report your own content" {
+		t.Errorf("own post: err = %v", err)
+	}
+	if err := reportDAO.Create(&Report{TargetType: ReportTargetPost, TargetID: 2, ReporterID: 3, Reason: ReportReasonSpam}); err == nil || err.Error() != "post not found" {
+		t.Errorf("missing post: err = %v", err)
+	}
+
+	for reporter := int64(2); reporter <= AutoHideReportThreshold; reporter++ {
+		if err := report(reporter); err != nil {
+			t.Fatalf("report by %d: %v", reporter, err)
+		}
+	}
+	if err := report(2); err == nil || err.Error() != "already reported" {
+		t.Errorf("second report: err = %v", err)
+	}
+	if hidden(f, "posts", 1) || len(f.Rows("moderation_decisions")) > 0 {
+		t.Fatalf("post hidden after %d reports", AutoHideReportThreshold-1)
+	}
+
+	if err := report(AutoHideReportThreshold + 1); err != nil {
+		t.Fatal(err)
+	}
+	if !hidden(f, "posts", 1) {
+		t.Fatalf("post not hidden after %d reports", AutoHideReportThreshold)
+	}
+	decisions := f.Rows("moderation_decisions")
+	if len(decisions) != 1 || decisions[0]["action"] != ModerationAutoHide ||
+		decisions[0]["reports"] != int64(AutoHideReportThreshold) || decisions[0]["target_user_id"] != int64(1) {
+		t.Fatalf("decisions %v, want one auto-hide of %d reports", decisions, AutoHideReportThreshold)
+	}
+
+	// Content that is already hidden is not hidden again
+	if err := report(AutoHideReportThreshold + 2); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(f.Rows("moderation_decisions")); n != 1 {
+		t.Errorf("%d decisions after a further report, want 1", n)
+	}
+	if hidden(f, "comments", 1) {
+		t.Error("comment hidden by reports on its post")
+	}
+}
+
+func TestResolveReport(t *testing.T) {
+	const moderatorID = 9
+
+	f := newReportFixture()
+	reportDAO := NewReportDAO(f.Open(t))
+
+	for reporter := int64(3); reporter < 3+AutoHideReportThreshold; reporter++ {
+		if err := reportDAO.Create(&Report{TargetType: ReportTargetComment, TargetID: 1, ReporterID: reporter, Reason: ReportReasonHarassment}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !hidden(f, "comments", 1) {
+		t.Fatal("comment not hidden")
+	}
+
+	first, err := reportDAO.GetByID(f.Rows("reports")[0]["id"].(int64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.TargetUserID != 2 || first.ContentSnapshot != "comment" || first.Status != ReportStatusOpen {
+		t.Fatalf("report %+v", first)
+	}
+
+	// Dismissing restores the comment and closes every report on it
+	if err := reportDAO.SetHidden(first.TargetType, first.TargetID, false); err != nil {
+		t.Fatal(err)
+	}
+	decision := &ModerationDecision{ModeratorID: moderatorID, Action: ModerationDismiss, Note: "not harassment"}
+	if err := reportDAO.Resolve(first, decision); err != nil {
+		t.Fatal(err)
+	}
+
+	if hidden(f, "comments", 1) {
+		t.Error("comment still hidden after dismissal")
+	}
+	for _, row := range f.Rows("reports") {
+		if row["status"] != ReportStatusResolved || row["resolution"] != ModerationDismiss || row["resolved_by"] != int64(moderatorID) {
+			t.Errorf("report %v not resolved by the dismissal", row)
+		}
+	}
+	if decision.ID == 0 || decision.ReportID != first.ID || decision.TargetUserID != 2 || decision.Reports != AutoHideReportThreshold {
+		t.Errorf("decision %+v", decision)
+	}
+	if n := len(f.Rows("moderation_decisions")); n != 2 {
+		t.Errorf("%d decisions, want the auto-hide and the dismissal", n)
+	}
+}
@@ -0,0 +1,119 @@
+package dao
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserSession represents the user_sessions table structure.
+// One row is created for every token issued at login or registration.
+type UserSession struct {
+	ID         int64  `json:"id" gorm:"primaryKey"`
+	UserID     int64  `json:"user_id" gorm:"index"`
+	TokenID    string `json:"-" gorm:"uniqueIndex"` // JWT "jti" claim
+	DeviceName string `json:"device_name"`
+	Platform   string `json:"platform"`
+	IP         string `json:"ip"`
+	UserAgent  string `json:"user_agent"`
+	CreatedAt  int64  `json:"created_at"`
+	LastSeenAt int64  `json:"last_seen_at"`
+	ExpiresAt  int64  `json:"expires_at"` // Expiry of the session's token
+	RevokedAt  int64  `json:"-"`          // 0 while the session is active
+	// Virtual fields, not stored in database
+	Current bool `json:"current" gorm:"-"`
+}
+
+// TableName specifies the table name for GORM
+func (UserSession) TableName() string {
+	return "user_sessions"
+}
+
+// sessionTouchInterval limits how often last_seen_at is written for a session
+const sessionTouchInterval = time.Minute
+
+// SessionDAO handles database operations for user sessions
+type SessionDAO struct {
+	db *gorm.DB
+}
+
+// NewSessionDAO creates a new SessionDAO
+func NewSessionDAO(db *gorm.DB) *SessionDAO {
+	return &SessionDAO{db: db}
+}
+
+// Create inserts a new session
+func (dao *SessionDAO) Create(session *UserSession) error {
+	now := time.Now().UnixMilli()
+	session.CreatedAt = now
+	session.LastSeenAt = now
+
+	return dao.db.Create(session).Error
+}
+
+// GetByTokenID retrieves an active session by the token ID it was issued for
+func (dao *SessionDAO) GetByTokenID(tokenID string) (*UserSession, error) {
+	var session UserSession
+	err := dao.db.Where("token_id = ? AND revoked_at = 0", tokenID).First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("session not found")
+		}
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// Touch refreshes the last-seen time and IP of a session.
+// Writes are skipped if the session was seen within sessionTouchInterval.
+func (dao *SessionDAO) Touch(session *UserSession, ip string) error {
+	now := time.Now().UnixMilli()
+	if now-session.LastSeenAt < sessionTouchInterval.Milliseconds() && session.IP == ip {
+		return nil
+	}
+
+	session.LastSeenAt = now
+	session.IP = ip
+
+	return dao.db.Model(session).Updates(map[string]interface{}{
+		"last_seen_at": now,
+		"ip":           ip,
+	}).Error
+}
+
+// ListActive retrieves all sessions of a user that are neither revoked nor expired,
+// most recently used first
+func (dao *SessionDAO) ListActive(userID int64) ([]UserSession, error) {
+	var sessions []UserSession
+	err := dao.db.Where("user_id = ? AND revoked_at = 0 AND expires_at > ?", userID, time.Now().UnixMilli()).
+		Order("last_seen_at DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+// Revoke signs out a single session belonging to a user
+func (dao *SessionDAO) Revoke(userID, sessionID int64) error {
+	result := dao.db.Model(&UserSession{}).
+		Where("id = ? AND user_id = ? AND revoked_at = 0", sessionID, userID).
+		Update("revoked_at", time.Now().UnixMilli())
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("session not found")
+	}
+
+	return nil
+}
+
+// RevokeAllExcept signs out every active session of a user except keepID.
+// Passing keepID = 0 revokes all sessions.
+func (dao *SessionDAO) RevokeAllExcept(userID, keepID int64) (int64, error) {
+	result := dao.db.Model(&UserSession{}).
+		Where("user_id = ? AND id <> ? AND revoked_at = 0", userID, keepID).
+		Update("revoked_at", time.Now().UnixMilli())
+
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,28 @@
+package dao
+
+import (
+	"testing"
+	"time"
+
+	"hope_backend/dao/daotest"
+)
+
+func TestListActiveSessions(t *testing.T) {
+	now := time.Now().UnixMilli()
+	f := daotest.New(map[string][]daotest.Row{
+		"user_sessions": {
+			{"id": int64(1), "user_id": int64(1), "last_seen_at": now, "expires_at": now + 1000, "revoked_at": int64(0)},
+			{"id": int64(2), "user_id": int64(1), "last_seen_at": now, "expires_at": now - 1000, "revoked_at": int64(0)},
+			{"id": int64(3), "user_id": int64(1), "last_seen_at": now, "expires_at": now + 1000, "revoked_at": now},
+			{"id": int64(4), "user_id": int64(2), "last_seen_at": now, "expires_at": now + 1000, "revoked_at": int64(0)},
+		},
+	})
+
+	sessions, err := NewSessionDAO(f.Open(t)).ListActive(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != 1 {
+		t.Errorf("sessions %+v, want only session 1", sessions)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"time"
 
+	"hope_backend/fieldcrypt"
+
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
@@ -14,14 +16,18 @@ type UserProfile struct {
 	ID                    int64  `json:"id" gorm:"primaryKey"`
 	PatientName           string `json:"patient_name"`
 	RelationshipToPatient string `json:"relationship_to_patient"`
-	IllnessCause          string `json:"illness_cause"`
+	IllnessCause          string `json:"illness_cause"` // Encrypted at rest
 	ChatBackground        string `json:"chat_background"`
 	UserAvatar            string `json:"user_avatar"`
 	UserNickname          string `json:"user_nickname"`
 	MobileNumber          string `json:"mobile_number" gorm:"uniqueIndex"`
 	Password              string `json:"-"` // Excluded from JSON serialization
+	Role                  string `json:"role" gorm:"default:user"`
+	Timezone              string `json:"timezone" gorm:"default:Asia/Shanghai"` // IANA name, used to decide which day "today" is
+	CommunityBannedUntil  int64  `json:"community_banned_until"`                // Posting and commenting are blocked until then, milliseconds since epoch
 	CreatedAt             int64  `json:"created_at"`
 	UpdatedAt             int64  `json:"updated_at"`
+	KeyVersion            int    `json:"-"` // Master key illness_cause was encrypted with, 0 for plaintext
 }
 
 // TableName specifies the table name for GORM
@@ -64,6 +70,9 @@ func (dao *UserProfileDAO) GetByID(id int64) (*UserProfile, error) {
 		return nil, result.Error
 	}
 
+	if err := openProfile(&profile); err != nil {
+		return nil, err
+	}
 	return &profile, nil
 }
 
@@ -79,6 +88,9 @@ func (dao *UserProfileDAO) GetByMobileNumber(mobileNumber string) (*UserProfile,
 		return nil, result.Error
 	}
 
+	if err := openProfile(&profile); err != nil {
+		return nil, err
+	}
 	return &profile, nil
 }
 
@@ -97,6 +109,14 @@ func (dao *UserProfileDAO) Create(profile *UserProfile, plainPassword string) (i
 	profile.UpdatedAt = now
 	profile.Password = string(hashedPassword)
 
+	// Encrypt the illness cause for storage, the caller keeps the plaintext
+	illnessCause := profile.IllnessCause
+	profile.IllnessCause, profile.KeyVersion, err = fieldcrypt.Encrypt(illnessCause, illnessCauseContext)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { profile.IllnessCause = illnessCause }()
+
 	// Create the record
 	result := dao.db.Create(profile)
 	if result.Error != nil {
@@ -110,13 +130,20 @@ func (dao *UserProfileDAO) Create(profile *UserProfile, plainPassword string) (i
 func (dao *UserProfileDAO) Update(profile *UserProfile) error {
 	profile.UpdatedAt = time.Now().UnixMilli()
 
+	illnessCause, keyVersion, err := fieldcrypt.Encrypt(profile.IllnessCause, illnessCauseContext)
+	if err != nil {
+		return err
+	}
+
 	result := dao.db.Model(profile).Updates(map[string]interface{}{
 		"patient_name":            profile.PatientName,
 		"relationship_to_patient": profile.RelationshipToPatient,
-		"illness_cause":           profile.IllnessCause,
+		"illness_cause":           illnessCause,
+		"key_version":             keyVersion,
 		"chat_background":         profile.ChatBackground,
 		"user_avatar":             profile.UserAvatar,
 		"user_nickname":           profile.UserNickname,
+		"timezone":                profile.Timezone,
 		"updated_at":              profile.UpdatedAt,
 	})
 
@@ -153,6 +180,41 @@ func (dao *UserProfileDAO) UpdatePassword(userID int64, currentPassword, newPass
 	return result.Error
 }
 
+// UpdateRole changes a user's role
+func (dao *UserProfileDAO) UpdateRole(userID int64, role string) error {
+	result := dao.db.Model(&UserProfile{ID: userID}).Updates(map[string]interface{}{
+		"role":       role,
+		"updated_at": time.Now().UnixMilli(),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("user profile not found")
+	}
+
+	return nil
+}
+
+// BanFromCommunity blocks a user from posting and commenting until the given
+// time. An existing longer ban is kept.
+func (dao *UserProfileDAO) BanFromCommunity(userID int64, until int64) error {
+	result := dao.db.Model(&UserProfile{ID: userID}).Updates(map[string]interface{}{
+		"community_banned_until": gorm.Expr("GREATEST(community_banned_until, ?)", until),
+		"updated_at":             time.Now().UnixMilli(),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("user profile not found")
+	}
+
+	return nil
+}
+
 // VerifyPassword checks if the provided password matches the stored hash
 func (dao *UserProfileDAO) VerifyPassword(mobileNumber, password string) (bool, int64, error) {
 	profile, err := dao.GetByMobileNumber(mobileNumber)
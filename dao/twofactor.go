@@ -0,0 +1,245 @@
+package dao
+
+import (
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Lockout of two-factor code checks. From TwoFactorMaxFailures wrong codes on,
+// every further wrong code locks the checks, for twice as long each time.
+const (
+	TwoFactorMaxFailures = 5
+	TwoFactorLockout     = 15 * time.Minute
+	maxTwoFactorLockout  = 24 * time.Hour
+)
+
+// TwoFactor represents the user_two_factor table structure
+type TwoFactor struct {
+	UserID    int64  `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	Secret    string `json:"-"` // Base32 TOTP secret
+	Enabled   bool   `json:"enabled"`
+	EnabledAt int64  `json:"enabled_at"`
+	// Wrong codes since the last accepted one, counted across login attempts
+	FailedAttempts int   `json:"-"`
+	LockedUntil    int64 `json:"-"` // Codes are refused until then, milliseconds since epoch
+	LastUsedStep   int64 `json:"-"` // TOTP time step of the last accepted code
+	CreatedAt      int64 `json:"created_at"`
+	UpdatedAt      int64 `json:"updated_at"`
+}
+
+// Locked reports whether code checks are locked after too many wrong codes
+func (tf *TwoFactor) Locked() bool {
+	return tf.LockedUntil > time.Now().UnixMilli()
+}
+
+// TableName specifies the table name for GORM
+func (TwoFactor) TableName() string {
+	return "user_two_factor"
+}
+
+// RecoveryCode represents the user_recovery_codes table structure
+type RecoveryCode struct {
+	ID        int64  `gorm:"primaryKey"`
+	UserID    int64  `gorm:"index"`
+	CodeHash  string // bcrypt hash of the code
+	UsedAt    int64  // 0 while unused
+	CreatedAt int64
+}
+
+// TableName specifies the table name for GORM
+func (RecoveryCode) TableName() string {
+	return "user_recovery_codes"
+}
+
+// TwoFactorDAO handles database operations for two-factor authentication
+type TwoFactorDAO struct {
+	db *gorm.DB
+}
+
+// NewTwoFactorDAO creates a new TwoFactorDAO
+func NewTwoFactorDAO(db *gorm.DB) *TwoFactorDAO {
+	return &TwoFactorDAO{db: db}
+}
+
+// Get retrieves the two-factor settings of a user
+func (dao *TwoFactorDAO) Get(userID int64) (*TwoFactor, error) {
+	var tf TwoFactor
+	err := dao.db.First(&tf, userID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("two-factor not set up")
+		}
+		return nil, err
+	}
+
+	return &tf, nil
+}
+
+// IsEnabled reports whether the user has completed two-factor enrollment
+func (dao *TwoFactorDAO) IsEnabled(userID int64) (bool, error) {
+	var count int64
+	err := dao.db.Model(&TwoFactor{}).Where("user_id = ? AND enabled = ?", userID, true).Count(&count).Error
+	return count > 0, err
+}
+
+// SaveSecret stores a new, not yet enabled secret for the user
+func (dao *TwoFactorDAO) SaveSecret(userID int64, secret string) error {
+	if enabled, err := dao.IsEnabled(userID); err != nil {
+		return err
+	} else if enabled {
+		return errors.New("two-factor already enabled")
+	}
+
+	now := time.Now().UnixMilli()
+	return dao.db.Save(&TwoFactor{
+		UserID:    userID,
+		Secret:    secret,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}).Error
+}
+
+// Enable turns on two-factor authentication and replaces the recovery codes
+func (dao *TwoFactorDAO) Enable(userID int64, recoveryCodes []string) error {
+	return dao.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UnixMilli()
+		if err := tx.Model(&TwoFactor{UserID: userID}).Updates(map[string]interface{}{
+			"enabled":    true,
+			"enabled_at": now,
+			"updated_at": now,
+		}).Error; err != nil {
+			return err
+		}
+
+		return replaceRecoveryCodes(tx, userID, recoveryCodes)
+	})
+}
+
+// Disable turns off two-factor authentication and removes the secret and recovery codes
+func (dao *TwoFactorDAO) Disable(userID int64) error {
+	return dao.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&TwoFactor{UserID: userID}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error
+	})
+}
+
+// UseStep accepts a TOTP code of the given time step. Each step is accepted
+// once, and never one before the last accepted step, so a code cannot be
+// replayed. It returns false if the step was already used. Accepting a code
+// resets the failure count.
+func (dao *TwoFactorDAO) UseStep(userID, step int64) (bool, error) {
+	result := dao.db.Model(&TwoFactor{}).Where("user_id = ? AND last_used_step < ?", userID, step).
+		Updates(map[string]interface{}{
+			"last_used_step":  step,
+			"failed_attempts": 0,
+			"locked_until":    0,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// ResetFailures clears the failure count after a recovery code was accepted
+func (dao *TwoFactorDAO) ResetFailures(userID int64) error {
+	return dao.db.Model(&TwoFactor{}).Where("user_id = ?", userID).
+		Updates(map[string]interface{}{
+			"failed_attempts": 0,
+			"locked_until":    0,
+		}).Error
+}
+
+// RecordFailure counts a wrong code and locks code checks once the user has
+// reached TwoFactorMaxFailures
+func (dao *TwoFactorDAO) RecordFailure(userID int64) error {
+	return dao.db.Transaction(func(tx *gorm.DB) error {
+		var tf TwoFactor
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&tf, userID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{"failed_attempts": tf.FailedAttempts + 1}
+		if lockout := twoFactorLockout(tf.FailedAttempts + 1); lockout > 0 {
+			updates["locked_until"] = time.Now().Add(lockout).UnixMilli()
+		}
+		return tx.Model(&TwoFactor{}).Where("user_id = ?", userID).Updates(updates).Error
+	})
+}
+
+// twoFactorLockout returns how long code checks are locked after the given
+// number of wrong codes
+func twoFactorLockout(failures int) time.Duration {
+	if failures < TwoFactorMaxFailures {
+		return 0
+	}
+	lockout := TwoFactorLockout
+	for i := TwoFactorMaxFailures; i < failures && lockout < maxTwoFactorLockout; i++ {
+		lockout *= 2
+	}
+	return min(lockout, maxTwoFactorLockout)
+}
+
+// ReplaceRecoveryCodes invalidates all recovery codes of a user and stores new ones
+func (dao *TwoFactorDAO) ReplaceRecoveryCodes(userID int64, recoveryCodes []string) error {
+	return dao.db.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, recoveryCodes)
+	})
+}
+
+// replaceRecoveryCodes hashes and stores recovery codes inside a transaction
+func replaceRecoveryCodes(tx *gorm.DB, userID int64, recoveryCodes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error; err != nil {
+		return err
+	}
+
+	now := time.Now().UnixMilli()
+	rows := make([]RecoveryCode, 0, len(recoveryCodes))
+	for _, code := range recoveryCodes {
+		hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, RecoveryCode{UserID: userID, CodeHash: string(hash), CreatedAt: now})
+	}
+
+	return tx.Create(&rows).Error
+}
+
+// UseRecoveryCode consumes a matching unused recovery code.
+// It returns false if no unused code matches.
+func (dao *TwoFactorDAO) UseRecoveryCode(userID int64, code string) (bool, error) {
+	var codes []RecoveryCode
+	if err := dao.db.Where("user_id = ? AND used_at = 0", userID).Find(&codes).Error; err != nil {
+		return false, err
+	}
+
+	for _, rc := range codes {
+		if bcrypt.CompareHashAndPassword([]byte(rc.CodeHash), []byte(code)) != nil {
+			continue
+		}
+
+		// Only one request may consume the code
+		result := dao.db.Model(&RecoveryCode{}).Where("id = ? AND used_at = 0", rc.ID).
+			Update("used_at", time.Now().UnixMilli())
+		if result.Error != nil {
+			return false, result.Error
+		}
+		return result.RowsAffected > 0, nil
+	}
+
+	return false, nil
+}
+
+// CountUnusedRecoveryCodes returns how many recovery codes the user has left
+func (dao *TwoFactorDAO) CountUnusedRecoveryCodes(userID int64) (int64, error) {
+	var count int64
+	err := dao.db.Model(&RecoveryCode{}).Where("user_id = ? AND used_at = 0", userID).Count(&count).Error
+	return count, err
+}
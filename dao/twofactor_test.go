@@ -0,0 +1,27 @@
+package dao
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTwoFactorLockout(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{TwoFactorMaxFailures - 1, 0},
+		{TwoFactorMaxFailures, TwoFactorLockout},
+		{TwoFactorMaxFailures + 1, 2 * TwoFactorLockout},
+		{TwoFactorMaxFailures + 2, 4 * TwoFactorLockout},
+		{TwoFactorMaxFailures + 20, maxTwoFactorLockout},
+		{1 << 20, maxTwoFactorLockout},
+	}
+
+	for _, tt := range tests {
+		if got := twoFactorLockout(tt.failures); got != tt.want {
+			t.Errorf("%d failures: lockout %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
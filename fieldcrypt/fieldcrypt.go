@@ -0,0 +1,250 @@
+// Package fieldcrypt encrypts sensitive column values before they are stored.
+//
+// Every value gets its own random 256-bit data key. The value is sealed with
+// AES-GCM under the data key, and the data key is sealed with AES-GCM under a
+// master key. The stored value holds both, base64-encoded; the master key
+// version is kept in a separate key_version column so rows written under an
+// old master key can be found and re-encrypted after a rotation. Version 0
+// means the value is plaintext (written before encryption was enabled).
+//
+// Master keys are read from the environment:
+//
+//	FIELD_ENCRYPTION_KEYS         - comma-separated "version:base64key" pairs, keys are 32 bytes
+//	FIELD_ENCRYPTION_KEY_VERSION  - version used for new values, defaults to the highest version
+//
+// Without FIELD_ENCRYPTION_KEYS values are stored in plaintext with version 0.
+//
+// Encrypted columns can only be read back whole: the database cannot filter,
+// sort or search on them (LIKE, ORDER BY, indexes). Features that need to
+// match on such a field must store a separate keyed hash (blind index) for
+// exact matches; substring search over encrypted content is not possible.
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	keySize   = 32
+	nonceSize = 12
+	// wrappedKeySize is a sealed data key: the key plus the GCM tag
+	wrappedKeySize = keySize + 16
+)
+
+// ErrUnknownKeyVersion is returned when a value was sealed with a master key that is not configured
+var ErrUnknownKeyVersion = errors.New("unknown encryption key version")
+
+// Keyring holds the master keys by version
+type Keyring struct {
+	keys    map[int]cipher.AEAD
+	current int // 0 disables encryption
+}
+
+// NewKeyring creates a keyring; current must be one of the versions in keys, or 0 to store plaintext
+func NewKeyring(keys map[int][]byte, current int) (*Keyring, error) {
+	k := &Keyring{keys: make(map[int]cipher.AEAD), current: current}
+	for version, key := range keys {
+		if version < 1 {
+			return nil, fmt.Errorf("key version %d must be positive", version)
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, fmt.Errorf("key version %d: %w", version, err)
+		}
+		k.keys[version] = aead
+	}
+
+	if current != 0 && k.keys[current] == nil {
+		return nil, fmt.Errorf("current key version %d is not configured", current)
+	}
+	return k, nil
+}
+
+// KeyringFromEnv builds a keyring from FIELD_ENCRYPTION_KEYS and FIELD_ENCRYPTION_KEY_VERSION
+func KeyringFromEnv() (*Keyring, error) {
+	keys := make(map[int][]byte)
+	var versions []int
+	for _, entry := range strings.Split(os.Getenv("FIELD_ENCRYPTION_KEYS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 2)
+		if len(parts) != 2 {
+			return nil, errors.New("FIELD_ENCRYPTION_KEYS entries must look like version:base64key")
+		}
+		version, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid key version %q", parts[0])
+		}
+		key, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("key version %d is not valid base64", version)
+		}
+		keys[version] = key
+		versions = append(versions, version)
+	}
+
+	current := 0
+	if len(versions) > 0 {
+		sort.Ints(versions)
+		current = versions[len(versions)-1]
+	}
+	if value := os.Getenv("FIELD_ENCRYPTION_KEY_VERSION"); value != "" {
+		version, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid FIELD_ENCRYPTION_KEY_VERSION %q", value)
+		}
+		current = version
+	}
+
+	return NewKeyring(keys, current)
+}
+
+// CurrentVersion returns the key version used for new values, 0 if encryption is disabled
+func (k *Keyring) CurrentVersion() int {
+	return k.current
+}
+
+// Encrypt seals a value under the current master key. The context names the
+// column (e.g. "notes.content") and must be passed again to Decrypt, so a
+// value copied into another column does not decrypt.
+func (k *Keyring) Encrypt(plaintext, context string) (string, int, error) {
+	if k.current == 0 || plaintext == "" {
+		return plaintext, k.current, nil
+	}
+	master := k.keys[k.current]
+
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", 0, err
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return "", 0, err
+	}
+
+	out := make([]byte, 0, 2*nonceSize+wrappedKeySize+len(plaintext)+data.Overhead())
+
+	// Wrapped data key, bound to the master key version
+	wrapNonce, err := randomNonce()
+	if err != nil {
+		return "", 0, err
+	}
+	out = append(out, wrapNonce...)
+	out = master.Seal(out, wrapNonce, dataKey, []byte("v"+strconv.Itoa(k.current)))
+
+	// Value, bound to the column
+	dataNonce, err := randomNonce()
+	if err != nil {
+		return "", 0, err
+	}
+	out = append(out, dataNonce...)
+	out = data.Seal(out, dataNonce, []byte(plaintext), []byte(context))
+
+	return base64.StdEncoding.EncodeToString(out), k.current, nil
+}
+
+// Decrypt opens a value sealed with the given key version; version 0 values are returned as is
+func (k *Keyring) Decrypt(value string, version int, context string) (string, error) {
+	if version == 0 || value == "" {
+		return value, nil
+	}
+	master := k.keys[version]
+	if master == nil {
+		return "", ErrUnknownKeyVersion
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted value: %w", err)
+	}
+	if len(raw) < 2*nonceSize+wrappedKeySize {
+		return "", errors.New("malformed encrypted value: too short")
+	}
+
+	wrapNonce, rest := raw[:nonceSize], raw[nonceSize:]
+	wrappedKey, rest := rest[:wrappedKeySize], rest[wrappedKeySize:]
+	dataNonce, sealed := rest[:nonceSize], rest[nonceSize:]
+
+	dataKey, err := master.Open(nil, wrapNonce, wrappedKey, []byte("v"+strconv.Itoa(version)))
+	if err != nil {
+		return "", errors.New("failed to unwrap data key")
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := data.Open(nil, dataNonce, sealed, []byte(context))
+	if err != nil {
+		return "", errors.New("failed to decrypt value")
+	}
+	return string(plaintext), nil
+}
+
+// newAEAD creates AES-256-GCM for a key
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != keySize {
+		return nil, fmt.Errorf("key must be %d bytes", keySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// randomNonce returns a fresh GCM nonce
+func randomNonce() ([]byte, error) {
+	nonce := make([]byte, nonceSize)
+	_, err := rand.Read(nonce)
+	return nonce, err
+}
+
+// defaultKeyring is configured from the environment on first use
+var defaultKeyring struct {
+	once    sync.Once
+	keyring *Keyring
+}
+
+// Default returns the keyring configured in the environment. A broken
+// configuration stops the process, since continuing would either write
+// plaintext or make stored values unreadable.
+func Default() *Keyring {
+	defaultKeyring.once.Do(func() {
+		keyring, err := KeyringFromEnv()
+		if err != nil {
+			panic("fieldcrypt: " + err.Error())
+		}
+		if keyring.CurrentVersion() == 0 {
+			fmt.Println("Warning: FIELD_ENCRYPTION_KEYS is not set, sensitive fields are stored in plaintext")
+		}
+		defaultKeyring.keyring = keyring
+	})
+	return defaultKeyring.keyring
+}
+
+// Encrypt seals a value with the default keyring
+func Encrypt(plaintext, context string) (string, int, error) {
+	return Default().Encrypt(plaintext, context)
+}
+
+// Decrypt opens a value with the default keyring
+func Decrypt(value string, version int, context string) (string, error) {
+	return Default().Decrypt(value, version, context)
+}
+
+// CurrentVersion returns the key version the default keyring uses for new values
+func CurrentVersion() int {
+	return Default().CurrentVersion()
+}
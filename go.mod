@@ -10,6 +10,7 @@ require (
 	github.com/gin-gonic/gin v1.11.0
 	github.com/golang-jwt/jwt/v4 v4.5.1
 	github.com/google/uuid v1.6.0
+	github.com/jung-kurt/gofpdf v1.16.2
 	github.com/sashabaranov/go-openai v1.37.0
 	golang.org/x/crypto v0.43.0
 	gorm.io/driver/mysql v1.5.7
@@ -4,40 +4,86 @@ import (
 	"hope_backend/api"
 	"hope_backend/config"
 	"hope_backend/dao"
+	"hope_backend/fieldcrypt"
+	"hope_backend/notify"
+	"hope_backend/oauth"
+	"hope_backend/scheduler"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func main() {
 	db := config.InitDB() // Initialize DB connection
+	fieldcrypt.Default()  // Load the field encryption keys now so a bad configuration stops startup
+	api.MustLoadNoteExportFont()
+
+	// Scheduled jobs; notifications are delivered from a queue so slow push services do not delay the schedule
+	auditDAO := dao.NewAuditDAO(db)
+	reminderDAO := dao.NewReminderDAO(db)
+	notifier := notify.NewQueue(notify.NewNotifierFromEnv(api.NewPushDeviceStore(reminderDAO)), 1000)
+	jobs := scheduler.New()
+	jobs.Every("account-deletion", api.AccountDeletionInterval, api.AccountDeletionJob(dao.NewAccountDAO(db), auditDAO))
+	jobs.Every("audit-retention", api.AuditRetentionInterval, api.AuditRetentionJob(auditDAO))
+	jobs.Every("note-trash", api.NoteTrashPurgeInterval, api.NoteTrashJob())
+	jobs.Every("note-reminders", api.NoteReminderInterval, api.NoteReminderJob(reminderDAO, notifier))
+	jobs.Every("field-reencryption", api.FieldReencryptionInterval, api.FieldReencryptionJob())
+	jobs.Every("note-import", api.NoteImportInterval, api.NoteImportJob())
+	jobs.Start()
+
+	r := setupRouter(db, notifier)
 
+	// Start the server on port 8080
+	r.Run(":12580")
+}
+
+// setupRouter registers every route. Each route is declared in a public,
+// authenticated or role-restricted group; see api.AccessGroup.
+func setupRouter(db *gorm.DB, notifier notify.Notifier) *gin.Engine {
 	// Initialize DAOs
 	userProfileDAO := dao.NewUserProfileDAO(db)
 	postDAO := dao.NewPostDAO(db)
 	commentDAO := dao.NewCommentDAO(db)
+	sessionDAO := dao.NewSessionDAO(db)
+	accountDAO := dao.NewAccountDAO(db)
+	identityDAO := dao.NewIdentityDAO(db)
+	twoFactorDAO := dao.NewTwoFactorDAO(db)
+	auditDAO := dao.NewAuditDAO(db)
+	reminderDAO := dao.NewReminderDAO(db)
+	reportDAO := dao.NewReportDAO(db)
+
+	// Third-party identity providers
+	identityProviders := oauth.NewRegistryFromEnv()
 
 	// Create a new Gin router
 	r := gin.Default()
 
 	api.SetupStaticFileServer(r)
+	api.SetupFakeOIDCProvider(r)
 
 	r.Use(api.CurlLoggingMiddleware())
-	r.Use(api.AuthMiddleware())
+
+	auth := api.AuthMiddleware(sessionDAO, twoFactorDAO)
 
 	// Create a group for all /hope routes
 	hopeGroup := r.Group("/hope")
 	{
+		// Routes anyone can call, and routes that need a login token
+		publicGroup := api.PublicGroup(hopeGroup, "")
+		userGroup := api.AuthenticatedGroup(hopeGroup, "", auth)
 
 		//测试路由
-		hopeGroup.GET("/ping", api.PingHandler)
-		hopeGroup.POST("/user", api.UserHandler)
+		publicGroup.GET("/ping", api.PingHandler)
+		publicGroup.GET("/shared/:token", api.GetSharedLinkHandler)
+		publicGroup.GET("/shared/:token/attachments/:attachmentId", api.GetSharedLinkAttachmentHandler)
+		userGroup.POST("/user", api.UserHandler)
 
 		// 消息页路由
-		hopeGroup.POST("/send", api.SendMessageHandler(userProfileDAO))
-		hopeGroup.GET("/messages", api.GetMessagesHandler)
+		userGroup.POST("/send", api.SendMessageHandler(userProfileDAO))
+		userGroup.GET("/messages", api.GetMessagesHandler)
 
 		// 笔记页面相关接口
-		notesGroup := hopeGroup.Group("/notes")
+		notesGroup := userGroup.Group("/notes")
 		{
 			// Create a new note
 			notesGroup.POST("", api.CreateNoteHandler)
@@ -62,13 +108,62 @@ func main() {
 
 			// Get notes for a specific month
 			notesGroup.GET("/month/:year/:month", api.GetNotesByMonthHandler)
+
+			// Mood and journaling statistics
+			notesGroup.GET("/stats", api.GetNoteStatsHandler)
+
+			// Downloadable report of a date range (md, pdf or csv)
+			notesGroup.GET("/export", api.ExportNotesHandler)
+
+			// Import from other journaling apps, run in the background
+			notesGroup.POST("/import", api.ImportNotesHandler)
+			notesGroup.GET("/import", api.ListNoteImportsHandler)
+			notesGroup.GET("/import/:jobId", api.GetNoteImportHandler)
+
+			// Journaling prompts and the user's own note templates
+			notesGroup.GET("/prompts", api.ListPromptsHandler)
+			notesGroup.GET("/prompts/daily", api.GetDailyPromptHandler)
+			notesGroup.GET("/templates", api.ListNoteTemplatesHandler)
+			notesGroup.POST("/templates", api.CreateNoteTemplateHandler)
+			notesGroup.PUT("/templates/:id", api.UpdateNoteTemplateHandler)
+			notesGroup.DELETE("/templates/:id", api.DeleteNoteTemplateHandler)
+
+			// Revision history
+			notesGroup.GET("/:id/revisions", api.ListNoteRevisionsHandler)
+			notesGroup.GET("/:id/revisions/diff", api.DiffNoteRevisionsHandler)
+			notesGroup.POST("/:id/revisions/:revisionId/restore", api.RestoreNoteRevisionHandler)
+
+			// AI reflection; notes matching crisis screening get the crisis response instead
+			notesGroup.POST("/:id/reflect", api.ReflectNoteHandler)
+			notesGroup.GET("/:id/reflection", api.GetNoteReflectionHandler)
+
+			// Photos; files are private and served through the API, removed when the note is purged
+			notesGroup.POST("/:id/attachments", api.AddNoteAttachmentHandler)
+			notesGroup.DELETE("/:id/attachments/:attachmentId", api.DeleteNoteAttachmentHandler)
+			notesGroup.GET("/attachments/:attachmentId", api.GetNoteAttachmentFileHandler)
+
+			// 笔记分享
+			notesGroup.POST("/shares", api.CreateNoteShareHandler)
+			notesGroup.GET("/shares", api.ListNoteSharesHandler)
+			notesGroup.GET("/shares/received", api.ListSharedWithMeHandler)
+			notesGroup.GET("/shares/access", api.ListNoteShareAccessHandler)
+			notesGroup.DELETE("/shares/:shareId", api.RevokeNoteShareHandler)
+
+			// Trash: deleted notes can be restored for 30 days
+			notesGroup.GET("/trash", api.ListTrashedNotesHandler)
+			notesGroup.POST("/trash/:id/restore", api.RestoreTrashedNoteHandler)
+			notesGroup.DELETE("/trash/:id", api.PurgeTrashedNoteHandler)
+
+			// Offline sync: pull changes after a cursor, or push a batch of changes and pull
+			notesGroup.GET("/sync", api.PullNoteChangesHandler)
+			notesGroup.POST("/sync", api.SyncNotesHandler)
 		}
 
 		// Future endpoints can be added here within the group
 		// Diary endpoints will go here
 		// Settings endpoints will go here
 		// Settings page related APIs
-		settingsGroup := hopeGroup.Group("/user")
+		settingsGroup := userGroup.Group("/user")
 		{
 			// Get user profile
 			settingsGroup.GET("/profile", api.GetUserProfileHandler(userProfileDAO))
@@ -77,22 +172,65 @@ func main() {
 			settingsGroup.PUT("/profile", api.UpdateUserProfileHandler(userProfileDAO))
 
 			// Update user password
-			settingsGroup.PUT("/password", api.UpdatePasswordHandler(userProfileDAO))
+			settingsGroup.PUT("/password", api.UpdatePasswordHandler(userProfileDAO, auditDAO))
 
 			// Update mobile number with verification
-			settingsGroup.PUT("/mobile", api.UpdateMobileNumberHandler(userProfileDAO))
+			settingsGroup.PUT("/mobile", api.UpdateMobileNumberHandler(userProfileDAO, auditDAO))
 
 			settingsGroup.POST("/upload", api.FileUploadHandler(userProfileDAO))
+
+			// List devices the user is logged in on
+			settingsGroup.GET("/sessions", api.ListSessionsHandler(sessionDAO))
+
+			// Sign out a single device
+			settingsGroup.DELETE("/sessions/:id", api.RevokeSessionHandler(sessionDAO, auditDAO))
+
+			// Sign out every device except the current one
+			settingsGroup.DELETE("/sessions", api.RevokeOtherSessionsHandler(sessionDAO, auditDAO))
+
+			// Manage linked third-party identities
+			settingsGroup.GET("/identities", api.ListIdentitiesHandler(identityDAO))
+			settingsGroup.POST("/identities/:provider", api.LinkIdentityHandler(identityProviders, identityDAO))
+			settingsGroup.DELETE("/identities/:provider", api.UnlinkIdentityHandler(identityDAO))
+
+			// Two-factor authentication (TOTP)
+			settingsGroup.GET("/2fa", api.GetTwoFactorStatusHandler(twoFactorDAO))
+			settingsGroup.POST("/2fa/setup", api.SetupTwoFactorHandler(userProfileDAO, twoFactorDAO))
+			settingsGroup.POST("/2fa/enable", api.EnableTwoFactorHandler(twoFactorDAO, sessionDAO, auditDAO))
+			settingsGroup.POST("/2fa/disable", api.DisableTwoFactorHandler(userProfileDAO, twoFactorDAO, auditDAO))
+			settingsGroup.POST("/2fa/recovery-codes", api.RegenerateRecoveryCodesHandler(twoFactorDAO, auditDAO))
+
+			// Download all personal data as a ZIP archive
+			settingsGroup.GET("/export", api.ExportUserDataHandler(accountDAO, auditDAO))
+
+			// Schedule account deletion (requires password)
+			settingsGroup.DELETE("/account", api.DeleteAccountHandler(userProfileDAO, accountDAO, sessionDAO, auditDAO))
+
+			// Check or cancel a pending account deletion
+			settingsGroup.GET("/account/deletion", api.GetAccountDeletionHandler(accountDAO))
+			settingsGroup.DELETE("/account/deletion", api.CancelAccountDeletionHandler(accountDAO, auditDAO))
+
+			// Daily journaling reminders and the push tokens they are delivered to
+			settingsGroup.GET("/reminders", api.GetReminderSettingsHandler(reminderDAO))
+			settingsGroup.PUT("/reminders", api.UpdateReminderSettingsHandler(reminderDAO))
+			settingsGroup.POST("/push-devices", api.RegisterPushDeviceHandler(reminderDAO))
+			settingsGroup.DELETE("/push-devices/:token", api.UnregisterPushDeviceHandler(reminderDAO))
 		}
 
 		// Authentication routes (outside the settingsGroup)
-		authGroup := hopeGroup.Group("/auth")
+		authGroup := publicGroup.Group("/auth")
 		{
 			// User registration
-			authGroup.POST("/register", api.RegisterUserHandler(userProfileDAO))
+			authGroup.POST("/register", api.RegisterUserHandler(userProfileDAO, sessionDAO, identityDAO, auditDAO))
 
 			// User login
-			authGroup.POST("/login", api.LoginHandler(userProfileDAO))
+			authGroup.POST("/login", api.LoginHandler(userProfileDAO, sessionDAO, twoFactorDAO, auditDAO))
+
+			// Second login step for users with 2FA enabled
+			authGroup.POST("/login/2fa", api.LoginTwoFactorHandler(userProfileDAO, twoFactorDAO, sessionDAO, auditDAO))
+
+			// Login with WeChat, Apple, ...
+			authGroup.POST("/oauth/:provider/login", api.OAuthLoginHandler(identityProviders, identityDAO, userProfileDAO, sessionDAO, twoFactorDAO, auditDAO))
 
 			// Request verification code for mobile number
 			authGroup.POST("/verification-code", api.RequestVerificationCodeHandler())
@@ -103,7 +241,7 @@ func main() {
 
 		// Inside the hopeGroup
 		// Post-related endpoints
-		postsGroup := hopeGroup.Group("/posts")
+		postsGroup := userGroup.Group("/posts")
 		{
 			// Create a new post
 			postsGroup.POST("", api.CreatePostHandler(postDAO))
@@ -115,7 +253,7 @@ func main() {
 			postsGroup.PUT("/:id", api.UpdatePostHandler(postDAO))
 
 			// Delete a post
-			postsGroup.DELETE("/:id", api.DeletePostHandler(postDAO))
+			postsGroup.DELETE("/:id", api.DeletePostHandler(postDAO, auditDAO))
 
 			// List posts with pagination
 			postsGroup.GET("", api.ListPostsHandler(postDAO))
@@ -127,24 +265,51 @@ func main() {
 			postsGroup.POST("/:id/unlike", api.UnlikePostHandler(postDAO))
 
 			// Comment endpoints
-			postsGroup.POST("/:id/comments", api.CreateCommentHandler(commentDAO))
-			postsGroup.GET("/:id/comments", api.ListCommentsHandler(commentDAO))
+			postsGroup.POST("/:id/comments", api.CreateCommentHandler(commentDAO, postDAO))
+			postsGroup.GET("/:id/comments", api.ListCommentsHandler(commentDAO, postDAO))
+
+			// Report a post to the moderators
+			postsGroup.POST("/:id/report", api.ReportPostHandler(reportDAO))
 		}
 
 		// Comment-related endpoints
-		commentsGroup := hopeGroup.Group("/comments")
+		commentsGroup := userGroup.Group("/comments")
 		{
 			// Delete a comment
-			commentsGroup.DELETE("/:id", api.DeleteCommentHandler(commentDAO))
+			commentsGroup.DELETE("/:id", api.DeleteCommentHandler(commentDAO, auditDAO))
 
 			// Like a comment
-			commentsGroup.POST("/:id/like", api.LikeCommentHandler(commentDAO))
+			commentsGroup.POST("/:id/like", api.LikeCommentHandler(commentDAO, postDAO))
 
 			// Unlike a comment
-			commentsGroup.POST("/:id/unlike", api.UnlikeCommentHandler(commentDAO))
+			commentsGroup.POST("/:id/unlike", api.UnlikeCommentHandler(commentDAO, postDAO))
+
+			// Report a comment to the moderators
+			commentsGroup.POST("/:id/report", api.ReportCommentHandler(reportDAO))
 		}
 
-		hopeGroup.Static("/file/posts", "./uploads/posts")
+		// Moderation queue for reported posts and comments
+		moderationGroup := api.RoleGroup(hopeGroup, "/moderation", auth, api.RoleModerator, api.RoleAdmin)
+		{
+			moderationGroup.GET("/reports", api.ListReportsHandler(reportDAO))
+			moderationGroup.POST("/reports/:id/claim", api.ClaimReportHandler(reportDAO))
+			moderationGroup.POST("/reports/:id/resolve", api.ResolveReportHandler(reportDAO, postDAO, commentDAO, userProfileDAO, auditDAO, notifier))
+
+			// Record of every moderation decision
+			moderationGroup.GET("/decisions", api.ListModerationDecisionsHandler(reportDAO))
+		}
+
+		// Admin endpoints
+		adminGroup := api.RoleGroup(hopeGroup, "/admin", auth, api.RoleAdmin)
+		{
+			// Change a user's role
+			adminGroup.PUT("/users/:id/role", api.UpdateUserRoleHandler(userProfileDAO, sessionDAO, auditDAO))
+
+			// Search the security audit log
+			adminGroup.GET("/audit-logs", api.ListAuditLogsHandler(auditDAO))
+		}
+
+		publicGroup.Static("/file/posts", "./uploads/posts")
 
 	}
 
@@ -152,6 +317,5 @@ func main() {
 	// r.Static("/hope/file/post", "./uploads/posts") // assuming your images are stored in ./uploads/post directory
 	// r.Static("/uploads", "./uploads")
 
-	// Start the server on port 8080
-	r.Run(":12580")
+	return r
 }
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"testing"
+
+	"hope_backend/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestEveryRouteDeclaresAccessPolicy fails when a route is registered
+// outside a public, authenticated or role-restricted group.
+func TestEveryRouteDeclaresAccessPolicy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := setupRouter(nil, nil)
+
+	for _, route := range r.Routes() {
+		if _, ok := api.RoutePolicy(route.Method, route.Path); !ok {
+			t.Errorf("%s %s has no declared access policy", route.Method, route.Path)
+		}
+	}
+}
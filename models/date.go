@@ -0,0 +1,130 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// DateLayout is the "YYYY.M.D" format clients have always used for note dates
+	DateLayout = "2006.1.2"
+	// ISODateLayout is the "YYYY-MM-DD" format, accepted as input and used for the DATE column
+	ISODateLayout = "2006-01-02"
+
+	minDateYear = 1900
+	maxDateYear = 2100
+)
+
+// Date is a calendar day without a time of day or timezone, stored in a DATE column.
+// It is written to JSON as "YYYY.M.D" so existing clients keep working.
+type Date struct {
+	time.Time // Midnight UTC of the day
+}
+
+// NewDate returns the date for a year, month and day
+func NewDate(year int, month time.Month, day int) Date {
+	return Date{time.Date(year, month, day, 0, 0, 0, 0, time.UTC)}
+}
+
+// DateOf returns the calendar day of t in t's location
+func DateOf(t time.Time) Date {
+	return NewDate(t.Year(), t.Month(), t.Day())
+}
+
+// ParseDate parses "YYYY.M.D" (zero padding optional) or "YYYY-MM-DD"
+func ParseDate(value string) (Date, error) {
+	value = strings.TrimSpace(value)
+
+	layout := DateLayout
+	if strings.Contains(value, "-") {
+		layout = ISODateLayout
+	}
+
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return Date{}, fmt.Errorf("invalid date %q, expected YYYY.M.D or YYYY-MM-DD", value)
+	}
+	if t.Year() < minDateYear || t.Year() > maxDateYear {
+		return Date{}, fmt.Errorf("date %q is out of range", value)
+	}
+
+	return Date{t}, nil
+}
+
+// String formats the date as "YYYY.M.D"
+func (d Date) String() string {
+	return d.Format(DateLayout)
+}
+
+// AddDays returns the date n days later (or earlier for negative n)
+func (d Date) AddDays(n int) Date {
+	return Date{d.AddDate(0, 0, n)}
+}
+
+// MarshalJSON writes the date as "YYYY.M.D"
+func (d Date) MarshalJSON() ([]byte, error) {
+	if d.IsZero() {
+		return []byte(`""`), nil
+	}
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON accepts "YYYY.M.D", "YYYY-MM-DD" or an empty string
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if value == "" {
+		*d = Date{}
+		return nil
+	}
+
+	parsed, err := ParseDate(value)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// GormDataType tells GORM to use a DATE column
+func (Date) GormDataType() string {
+	return "date"
+}
+
+// Value implements driver.Valuer
+func (d Date) Value() (driver.Value, error) {
+	return d.Format(ISODateLayout), nil
+}
+
+// Scan implements sql.Scanner for DATE columns read with or without parseTime
+func (d *Date) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case time.Time:
+		*d = NewDate(v.Year(), v.Month(), v.Day())
+		return nil
+	case []byte:
+		return d.scanString(string(v))
+	case string:
+		return d.scanString(v)
+	case nil:
+		*d = Date{}
+		return nil
+	}
+	return errors.New("unsupported type for Date")
+}
+
+// scanString parses the text form of a DATE column
+func (d *Date) scanString(value string) error {
+	t, err := time.Parse(ISODateLayout, value)
+	if err != nil {
+		return err
+	}
+	*d = Date{t}
+	return nil
+}
@@ -5,7 +5,8 @@ type Message struct {
 	SenderID    int64  `gorm:"not null" json:"sender_id"`
 	ReceiverID  int64  `gorm:"not null" json:"receiver_id"`
 	ChatID      string `gorm:"not null" json:"chat_id"`
-	Content     string `gorm:"type:varchar(2000);not null" json:"content"`
+	Content     string `gorm:"type:text;not null" json:"content"`  // Encrypted at rest, at most 2000 characters before encryption
+	KeyVersion  int    `gorm:"not null;default:0" json:"-"`        // Master key the content was encrypted with, 0 for plaintext
 	MsgType     uint8  `gorm:"not null;default:1" json:"msg_type"` // 1=text, 2=image, etc.
 	Status      uint8  `gorm:"not null;default:0" json:"status"`   // 0=sent, 1=delivered, 2=read
 	CreatedTime int64  `gorm:"autoCreateTime" json:"created_time"`
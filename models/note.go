@@ -2,10 +2,63 @@ package models
 
 // Note represents a daily note in the system
 type Note struct {
-	NoteID    int    `json:"note_id" db:"note_id"`
+	NoteID    int    `json:"note_id" db:"note_id" gorm:"primaryKey"`
 	UserID    int64  `json:"user_id" db:"user_id"`
-	NoteDate  string `json:"note_date" db:"note_date"`
+	NoteDate  Date   `json:"note_date" db:"note_date"` // DATE column, "YYYY.M.D" in JSON
 	Content   string `json:"content" db:"content"`
 	CreatedAt int64  `json:"created_at" db:"created_at"`
 	UpdatedAt int64  `json:"updated_at" db:"updated_at"`
+	DeletedAt int64  `json:"deleted_at,omitempty" db:"deleted_at"` // Moved to the trash at, 0 while live
+	PromptID  *int   `json:"prompt_id" db:"prompt_id"`             // Journaling prompt the note answers, if any
+
+	// Offline sync
+	ClientID *string `json:"client_id" db:"client_id"` // ID generated by the client that created the note offline
+	Version  int     `json:"version" db:"version"`     // Incremented on every change, used for optimistic concurrency
+	SyncSeq  int64   `json:"sync_seq" db:"sync_seq"`   // Position in the user's change feed
+
+	// Master key the content was encrypted with, 0 for plaintext; see dao.sealNote
+	KeyVersion int `json:"-" db:"key_version"`
+
+	// Structured check-in, all optional; nil means not logged that day
+	PatientMood   *int     `json:"patient_mood" db:"patient_mood"`                        // 1-10
+	CaregiverMood *int     `json:"caregiver_mood" db:"caregiver_mood"`                    // 1-10
+	SleepHours    *float64 `json:"sleep_hours" db:"sleep_hours"`                          // Patient's sleep, 0-24
+	EmotionTags   []string `json:"emotion_tags" db:"emotion_tags" gorm:"serializer:json"` // Stored as a JSON array
+
+	// Photos, loaded from note_attachments
+	Attachments []NoteAttachment `json:"attachments" gorm:"-"`
+}
+
+// NoteRevision is a previous version of a note, saved every time the note is changed
+type NoteRevision struct {
+	RevisionID    int64    `json:"revision_id" db:"revision_id" gorm:"primaryKey"`
+	NoteID        int      `json:"note_id" db:"note_id"`
+	UserID        int64    `json:"user_id" db:"user_id"`
+	Content       string   `json:"content" db:"content"`
+	KeyVersion    int      `json:"-" db:"key_version"`
+	PromptID      *int     `json:"prompt_id" db:"prompt_id"`
+	PatientMood   *int     `json:"patient_mood" db:"patient_mood"`
+	CaregiverMood *int     `json:"caregiver_mood" db:"caregiver_mood"`
+	SleepHours    *float64 `json:"sleep_hours" db:"sleep_hours"`
+	EmotionTags   []string `json:"emotion_tags" db:"emotion_tags" gorm:"serializer:json"`
+	NoteUpdatedAt int64    `json:"note_updated_at" db:"note_updated_at"` // When this version was written
+	CreatedAt     int64    `json:"created_at" db:"created_at"`           // When it was replaced
+}
+
+// NewNoteRevision snapshots the current state of a note. The content is
+// copied as stored, so the snapshot of an encrypted note stays encrypted.
+func NewNoteRevision(note *Note, now int64) *NoteRevision {
+	return &NoteRevision{
+		NoteID:        note.NoteID,
+		UserID:        note.UserID,
+		Content:       note.Content,
+		KeyVersion:    note.KeyVersion,
+		PromptID:      note.PromptID,
+		PatientMood:   note.PatientMood,
+		CaregiverMood: note.CaregiverMood,
+		SleepHours:    note.SleepHours,
+		EmotionTags:   note.EmotionTags,
+		NoteUpdatedAt: note.UpdatedAt,
+		CreatedAt:     now,
+	}
 }
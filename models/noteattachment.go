@@ -0,0 +1,27 @@
+package models
+
+import "fmt"
+
+// NoteAttachmentBaseURL is where attachment files are served. Unlike post
+// images they are not public: the request needs a token of a user who may read the note.
+const NoteAttachmentBaseURL = "https://hope.layu.cc/hope/notes/attachments"
+
+// NoteAttachment is a photo attached to a note
+type NoteAttachment struct {
+	AttachmentID int64  `json:"attachment_id" db:"attachment_id" gorm:"primaryKey"`
+	NoteID       int    `json:"note_id" db:"note_id"`
+	UserID       int64  `json:"user_id" db:"user_id"`
+	FilePath     string `json:"-" db:"file_path"` // Relative to the uploads directory, e.g. "notes/12-<uuid>.jpg"
+	DisplayOrder int    `json:"display_order" db:"display_order"`
+	CreatedAt    int64  `json:"created_at" db:"created_at"`
+
+	// Set when the attachment is loaded, not stored
+	URL          string `json:"url" gorm:"-"`
+	ThumbnailURL string `json:"thumbnail_url" gorm:"-"`
+}
+
+// SetURLs fills in the URLs the attachment is served at
+func (a *NoteAttachment) SetURLs() {
+	a.URL = fmt.Sprintf("%s/%d", NoteAttachmentBaseURL, a.AttachmentID)
+	a.ThumbnailURL = a.URL + "?size=thumbnail"
+}
@@ -0,0 +1,54 @@
+package models
+
+// Note import job statuses
+const (
+	ImportStatusPending   = "pending"
+	ImportStatusRunning   = "running"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+)
+
+// What an import does with an entry whose date already has a note
+const (
+	ImportConflictSkip  = "skip"  // Keep the existing note unchanged
+	ImportConflictMerge = "merge" // Append the entry to the existing note
+)
+
+// NoteImportJob is a file of journal entries from another app being imported
+// in the background. Entries are processed in file order, and the counters
+// are saved after each one so clients can show progress.
+type NoteImportJob struct {
+	JobID      int64  `json:"job_id" db:"job_id" gorm:"primaryKey"`
+	UserID     int64  `json:"user_id" db:"user_id"`
+	Status     string `json:"status" db:"status"`
+	FileName   string `json:"file_name" db:"file_name"` // Name of the uploaded file
+	FilePath   string `json:"-" db:"file_path"`         // Stored upload relative to the uploads directory, removed when the job ends
+	OnConflict string `json:"on_conflict" db:"on_conflict"`
+
+	Total     int `json:"total" db:"total"`         // Entries found, one per date, plus entries that could not be read
+	Processed int `json:"processed" db:"processed"` // Entries handled so far
+	Created   int `json:"created" db:"created"`
+	Merged    int `json:"merged" db:"merged"`
+	Skipped   int `json:"skipped" db:"skipped"`
+	Failed    int `json:"failed" db:"failed"`
+
+	Errors  []NoteImportError `json:"errors" db:"errors" gorm:"serializer:json"` // The first failed entries
+	Message string            `json:"message,omitempty" db:"message"`            // Why the whole import failed
+
+	CreatedAt  int64 `json:"created_at" db:"created_at"`
+	StartedAt  int64 `json:"started_at" db:"started_at"`
+	UpdatedAt  int64 `json:"updated_at" db:"updated_at"` // Last progress, used to resume jobs cut off by a restart
+	FinishedAt int64 `json:"finished_at" db:"finished_at"`
+}
+
+// NoteImportError describes an entry that was not imported
+type NoteImportError struct {
+	Source  string `json:"source"` // File and position, e.g. "Journal.json entry 3" or "notes.csv line 12"
+	Date    string `json:"date,omitempty"`
+	Message string `json:"message"`
+}
+
+// Done reports whether the job has finished, successfully or not
+func (j *NoteImportJob) Done() bool {
+	return j.Status == ImportStatusCompleted || j.Status == ImportStatusFailed
+}
@@ -0,0 +1,17 @@
+package models
+
+// NoteReflection is a short AI reflection on a note: a validating response
+// and one small suggestion. A note keeps only its latest reflection.
+type NoteReflection struct {
+	ReflectionID int64  `json:"reflection_id" db:"reflection_id" gorm:"primaryKey"`
+	NoteID       int    `json:"note_id" db:"note_id"`
+	UserID       int64  `json:"user_id" db:"user_id"`
+	Content      string `json:"content" db:"content"`
+	KeyVersion   int    `json:"-" db:"key_version"`
+	Provider     string `json:"provider" db:"provider"`           // AI provider that wrote it, empty for a crisis response
+	Model        string `json:"model" db:"model"`                 // Model of that provider
+	Crisis       bool   `json:"crisis" db:"crisis"`               // The note matched crisis screening; Content is the crisis response
+	IncludedMood bool   `json:"included_mood" db:"included_mood"` // The mood fields were sent along with the content
+	NoteVersion  int    `json:"note_version" db:"note_version"`   // Version of the note it was written for; older than the note means stale
+	CreatedAt    int64  `json:"created_at" db:"created_at"`
+}
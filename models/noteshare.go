@@ -0,0 +1,46 @@
+package models
+
+// NoteShare gives another user, or anyone holding a link, read-only access
+// to some of a user's notes: either a list of notes or every note in a date
+// range. Only live notes are visible; notes in the trash are hidden.
+//
+// A share with a user is addressed to a mobile number. If no account has the
+// number yet, the share is pending and goes to the account that registers it.
+// The recipient's account is never shown to the owner, so that sharing does
+// not reveal which numbers are registered.
+type NoteShare struct {
+	ShareID         int64   `json:"share_id" db:"share_id" gorm:"primaryKey"`
+	OwnerID         int64   `json:"owner_id" db:"owner_id"`
+	RecipientID     *int64  `json:"-" db:"recipient_id"`                              // Nil for link shares and pending shares
+	RecipientMobile string  `json:"recipient_mobile,omitempty" db:"recipient_mobile"` // Empty for link shares
+	TokenHash       *string `json:"-" db:"token_hash"`                                // SHA-256 of the link token, link shares only
+	Label           string  `json:"label" db:"label"`                                 // Owner's name for the share, e.g. "Dr. Li"
+	NoteIDs         []int   `json:"note_ids" db:"note_ids" gorm:"serializer:json"`
+	StartDate       *Date   `json:"start_date" db:"start_date"` // Date range, both ends inclusive; nil when NoteIDs are shared
+	EndDate         *Date   `json:"end_date" db:"end_date"`
+	ExpiresAt       int64   `json:"expires_at" db:"expires_at"`           // Unix seconds, 0 for no expiry
+	RevokedAt       int64   `json:"revoked_at,omitempty" db:"revoked_at"` // 0 while active
+	CreatedAt       int64   `json:"created_at" db:"created_at"`
+}
+
+// Active reports whether the share grants access at the given time (unix seconds)
+func (s *NoteShare) Active(now int64) bool {
+	return s.RevokedAt == 0 && (s.ExpiresAt == 0 || now < s.ExpiresAt)
+}
+
+// Covers reports whether the share includes a note of its owner
+func (s *NoteShare) Covers(note *Note) bool {
+	if note.UserID != s.OwnerID || note.DeletedAt != 0 {
+		return false
+	}
+
+	if s.StartDate != nil && s.EndDate != nil {
+		return !note.NoteDate.Before(s.StartDate.Time) && !note.NoteDate.After(s.EndDate.Time)
+	}
+	for _, noteID := range s.NoteIDs {
+		if noteID == note.NoteID {
+			return true
+		}
+	}
+	return false
+}
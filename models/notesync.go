@@ -0,0 +1,24 @@
+package models
+
+// NoteSyncState holds the per-user change counter used as the sync cursor.
+// Every note change takes the next value, so changes are ordered per user.
+type NoteSyncState struct {
+	UserID int64 `json:"user_id" db:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	Seq    int64 `json:"seq" db:"seq"`
+}
+
+// TableName specifies the table name for GORM
+func (NoteSyncState) TableName() string {
+	return "note_sync_state"
+}
+
+// NoteTombstone records a note that was permanently deleted, so clients
+// syncing from an older cursor still learn about the deletion
+type NoteTombstone struct {
+	ID        int64   `json:"-" db:"id" gorm:"primaryKey"`
+	UserID    int64   `json:"-" db:"user_id"`
+	NoteID    int     `json:"note_id" db:"note_id"`
+	ClientID  *string `json:"client_id" db:"client_id"`
+	SyncSeq   int64   `json:"sync_seq" db:"sync_seq"`
+	DeletedAt int64   `json:"deleted_at" db:"deleted_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+// JournalPrompt is a question from the prompt library that helps users start a note
+type JournalPrompt struct {
+	PromptID  int    `json:"prompt_id" db:"prompt_id" gorm:"primaryKey"`
+	Category  string `json:"category" db:"category"` // e.g. gratitude, coping, self_care
+	Locale    string `json:"locale" db:"locale"`     // BCP 47 tag such as "zh-CN" or "en"
+	Text      string `json:"text" db:"text"`
+	Active    bool   `json:"-" db:"active"` // Retired prompts stay so old notes keep their reference
+	CreatedAt int64  `json:"created_at" db:"created_at"`
+}
+
+// NoteTemplate is a note outline a user saved for reuse
+type NoteTemplate struct {
+	TemplateID int    `json:"template_id" db:"template_id" gorm:"primaryKey"`
+	UserID     int64  `json:"user_id" db:"user_id"`
+	Name       string `json:"name" db:"name"`
+	Content    string `json:"content" db:"content"`
+	PromptID   *int   `json:"prompt_id" db:"prompt_id"` // Prompt the template answers, if any
+	CreatedAt  int64  `json:"created_at" db:"created_at"`
+	UpdatedAt  int64  `json:"updated_at" db:"updated_at"`
+}
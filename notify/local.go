@@ -0,0 +1,70 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// LogNotifier prints notifications instead of delivering them, for local development
+type LogNotifier struct{}
+
+// NewLogNotifier creates a LogNotifier
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+// Name implements Notifier
+func (n *LogNotifier) Name() string {
+	return "log"
+}
+
+// Notify implements Notifier
+func (n *LogNotifier) Notify(ctx context.Context, notification Notification) error {
+	fmt.Printf("[Notify] user=%d category=%s title=%q body=%q\n",
+		notification.UserID, notification.Category, notification.Title, notification.Body)
+	return nil
+}
+
+// FileNotifier appends notifications to a file as JSON lines, so tests and
+// local setups can inspect what would have been sent
+type FileNotifier struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileNotifier creates a FileNotifier writing to path
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{path: path}
+}
+
+// Name implements Notifier
+func (n *FileNotifier) Name() string {
+	return "file"
+}
+
+// Notify implements Notifier
+func (n *FileNotifier) Notify(ctx context.Context, notification Notification) error {
+	line, err := json.Marshal(struct {
+		Notification
+		SentAt int64 `json:"sent_at"`
+	}{notification, time.Now().UnixMilli()})
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f, err := os.OpenFile(n.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,75 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Notification is a message for one user
+type Notification struct {
+	UserID   int64             `json:"user_id"`
+	Category string            `json:"category"` // e.g. "note_reminder", lets clients route the tap
+	Title    string            `json:"title"`
+	Body     string            `json:"body"`
+	Data     map[string]string `json:"data,omitempty"`
+}
+
+// Notifier delivers notifications to users
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, n Notification) error
+}
+
+// Device is a push token registered by one of the user's app installs
+type Device struct {
+	Platform string // PlatformIOS or PlatformAndroid
+	Token    string
+}
+
+// Push platforms
+const (
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+)
+
+// DeviceStore looks up the push tokens of a user
+type DeviceStore interface {
+	Devices(userID int64) ([]Device, error)
+	// RemoveDevice forgets a token the push service reported as no longer valid
+	RemoveDevice(token string) error
+}
+
+// ErrQueueFull is returned by Queue.Notify when the delivery backlog is full
+var ErrQueueFull = errors.New("notification queue is full")
+
+// NewNotifierFromEnv creates the notifier selected in the environment:
+//
+//	NOTIFIER=log   - print notifications to stdout (default)
+//	NOTIFIER=file  - append notifications as JSON lines to NOTIFY_FILE (default notifications.log)
+//	NOTIFIER=push  - send through APNs and/or FCM, see NewPushNotifierFromEnv
+func NewNotifierFromEnv(devices DeviceStore) Notifier {
+	var n Notifier
+	switch os.Getenv("NOTIFIER") {
+	case "file":
+		path := os.Getenv("NOTIFY_FILE")
+		if path == "" {
+			path = "notifications.log"
+		}
+		n = NewFileNotifier(path)
+	case "push":
+		push, err := NewPushNotifierFromEnv(devices)
+		if err != nil {
+			fmt.Printf("Warning: Push notifications are not configured, falling back to log: %v\n", err)
+			n = NewLogNotifier()
+		} else {
+			n = push
+		}
+	default:
+		n = NewLogNotifier()
+	}
+
+	fmt.Printf("Notifier enabled: %s\n", n.Name())
+	return n
+}
@@ -0,0 +1,389 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	apnsProductionHost = "https://api.push.apple.com"
+	apnsSandboxHost    = "https://api.sandbox.push.apple.com"
+	// apnsTokenLifetime is how long a provider token is reused; Apple rejects tokens older than an hour
+	apnsTokenLifetime = 50 * time.Minute
+
+	fcmEndpoint = "https://fcm.googleapis.com/v1/projects/%s/messages:send"
+	fcmScope    = "https://www.googleapis.com/auth/firebase.messaging"
+)
+
+// errInvalidToken marks a device token the push service no longer accepts
+var errInvalidToken = errors.New("device token is no longer valid")
+
+// sender delivers one notification to one device
+type sender interface {
+	send(ctx context.Context, token string, n Notification) error
+}
+
+// PushNotifier sends notifications to every registered device of a user
+// through APNs (iOS) and FCM (Android)
+type PushNotifier struct {
+	devices DeviceStore
+	apns    sender // nil if APNs is not configured
+	fcm     sender // nil if FCM is not configured
+}
+
+// NewPushNotifierFromEnv configures the push services found in the environment:
+//
+//	APNS_KEY_FILE, APNS_KEY_ID, APNS_TEAM_ID, APNS_TOPIC - token-based APNs auth (.p8 key, bundle ID)
+//	APNS_PRODUCTION=true                                 - use the production gateway instead of the sandbox
+//	FCM_SERVICE_ACCOUNT_FILE                             - Firebase service account JSON for the FCM HTTP v1 API
+func NewPushNotifierFromEnv(devices DeviceStore) (*PushNotifier, error) {
+	n := &PushNotifier{devices: devices}
+
+	if keyFile := os.Getenv("APNS_KEY_FILE"); keyFile != "" {
+		apns, err := newAPNsSender(keyFile, os.Getenv("APNS_KEY_ID"), os.Getenv("APNS_TEAM_ID"),
+			os.Getenv("APNS_TOPIC"), os.Getenv("APNS_PRODUCTION") == "true")
+		if err != nil {
+			return nil, fmt.Errorf("APNs: %w", err)
+		}
+		n.apns = apns
+	}
+
+	if accountFile := os.Getenv("FCM_SERVICE_ACCOUNT_FILE"); accountFile != "" {
+		fcm, err := newFCMSender(accountFile)
+		if err != nil {
+			return nil, fmt.Errorf("FCM: %w", err)
+		}
+		n.fcm = fcm
+	}
+
+	if n.apns == nil && n.fcm == nil {
+		return nil, errors.New("neither APNS_KEY_FILE nor FCM_SERVICE_ACCOUNT_FILE is set")
+	}
+	return n, nil
+}
+
+// Name implements Notifier
+func (n *PushNotifier) Name() string {
+	return "push"
+}
+
+// Notify implements Notifier. Tokens rejected as invalid are removed; the
+// first other error is returned after all devices were tried.
+func (n *PushNotifier) Notify(ctx context.Context, notification Notification) error {
+	devices, err := n.devices.Devices(notification.UserID)
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	for _, device := range devices {
+		var s sender
+		switch device.Platform {
+		case PlatformIOS:
+			s = n.apns
+		case PlatformAndroid:
+			s = n.fcm
+		}
+		if s == nil {
+			continue
+		}
+
+		err := s.send(ctx, device.Token, notification)
+		if errors.Is(err, errInvalidToken) {
+			if err := n.devices.RemoveDevice(device.Token); err != nil {
+				fmt.Printf("Warning: Failed to remove invalid push token: %v\n", err)
+			}
+			continue
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// apnsSender talks to the APNs HTTP/2 API with a provider authentication token
+type apnsSender struct {
+	host   string
+	topic  string
+	keyID  string
+	teamID string
+	key    *ecdsa.PrivateKey
+	client *http.Client
+
+	mu       sync.Mutex
+	token    string
+	issuedAt time.Time
+}
+
+// newAPNsSender loads the .p8 signing key downloaded from the Apple developer account
+func newAPNsSender(keyFile, keyID, teamID, topic string, production bool) (*apnsSender, error) {
+	if keyID == "" || teamID == "" || topic == "" {
+		return nil, errors.New("APNS_KEY_ID, APNS_TEAM_ID and APNS_TOPIC are required")
+	}
+
+	pem, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := jwt.ParseECPrivateKeyFromPEM(pem)
+	if err != nil {
+		return nil, err
+	}
+
+	host := apnsSandboxHost
+	if production {
+		host = apnsProductionHost
+	}
+
+	return &apnsSender{
+		host:   host,
+		topic:  topic,
+		keyID:  keyID,
+		teamID: teamID,
+		key:    key,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// providerToken returns a cached ES256 token, signing a new one when it is about to expire
+func (s *apnsSender) providerToken() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && time.Since(s.issuedAt) < apnsTokenLifetime {
+		return s.token, nil
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": s.teamID,
+		"iat": now.Unix(),
+	})
+	token.Header["kid"] = s.keyID
+
+	signed, err := token.SignedString(s.key)
+	if err != nil {
+		return "", err
+	}
+	s.token, s.issuedAt = signed, now
+	return signed, nil
+}
+
+// send implements sender
+func (s *apnsSender) send(ctx context.Context, deviceToken string, n Notification) error {
+	payload := map[string]interface{}{
+		"aps": map[string]interface{}{
+			"alert":     map[string]string{"title": n.Title, "body": n.Body},
+			"sound":     "default",
+			"category":  n.Category,
+			"thread-id": n.Category,
+		},
+	}
+	for key, value := range n.Data {
+		payload[key] = value
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	authToken, err := s.providerToken()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.host+"/3/device/"+deviceToken, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("authorization", "bearer "+authToken)
+	req.Header.Set("apns-topic", s.topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+	req.Header.Set("content-type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var result struct {
+		Reason string `json:"reason"`
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+	if resp.StatusCode == http.StatusGone || result.Reason == "BadDeviceToken" || result.Reason == "Unregistered" {
+		return errInvalidToken
+	}
+	return fmt.Errorf("APNs returned %d: %s", resp.StatusCode, result.Reason)
+}
+
+// fcmSender talks to the FCM HTTP v1 API with an OAuth token for a service account
+type fcmSender struct {
+	projectID   string
+	clientEmail string
+	tokenURI    string
+	key         *rsa.PrivateKey
+	client      *http.Client
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// newFCMSender loads a Firebase service account file
+func newFCMSender(accountFile string) (*fcmSender, error) {
+	data, err := os.ReadFile(accountFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var account struct {
+		ProjectID   string `json:"project_id"`
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, err
+	}
+	if account.ProjectID == "" || account.ClientEmail == "" || account.TokenURI == "" {
+		return nil, errors.New("service account file is missing project_id, client_email or token_uri")
+	}
+
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, err
+	}
+
+	return &fcmSender{
+		projectID:   account.ProjectID,
+		clientEmail: account.ClientEmail,
+		tokenURI:    account.TokenURI,
+		key:         key,
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// accessToken returns a cached OAuth access token, exchanging a signed assertion for a new one when needed
+func (s *fcmSender) accessToken(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && time.Now().Before(s.expiresAt) {
+		return s.token, nil
+	}
+
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   s.clientEmail,
+		"scope": fcmScope,
+		"aud":   s.tokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(s.key)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.tokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("token exchange returned %d: %s", resp.StatusCode, body)
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+
+	// Refresh a minute early so a token never expires in flight
+	s.token = result.AccessToken
+	s.expiresAt = now.Add(time.Duration(result.ExpiresIn)*time.Second - time.Minute)
+	return s.token, nil
+}
+
+// send implements sender
+func (s *fcmSender) send(ctx context.Context, deviceToken string, n Notification) error {
+	data := map[string]string{"category": n.Category}
+	for key, value := range n.Data {
+		data[key] = value
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"message": map[string]interface{}{
+			"token":        deviceToken,
+			"notification": map[string]string{"title": n.Title, "body": n.Body},
+			"data":         data,
+			"android":      map[string]string{"priority": "high"},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	accessToken, err := s.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf(fcmEndpoint, s.projectID), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	// UNREGISTERED tokens come back as 404
+	if resp.StatusCode == http.StatusNotFound {
+		return errInvalidToken
+	}
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("FCM returned %d: %s", resp.StatusCode, message)
+}
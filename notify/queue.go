@@ -0,0 +1,44 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+)
+
+// Queue hands notifications to a background worker, so callers such as
+// scheduled jobs are not held up by slow push services
+type Queue struct {
+	next    Notifier
+	pending chan Notification
+}
+
+// NewQueue starts a worker that delivers up to size queued notifications through next
+func NewQueue(next Notifier, size int) *Queue {
+	q := &Queue{next: next, pending: make(chan Notification, size)}
+	go q.run()
+	return q
+}
+
+// Name implements Notifier
+func (q *Queue) Name() string {
+	return q.next.Name()
+}
+
+// Notify implements Notifier; it only enqueues and fails if the queue is full
+func (q *Queue) Notify(ctx context.Context, n Notification) error {
+	select {
+	case q.pending <- n:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// run delivers queued notifications one at a time
+func (q *Queue) run() {
+	for n := range q.pending {
+		if err := q.next.Notify(context.Background(), n); err != nil {
+			fmt.Printf("[Notify] Failed to deliver %s to user %d: %v\n", n.Category, n.UserID, err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Identity is the user identity asserted by an external provider
+type Identity struct {
+	Provider  string `json:"provider"`
+	Subject   string `json:"subject"` // Stable user ID at the provider
+	Nickname  string `json:"nickname"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+// Connector exchanges a client credential (authorization code or ID token)
+// for a verified identity at one provider
+type Connector interface {
+	Name() string
+	Exchange(ctx context.Context, credential string) (*Identity, error)
+}
+
+// ErrUnknownProvider is returned when no connector is registered for a provider
+var ErrUnknownProvider = errors.New("unknown identity provider")
+
+// Registry holds the enabled connectors by provider name
+type Registry map[string]Connector
+
+// Register adds a connector to the registry
+func (r Registry) Register(c Connector) {
+	r[c.Name()] = c
+}
+
+// Get returns the connector for a provider
+func (r Registry) Get(provider string) (Connector, error) {
+	c, ok := r[provider]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return c, nil
+}
+
+// NewRegistryFromEnv enables the connectors that are configured in the environment:
+//
+//	WECHAT_APP_ID, WECHAT_APP_SECRET  - WeChat open platform login
+//	APPLE_CLIENT_ID                   - Sign in with Apple (bundle or service ID)
+//	FAKE_OIDC_ISSUER                  - local fake provider, see FakeProvider
+func NewRegistryFromEnv() Registry {
+	r := Registry{}
+
+	if appID, secret := os.Getenv("WECHAT_APP_ID"), os.Getenv("WECHAT_APP_SECRET"); appID != "" && secret != "" {
+		r.Register(NewWeChatConnector(appID, secret))
+	}
+
+	if clientID := os.Getenv("APPLE_CLIENT_ID"); clientID != "" {
+		r.Register(NewAppleConnector(clientID))
+	}
+
+	if issuer := os.Getenv("FAKE_OIDC_ISSUER"); issuer != "" {
+		r.Register(NewFakeConnector(issuer))
+	}
+
+	fmt.Printf("Identity providers enabled: %d\n", len(r))
+	return r
+}
@@ -0,0 +1,173 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Client credentials accepted by FakeProvider
+const (
+	FakeClientID     = "hope-local"
+	FakeClientSecret = "hope-local-secret"
+	fakeKeyID        = "fake-1"
+)
+
+// FakeProvider is a minimal in-process OpenID Connect provider for local
+// development and offline integration tests. Any subject can log in:
+//
+//	GET  /authorize?sub=alice&name=Alice[&redirect_uri=...&state=...]  -> code
+//	POST /token   (grant_type=authorization_code)                       -> id_token
+//	GET  /jwks                                                          -> signing keys
+type FakeProvider struct {
+	issuer string
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]fakeGrant
+}
+
+type fakeGrant struct {
+	subject string
+	name    string
+	expires time.Time
+}
+
+// NewFakeProvider creates a provider that signs tokens as issuer
+func NewFakeProvider(issuer string) (*FakeProvider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FakeProvider{
+		issuer: issuer,
+		key:    key,
+		codes:  make(map[string]fakeGrant),
+	}, nil
+}
+
+// IssueCode creates a single-use authorization code for a subject
+func (p *FakeProvider) IssueCode(subject, name string) string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	code := hex.EncodeToString(buf)
+
+	p.mu.Lock()
+	p.codes[code] = fakeGrant{subject: subject, name: name, expires: time.Now().Add(5 * time.Minute)}
+	p.mu.Unlock()
+
+	return code
+}
+
+// ServeHTTP routes the provider endpoints
+func (p *FakeProvider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/.well-known/openid-configuration":
+		writeJSON(w, http.StatusOK, map[string]string{
+			"issuer":                 p.issuer,
+			"authorization_endpoint": p.issuer + "/authorize",
+			"token_endpoint":         p.issuer + "/token",
+			"jwks_uri":               p.issuer + "/jwks",
+		})
+	case "/authorize":
+		p.authorize(w, r)
+	case "/token":
+		p.token(w, r)
+	case "/jwks":
+		writeJSON(w, http.StatusOK, map[string][]jsonWebKey{
+			"keys": {encodeJWK(fakeKeyID, &p.key.PublicKey)},
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// authorize logs in as the requested subject without asking for credentials
+func (p *FakeProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	subject := r.URL.Query().Get("sub")
+	if subject == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "sub is required"})
+		return
+	}
+	code := p.IssueCode(subject, r.URL.Query().Get("name"))
+
+	redirectURI := r.URL.Query().Get("redirect_uri")
+	if redirectURI == "" {
+		writeJSON(w, http.StatusOK, map[string]string{"code": code})
+		return
+	}
+
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid redirect_uri"})
+		return
+	}
+	q := target.Query()
+	q.Set("code", code)
+	q.Set("state", r.URL.Query().Get("state"))
+	target.RawQuery = q.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+// token redeems an authorization code for a signed ID token
+func (p *FakeProvider) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "invalid_request"})
+		return
+	}
+	if r.FormValue("client_id") != FakeClientID || r.FormValue("client_secret") != FakeClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	code := r.FormValue("code")
+	p.mu.Lock()
+	grant, ok := p.codes[code]
+	delete(p.codes, code)
+	p.mu.Unlock()
+
+	if !ok || time.Now().After(grant.expires) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, idTokenClaims{
+		Name: grant.name,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    p.issuer,
+			Subject:   grant.subject,
+			Audience:  jwt.ClaimStrings{FakeClientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(10 * time.Minute)),
+		},
+	})
+	token.Header["kid"] = fakeKeyID
+
+	idToken, err := token.SignedString(p.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id_token":   idToken,
+		"token_type": "Bearer",
+		"expires_in": 600,
+	})
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,237 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// jwksCacheTTL controls how long signing keys are cached before refetching
+const jwksCacheTTL = time.Hour
+
+// OIDCConnector verifies OpenID Connect ID tokens signed with RS256.
+// If tokenURL is set the credential is an authorization code that is exchanged
+// for an ID token first; otherwise the credential must be the ID token itself.
+type OIDCConnector struct {
+	name         string
+	issuer       string
+	clientID     string
+	clientSecret string
+	redirectURI  string
+	tokenURL     string
+	jwksURL      string
+	client       *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	keysFetch time.Time
+}
+
+// NewAppleConnector creates a Sign in with Apple connector.
+// The iOS app sends the identityToken it receives from ASAuthorization.
+func NewAppleConnector(clientID string) *OIDCConnector {
+	return &OIDCConnector{
+		name:     "apple",
+		issuer:   "https://appleid.apple.com",
+		clientID: clientID,
+		jwksURL:  "https://appleid.apple.com/auth/keys",
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// NewFakeConnector creates a connector for a FakeProvider served at issuer
+func NewFakeConnector(issuer string) *OIDCConnector {
+	return &OIDCConnector{
+		name:         "fake",
+		issuer:       issuer,
+		clientID:     FakeClientID,
+		clientSecret: FakeClientSecret,
+		tokenURL:     issuer + "/token",
+		jwksURL:      issuer + "/jwks",
+		client:       &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name returns the provider name
+func (o *OIDCConnector) Name() string {
+	return o.name
+}
+
+// idTokenClaims are the ID token claims we rely on
+type idTokenClaims struct {
+	Name    string `json:"name"`
+	Picture string `json:"picture"`
+	jwt.RegisteredClaims
+}
+
+// Exchange verifies the credential and returns the identity in the ID token
+func (o *OIDCConnector) Exchange(ctx context.Context, credential string) (*Identity, error) {
+	idToken := credential
+	if o.tokenURL != "" {
+		var err error
+		if idToken, err = o.exchangeCode(ctx, credential); err != nil {
+			return nil, err
+		}
+	}
+
+	claims := &idTokenClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		return o.key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+
+	if !claims.VerifyIssuer(o.issuer, true) {
+		return nil, errors.New("invalid ID token: wrong issuer")
+	}
+	if !claims.VerifyAudience(o.clientID, true) {
+		return nil, errors.New("invalid ID token: wrong audience")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("invalid ID token: missing subject")
+	}
+
+	return &Identity{
+		Provider:  o.name,
+		Subject:   claims.Subject,
+		Nickname:  claims.Name,
+		AvatarURL: claims.Picture,
+	}, nil
+}
+
+// exchangeCode redeems an authorization code at the token endpoint
+func (o *OIDCConnector) exchangeCode(ctx context.Context, code string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"client_id":     {o.clientID},
+		"client_secret": {o.clientSecret},
+	}
+	if o.redirectURI != "" {
+		form.Set("redirect_uri", o.redirectURI)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken string `json:"id_token"`
+		Error   string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK || body.IDToken == "" {
+		return "", fmt.Errorf("token endpoint error: status %d, %s", resp.StatusCode, body.Error)
+	}
+
+	return body.IDToken, nil
+}
+
+// key returns the provider's signing key with the given key ID, refreshing the JWKS when needed
+func (o *OIDCConnector) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if k, ok := o.keys[kid]; ok && time.Since(o.keysFetch) < jwksCacheTTL {
+		return k, nil
+	}
+
+	keys, err := fetchJWKS(ctx, o.client, o.jwksURL)
+	if err != nil {
+		return nil, err
+	}
+	o.keys = keys
+	o.keysFetch = time.Now()
+
+	k, ok := keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("signing key %q not found", kid)
+	}
+	return k, nil
+}
+
+// jsonWebKey is an RSA key in a JWKS document
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// fetchJWKS downloads and parses the RSA keys of a JWKS endpoint
+func fetchJWKS(ctx context.Context, client *http.Client, jwksURL string) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var doc struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(doc.Keys))
+	for _, k := range doc.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	return keys, nil
+}
+
+// encodeJWK converts an RSA public key to its JWKS representation
+func encodeJWK(kid string, key *rsa.PublicKey) jsonWebKey {
+	return jsonWebKey{
+		Kid: kid,
+		Kty: "RSA",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
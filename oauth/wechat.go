@@ -0,0 +1,112 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const wechatAPIBase = "https://api.weixin.qq.com/sns"
+
+// WeChatConnector implements WeChat open platform login for mobile apps.
+// The credential is the authorization code returned by the WeChat SDK.
+type WeChatConnector struct {
+	appID     string
+	appSecret string
+	baseURL   string
+	client    *http.Client
+}
+
+// NewWeChatConnector creates a WeChat connector
+func NewWeChatConnector(appID, appSecret string) *WeChatConnector {
+	return &WeChatConnector{
+		appID:     appID,
+		appSecret: appSecret,
+		baseURL:   wechatAPIBase,
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name returns the provider name
+func (w *WeChatConnector) Name() string {
+	return "wechat"
+}
+
+// wechatError is embedded in every WeChat API response
+type wechatError struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+type wechatTokenResponse struct {
+	wechatError
+	AccessToken string `json:"access_token"`
+	OpenID      string `json:"openid"`
+	UnionID     string `json:"unionid"`
+}
+
+type wechatUserInfo struct {
+	wechatError
+	Nickname   string `json:"nickname"`
+	HeadImgURL string `json:"headimgurl"`
+}
+
+// Exchange trades the authorization code for the user's identity
+func (w *WeChatConnector) Exchange(ctx context.Context, code string) (*Identity, error) {
+	var token wechatTokenResponse
+	err := w.get(ctx, "/oauth2/access_token", url.Values{
+		"appid":      {w.appID},
+		"secret":     {w.appSecret},
+		"code":       {code},
+		"grant_type": {"authorization_code"},
+	}, &token)
+	if err != nil {
+		return nil, err
+	}
+	if token.ErrCode != 0 {
+		return nil, fmt.Errorf("wechat token error %d: %s", token.ErrCode, token.ErrMsg)
+	}
+
+	// unionid is stable across all apps of the same WeChat developer account
+	subject := token.UnionID
+	if subject == "" {
+		subject = token.OpenID
+	}
+
+	identity := &Identity{Provider: w.Name(), Subject: subject}
+
+	var info wechatUserInfo
+	err = w.get(ctx, "/userinfo", url.Values{
+		"access_token": {token.AccessToken},
+		"openid":       {token.OpenID},
+	}, &info)
+	if err == nil && info.ErrCode == 0 {
+		identity.Nickname = info.Nickname
+		identity.AvatarURL = info.HeadImgURL
+	}
+
+	return identity, nil
+}
+
+// get calls a WeChat API endpoint and decodes the JSON response
+func (w *WeChatConnector) get(ctx context.Context, path string, params url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, w.baseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("wechat API error: status %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
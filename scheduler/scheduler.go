@@ -0,0 +1,84 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Job is a task that runs at a fixed interval
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Scheduler runs registered jobs in the background, each in its own goroutine.
+// A job never overlaps with itself: a run that takes longer than the interval
+// delays the next one.
+type Scheduler struct {
+	jobs   []Job
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates an empty scheduler
+func New() *Scheduler {
+	return &Scheduler{}
+}
+
+// Every registers a job; it must be called before Start
+func (s *Scheduler) Every(name string, interval time.Duration, run func(ctx context.Context) error) {
+	s.jobs = append(s.jobs, Job{Name: name, Interval: interval, Run: run})
+}
+
+// Start runs every registered job once per interval until Stop is called
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go func(job Job) {
+			defer s.wg.Done()
+
+			ticker := time.NewTicker(job.Interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					runJob(ctx, job)
+				}
+			}
+		}(job)
+	}
+
+	fmt.Printf("Scheduler started with %d jobs\n", len(s.jobs))
+}
+
+// Stop cancels running jobs and waits for them to return
+func (s *Scheduler) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+}
+
+// runJob runs a job once, logging errors and recovering from panics so one
+// failing run does not stop the schedule
+func runJob(ctx context.Context, job Job) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("[Scheduler] Job %s panicked: %v\n", job.Name, r)
+		}
+	}()
+
+	if err := job.Run(ctx); err != nil {
+		fmt.Printf("[Scheduler] Job %s failed: %v\n", job.Name, err)
+	}
+}
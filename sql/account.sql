@@ -0,0 +1,13 @@
+-- Account deletion jobs: deletion runs once the grace period has passed
+CREATE TABLE `account_deletion_jobs` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `user_id` bigint(20) NOT NULL COMMENT 'User whose account will be deleted',
+  `status` varchar(20) NOT NULL DEFAULT 'pending' COMMENT 'pending, cancelled, completed, failed',
+  `requested_at` bigint(20) NOT NULL COMMENT 'Request timestamp in milliseconds since epoch',
+  `scheduled_at` bigint(20) NOT NULL COMMENT 'End of grace period in milliseconds since epoch',
+  `completed_at` bigint(20) NOT NULL DEFAULT 0 COMMENT 'Completion timestamp in milliseconds since epoch',
+  `error` varchar(500) NOT NULL DEFAULT '' COMMENT 'Failure reason',
+  PRIMARY KEY (`id`),
+  KEY `idx_user_id` (`user_id`),
+  KEY `idx_status_scheduled` (`status`, `scheduled_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,18 @@
+-- Audit log: append-only record of security relevant account and data-access events
+CREATE TABLE `audit_logs` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `actor_id` bigint(20) NOT NULL DEFAULT 0 COMMENT 'User who performed the action, 0 for anonymous or system',
+  `action` varchar(64) NOT NULL COMMENT 'Event type, e.g. auth.login, user.password_change',
+  `target_user_id` bigint(20) NOT NULL DEFAULT 0 COMMENT 'User the action was performed on, 0 if unknown',
+  `detail` text COMMENT 'JSON object with action specific fields',
+  `ip` varchar(64) NOT NULL DEFAULT '' COMMENT 'Client IP',
+  `user_agent` varchar(255) NOT NULL DEFAULT '' COMMENT 'Client User-Agent',
+  `request_id` varchar(64) NOT NULL DEFAULT '' COMMENT 'Request ID, also returned in the X-Request-ID header',
+  `created_at` bigint(20) NOT NULL COMMENT 'Creation timestamp in milliseconds since epoch',
+  PRIMARY KEY (`id`),
+  KEY `idx_actor_id` (`actor_id`),
+  KEY `idx_action` (`action`),
+  KEY `idx_target_user_id` (`target_user_id`),
+  KEY `idx_request_id` (`request_id`),
+  KEY `idx_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
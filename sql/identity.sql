@@ -0,0 +1,12 @@
+-- External identities (WeChat, Apple, ...) linked to user profiles
+CREATE TABLE `user_identities` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `user_id` bigint(20) NOT NULL COMMENT 'Linked user profile',
+  `provider` varchar(20) NOT NULL COMMENT 'wechat, apple, fake',
+  `subject` varchar(255) NOT NULL COMMENT 'User ID at the provider',
+  `nickname` varchar(100) NOT NULL DEFAULT '' COMMENT 'Nickname reported by the provider',
+  `created_at` bigint(20) NOT NULL COMMENT 'Creation timestamp in milliseconds since epoch',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_provider_subject` (`provider`, `subject`),
+  UNIQUE KEY `uk_user_provider` (`user_id`, `provider`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
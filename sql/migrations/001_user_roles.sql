@@ -0,0 +1,2 @@
+-- Add roles to user_profiles on existing installations
+ALTER TABLE user_profiles ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'user' COMMENT 'user, moderator or admin' AFTER password;
@@ -0,0 +1,6 @@
+-- Add the structured daily check-in to notes on existing installations
+ALTER TABLE notes
+    ADD COLUMN patient_mood TINYINT NULL COMMENT 'Patient mood 1-10, NULL if not logged' AFTER content,
+    ADD COLUMN caregiver_mood TINYINT NULL COMMENT 'Caregiver mood 1-10, NULL if not logged' AFTER patient_mood,
+    ADD COLUMN sleep_hours DECIMAL(4,1) NULL COMMENT 'Patient sleep in hours, NULL if not logged' AFTER caregiver_mood,
+    ADD COLUMN emotion_tags VARCHAR(500) NULL COMMENT 'JSON array of emotion tags' AFTER sleep_hours;
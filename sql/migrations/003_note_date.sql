@@ -0,0 +1,25 @@
+-- Convert notes.note_date from a "YYYY.M.D" VARCHAR to a DATE column.
+-- The API keeps accepting and returning "YYYY.M.D", so clients need no changes.
+
+-- 1. Add the new column and fill it from the old text
+ALTER TABLE notes ADD COLUMN note_day DATE NULL AFTER note_date;
+UPDATE notes SET note_day = STR_TO_DATE(note_date, '%Y.%c.%e') WHERE note_day IS NULL;
+
+-- 2. Check before continuing; both queries must return no rows.
+--    Rows that could not be parsed:
+--      SELECT note_id, user_id, note_date FROM notes WHERE note_day IS NULL;
+--    Users with two notes on the same day (e.g. "2024.1.5" and "2024.01.05"):
+--      SELECT user_id, note_day, COUNT(*) FROM notes GROUP BY user_id, note_day HAVING COUNT(*) > 1;
+--    Fix or merge those rows by hand; nothing is deleted automatically.
+
+-- 3. Swap the columns. The old text is kept as note_date_legacy until the migration is verified.
+ALTER TABLE notes
+    DROP INDEX user_id,
+    DROP INDEX idx_notes_user_date,
+    CHANGE note_date note_date_legacy VARCHAR(20) NULL,
+    CHANGE note_day note_date DATE NOT NULL,
+    ADD UNIQUE KEY uk_notes_user_date (user_id, note_date),
+    ADD INDEX idx_notes_user_date (user_id, note_date);
+
+-- 4. Once verified:
+--    ALTER TABLE notes DROP COLUMN note_date_legacy;
@@ -0,0 +1,2 @@
+-- Store each user's timezone so "today" follows the user rather than the server
+ALTER TABLE user_profiles ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT 'Asia/Shanghai' COMMENT 'IANA timezone used to decide which day "today" is' AFTER role;
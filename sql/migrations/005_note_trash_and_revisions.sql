@@ -0,0 +1,21 @@
+-- Soft delete for notes (30-day trash) and revision history
+
+ALTER TABLE notes
+    ADD COLUMN deleted_at bigint unsigned NOT NULL DEFAULT 0 COMMENT 'Moved to the trash at (unix seconds), 0 while live' AFTER updated_at,
+    DROP INDEX uk_notes_user_date,
+    ADD UNIQUE KEY uk_notes_user_date_deleted (user_id, note_date, deleted_at);
+
+CREATE TABLE note_revisions (
+    revision_id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    note_id INT NOT NULL,
+    user_id INT NOT NULL,
+    content VARCHAR(1000) NOT NULL,
+    patient_mood TINYINT NULL,
+    caregiver_mood TINYINT NULL,
+    sleep_hours DECIMAL(4,1) NULL,
+    emotion_tags VARCHAR(500) NULL,
+    note_updated_at bigint unsigned NOT NULL COMMENT 'When this version was written',
+    created_at bigint unsigned NOT NULL COMMENT 'When it was replaced',
+    INDEX idx_note_revisions_note (note_id, revision_id),
+    INDEX idx_note_revisions_user (user_id)
+);
@@ -0,0 +1,30 @@
+-- Offline sync: note versions, client IDs and a per-user change feed
+
+ALTER TABLE notes
+    ADD COLUMN client_id VARCHAR(64) NULL COMMENT 'ID generated by the app when the note was created offline' AFTER deleted_at,
+    ADD COLUMN version INT NOT NULL DEFAULT 1 COMMENT 'Incremented on every change' AFTER client_id,
+    ADD COLUMN sync_seq BIGINT NOT NULL DEFAULT 0 COMMENT 'Position in the user''s change feed' AFTER version,
+    ADD UNIQUE KEY uk_notes_user_client (user_id, client_id),
+    ADD INDEX idx_notes_user_sync (user_id, sync_seq);
+
+CREATE TABLE note_sync_state (
+    user_id INT PRIMARY KEY,
+    seq BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE TABLE note_tombstones (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    note_id INT NOT NULL,
+    client_id VARCHAR(64) NULL,
+    sync_seq BIGINT NOT NULL,
+    deleted_at bigint unsigned NOT NULL,
+    INDEX idx_note_tombstones_user_sync (user_id, sync_seq)
+);
+
+-- Existing notes enter the feed in the order they were created; note_id is
+-- unique, so every user's notes get distinct positions
+UPDATE notes SET sync_seq = note_id;
+
+INSERT INTO note_sync_state (user_id, seq)
+SELECT user_id, MAX(note_id) FROM notes GROUP BY user_id;
@@ -0,0 +1,28 @@
+-- Journaling reminders and push notification tokens, see sql/reminder.sql
+
+-- Daily journaling reminder settings, one row per user who set them up
+CREATE TABLE `reminder_settings` (
+  `user_id` bigint(20) NOT NULL COMMENT 'User the setting belongs to',
+  `enabled` tinyint(1) NOT NULL DEFAULT 0 COMMENT '1 while reminders are on',
+  `time_of_day` char(5) NOT NULL COMMENT 'Local reminder time as HH:MM',
+  `timezone` varchar(64) NOT NULL DEFAULT '' COMMENT 'IANA timezone, empty to follow user_profiles.timezone',
+  `weekdays` varchar(32) NOT NULL COMMENT 'JSON array of weekdays, 0 = Sunday',
+  `last_sent_at` bigint(20) NOT NULL DEFAULT 0 COMMENT 'Last reminder enqueued, milliseconds since epoch',
+  `created_at` bigint(20) NOT NULL COMMENT 'Creation timestamp in milliseconds since epoch',
+  `updated_at` bigint(20) NOT NULL COMMENT 'Last update timestamp in milliseconds since epoch',
+  PRIMARY KEY (`user_id`),
+  KEY `idx_enabled` (`enabled`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Push notification tokens (APNs device tokens, FCM registration tokens)
+CREATE TABLE `push_devices` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `user_id` bigint(20) NOT NULL COMMENT 'User currently signed in on the device',
+  `platform` varchar(16) NOT NULL COMMENT 'ios or android',
+  `token` varchar(255) NOT NULL COMMENT 'Token issued by the push service',
+  `created_at` bigint(20) NOT NULL COMMENT 'Creation timestamp in milliseconds since epoch',
+  `updated_at` bigint(20) NOT NULL COMMENT 'Last registration in milliseconds since epoch',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_token` (`token`),
+  KEY `idx_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,27 @@
+-- Field-level encryption of note content, chat messages and illness cause.
+-- Encrypted values are base64 and longer than the plaintext, so the columns
+-- are widened and the old limits are enforced by the API instead. Existing
+-- rows keep key_version 0 (plaintext) until the field-reencryption job
+-- rewrites them with the current key.
+--
+-- Encrypted columns cannot be searched, sorted or filtered in SQL.
+
+ALTER TABLE notes
+    MODIFY content TEXT NOT NULL,
+    ADD COLUMN key_version INT NOT NULL DEFAULT 0 COMMENT 'Master key content was encrypted with, 0 for plaintext' AFTER prompt_id,
+    ADD INDEX idx_notes_key_version (key_version);
+
+ALTER TABLE note_revisions
+    MODIFY content TEXT NOT NULL,
+    ADD COLUMN key_version INT NOT NULL DEFAULT 0 AFTER content,
+    ADD INDEX idx_note_revisions_key_version (key_version);
+
+ALTER TABLE messages
+    MODIFY content TEXT NOT NULL,
+    ADD COLUMN key_version INT NOT NULL DEFAULT 0 AFTER content,
+    ADD INDEX idx_messages_key_version (key_version);
+
+ALTER TABLE user_profiles
+    MODIFY illness_cause VARCHAR(1024) COMMENT '患病主要原因 (Main cause of illness), encrypted',
+    ADD COLUMN key_version INT NOT NULL DEFAULT 0 COMMENT 'Master key illness_cause was encrypted with, 0 for plaintext',
+    ADD INDEX idx_user_profiles_key_version (key_version);
@@ -0,0 +1,16 @@
+-- Photo attachments and longer notes
+
+-- Notes can now hold 10000 characters; encrypted, that no longer fits in TEXT
+ALTER TABLE notes MODIFY content MEDIUMTEXT NOT NULL;
+ALTER TABLE note_revisions MODIFY content MEDIUMTEXT NOT NULL;
+
+CREATE TABLE note_attachments (
+    attachment_id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    note_id INT NOT NULL,
+    user_id INT NOT NULL,
+    file_path VARCHAR(255) NOT NULL, -- Relative to the uploads directory, e.g. notes/12-<uuid>.jpg
+    display_order INT NOT NULL DEFAULT 0,
+    created_at bigint unsigned NOT NULL,
+    INDEX idx_note_attachments_note (note_id, display_order),
+    INDEX idx_note_attachments_user (user_id)
+);
@@ -0,0 +1,18 @@
+-- Sharing notes with another user or through a read-only link
+
+CREATE TABLE note_shares (
+    share_id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    owner_id INT NOT NULL,
+    recipient_id INT NULL, -- NULL for link shares
+    token_hash CHAR(64) NULL, -- SHA-256 of the link token, link shares only
+    label VARCHAR(50) NOT NULL DEFAULT '',
+    note_ids JSON NULL, -- Shared note IDs when no date range is given
+    start_date DATE NULL,
+    end_date DATE NULL,
+    expires_at bigint unsigned NOT NULL DEFAULT 0,
+    revoked_at bigint unsigned NOT NULL DEFAULT 0,
+    created_at bigint unsigned NOT NULL,
+    UNIQUE INDEX idx_note_shares_token (token_hash),
+    INDEX idx_note_shares_owner (owner_id),
+    INDEX idx_note_shares_recipient (recipient_id, owner_id)
+);
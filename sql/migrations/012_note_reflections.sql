@@ -0,0 +1,18 @@
+-- AI reflections on notes
+
+CREATE TABLE note_reflections (
+    reflection_id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    note_id INT NOT NULL,
+    user_id INT NOT NULL,
+    content TEXT NOT NULL, -- Encrypted, see key_version
+    key_version INT NOT NULL DEFAULT 0,
+    provider VARCHAR(20) NOT NULL DEFAULT '', -- Empty for a crisis response
+    model VARCHAR(64) NOT NULL DEFAULT '',
+    crisis TINYINT(1) NOT NULL DEFAULT 0,
+    included_mood TINYINT(1) NOT NULL DEFAULT 0,
+    note_version INT NOT NULL DEFAULT 0,
+    created_at bigint unsigned NOT NULL,
+    UNIQUE INDEX idx_note_reflections_note (note_id),
+    INDEX idx_note_reflections_user (user_id),
+    INDEX idx_note_reflections_key_version (key_version)
+);
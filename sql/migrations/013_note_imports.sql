@@ -0,0 +1,24 @@
+-- Background imports of journal entries from other apps
+
+CREATE TABLE note_import_jobs (
+    job_id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    status VARCHAR(20) NOT NULL, -- pending, running, completed or failed
+    file_name VARCHAR(255) NOT NULL, -- Name of the uploaded file
+    file_path VARCHAR(255) NOT NULL, -- Relative to the uploads directory, removed when the job ends
+    on_conflict VARCHAR(10) NOT NULL, -- skip or merge, for dates that already have a note
+    total INT NOT NULL DEFAULT 0,
+    processed INT NOT NULL DEFAULT 0,
+    created INT NOT NULL DEFAULT 0,
+    merged INT NOT NULL DEFAULT 0,
+    skipped INT NOT NULL DEFAULT 0,
+    failed INT NOT NULL DEFAULT 0,
+    errors JSON NULL, -- The first failed entries, with their file position and reason
+    message VARCHAR(500) NOT NULL DEFAULT '', -- Why the whole import failed
+    created_at bigint unsigned NOT NULL,
+    started_at bigint unsigned NOT NULL DEFAULT 0,
+    updated_at bigint unsigned NOT NULL, -- Last progress; running jobs without progress for a while are resumed
+    finished_at bigint unsigned NOT NULL DEFAULT 0,
+    INDEX idx_note_import_jobs_user (user_id, job_id),
+    INDEX idx_note_import_jobs_status (status, job_id)
+);
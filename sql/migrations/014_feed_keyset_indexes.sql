@@ -0,0 +1,9 @@
+-- Indexes for cursor pagination of posts and comments, ordered by (created_at, id)
+
+ALTER TABLE `posts`
+    DROP INDEX `idx_created_at`,
+    ADD KEY `idx_created_at_id` (`created_at`, `id`),
+    ADD KEY `idx_user_created_at_id` (`user_id`, `created_at`, `id`);
+
+ALTER TABLE `comments`
+    ADD KEY `idx_post_level_created_at_id` (`post_id`, `level`, `created_at`, `id`);
@@ -0,0 +1,11 @@
+-- Anonymous posts and comments, shown under a pseudonym derived from a per-post seed
+
+ALTER TABLE `posts`
+    ADD COLUMN `anonymous` tinyint(1) NOT NULL DEFAULT 0 COMMENT 'Shown under a pseudonym, user_id is kept for moderation' AFTER `comment_count`,
+    ADD COLUMN `pseudonym_seed` varchar(64) NOT NULL DEFAULT '' COMMENT 'Secret the pseudonyms of anonymous authors in the post are derived from' AFTER `anonymous`;
+
+-- Existing posts get a random seed so anonymous comments on them work too
+UPDATE `posts` SET `pseudonym_seed` = SHA2(CONCAT(UUID(), RAND(), `id`), 256) WHERE `pseudonym_seed` = '';
+
+ALTER TABLE `comments`
+    ADD COLUMN `anonymous` tinyint(1) NOT NULL DEFAULT 0 COMMENT 'Shown under the author''s pseudonym in the post' AFTER `level`;
@@ -0,0 +1,50 @@
+-- Reports on posts and comments, the moderation queue and its decisions
+
+ALTER TABLE `posts`
+    ADD COLUMN `hidden` tinyint(1) NOT NULL DEFAULT 0 COMMENT 'Hidden from listings after reports or by a moderator' AFTER `pseudonym_seed`;
+
+ALTER TABLE `comments`
+    ADD COLUMN `hidden` tinyint(1) NOT NULL DEFAULT 0 COMMENT 'Hidden with its replies after reports or by a moderator' AFTER `anonymous`;
+
+ALTER TABLE user_profiles ADD COLUMN community_banned_until BIGINT NOT NULL DEFAULT 0 COMMENT 'Posting and commenting are blocked until then, milliseconds since epoch' AFTER timezone;
+
+CREATE TABLE `reports` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `target_type` varchar(16) NOT NULL COMMENT 'post or comment',
+  `target_id` bigint(20) NOT NULL COMMENT 'Reported post or comment',
+  `target_user_id` bigint(20) NOT NULL COMMENT 'Author of the reported content',
+  `reporter_id` bigint(20) NOT NULL COMMENT 'User who filed the report',
+  `reason` varchar(32) NOT NULL COMMENT 'Reason code, see dao.ReportReason',
+  `detail` text NOT NULL COMMENT 'Free text from the reporter',
+  `content_snapshot` text NOT NULL COMMENT 'Content when it was reported',
+  `status` varchar(16) NOT NULL DEFAULT 'open' COMMENT 'open, claimed or resolved',
+  `claimed_by` bigint(20) NOT NULL DEFAULT 0 COMMENT 'Moderator working on the report',
+  `claimed_at` bigint(20) NOT NULL DEFAULT 0 COMMENT 'Claim timestamp in milliseconds since epoch',
+  `resolution` varchar(16) NOT NULL DEFAULT '' COMMENT 'Moderation action the report was resolved with',
+  `resolved_by` bigint(20) NOT NULL DEFAULT 0 COMMENT 'Moderator who resolved the report',
+  `resolved_at` bigint(20) NOT NULL DEFAULT 0 COMMENT 'Resolution timestamp in milliseconds since epoch',
+  `created_at` bigint(20) NOT NULL COMMENT 'Creation timestamp in milliseconds since epoch',
+  `updated_at` bigint(20) NOT NULL COMMENT 'Last update timestamp in milliseconds since epoch',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_target_reporter` (`target_type`, `target_id`, `reporter_id`),
+  KEY `idx_status_created_at` (`status`, `created_at`),
+  KEY `idx_reporter_id` (`reporter_id`),
+  KEY `idx_target_user_id` (`target_user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE `moderation_decisions` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `report_id` bigint(20) NOT NULL COMMENT 'Report the decision resolved or that triggered it',
+  `target_type` varchar(16) NOT NULL COMMENT 'post or comment',
+  `target_id` bigint(20) NOT NULL COMMENT 'Moderated post or comment',
+  `target_user_id` bigint(20) NOT NULL COMMENT 'Author of the moderated content',
+  `moderator_id` bigint(20) NOT NULL DEFAULT 0 COMMENT 'Moderator who decided, 0 for the system',
+  `action` varchar(16) NOT NULL COMMENT 'dismiss, hide, delete, warn, ban or auto_hide',
+  `note` text NOT NULL COMMENT 'Moderator note, shown to the author with warnings and bans',
+  `ban_until` bigint(20) NOT NULL DEFAULT 0 COMMENT 'End of a ban in milliseconds since epoch',
+  `reports` int(11) NOT NULL DEFAULT 0 COMMENT 'Number of reports the decision resolved',
+  `created_at` bigint(20) NOT NULL COMMENT 'Creation timestamp in milliseconds since epoch',
+  PRIMARY KEY (`id`),
+  KEY `idx_target_user_id` (`target_user_id`),
+  KEY `idx_target` (`target_type`, `target_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
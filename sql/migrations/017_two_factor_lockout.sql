@@ -0,0 +1,6 @@
+-- Lock two-factor code checks after repeated failures and stop codes from being replayed
+
+ALTER TABLE `user_two_factor`
+    ADD COLUMN `failed_attempts` int(11) NOT NULL DEFAULT 0 COMMENT 'Wrong codes since the last accepted one' AFTER `enabled_at`,
+    ADD COLUMN `locked_until` bigint(20) NOT NULL DEFAULT 0 COMMENT 'Codes are refused until then, milliseconds since epoch' AFTER `failed_attempts`,
+    ADD COLUMN `last_used_step` bigint(20) NOT NULL DEFAULT 0 COMMENT 'TOTP time step of the last accepted code, earlier steps are refused' AFTER `locked_until`;
@@ -0,0 +1,7 @@
+-- Record when a session's token expires so expired sessions drop out of the device list
+
+ALTER TABLE `user_sessions`
+    ADD COLUMN `expires_at` bigint(20) NOT NULL DEFAULT 0 COMMENT 'Token expiry in milliseconds since epoch' AFTER `last_seen_at`;
+
+-- Tokens issued so far were valid for 24 hours
+UPDATE `user_sessions` SET `expires_at` = `created_at` + 86400000;
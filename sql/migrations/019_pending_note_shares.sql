@@ -0,0 +1,9 @@
+-- Address user shares to a mobile number, so numbers without an account can receive pending shares
+
+ALTER TABLE note_shares
+    ADD COLUMN recipient_mobile VARCHAR(20) NOT NULL DEFAULT '' AFTER recipient_id, -- Empty for link shares
+    ADD INDEX idx_note_shares_recipient_mobile (recipient_mobile);
+
+UPDATE note_shares s
+    JOIN user_profiles p ON p.id = s.recipient_id
+    SET s.recipient_mobile = p.mobile_number;
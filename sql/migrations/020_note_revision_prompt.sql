@@ -0,0 +1,9 @@
+-- Keep the journaling prompt in note revisions so restoring a revision restores it too
+
+ALTER TABLE note_revisions
+    ADD COLUMN prompt_id INT NULL COMMENT 'Journaling prompt the version answered' AFTER key_version;
+
+-- Earlier revisions did not record the prompt; assume the note's current one
+UPDATE note_revisions r
+    JOIN notes n ON n.note_id = r.note_id
+    SET r.prompt_id = n.prompt_id;
@@ -19,13 +19,15 @@ CREATE TABLE messages (
     sender_id     BIGINT NOT NULL, 
     receiver_id   BIGINT NOT NULL, 
     chat_id       VARCHAR(50) NOT NULL,
-    content       VARCHAR(2000) NOT NULL,  -- Replacing TEXT with VARCHAR(2000)
+    content       TEXT NOT NULL,           -- Encrypted, see key_version; the API limits user messages to 2000 characters
+    key_version   INT NOT NULL DEFAULT 0,  -- Master key content was encrypted with, 0 for plaintext
     msg_type      TINYINT NOT NULL DEFAULT 1, -- Using TINYINT instead of ENUM (1=text, 2=image, 3=video, etc.)
     status        TINYINT NOT NULL DEFAULT 0, -- Using TINYINT instead of ENUM (0=sent, 1=delivered, 2=read)
     created_time BIGINT NOT NULL,
     updated_time BIGINT NOT NULL,
     INDEX idx_sender_receiver (sender_id, receiver_id),
-    INDEX idx_chat (chat_id)
+    INDEX idx_chat (chat_id),
+    INDEX idx_messages_key_version (key_version)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 
 
@@ -2,13 +2,143 @@
 CREATE TABLE notes (
     note_id INT AUTO_INCREMENT PRIMARY KEY,
     user_id INT NOT NULL,
-    note_date VARCHAR(20) NOT NULL, -- Using VARCHAR for date storage (e.g., "2023.1.18" format)
-    content VARCHAR(1000) NOT NULL, -- Using VARCHAR instead of TEXT
+    note_date DATE NOT NULL, -- Calendar day; the API reads and writes it as "YYYY.M.D" (e.g., "2023.1.18")
+    content MEDIUMTEXT NOT NULL, -- Encrypted, see key_version; the API limits the plaintext to 10000 characters
+    patient_mood TINYINT NULL, -- Patient mood 1-10, NULL if not logged
+    caregiver_mood TINYINT NULL, -- Caregiver mood 1-10, NULL if not logged
+    sleep_hours DECIMAL(4,1) NULL, -- Patient sleep in hours
+    emotion_tags VARCHAR(500) NULL, -- JSON array of emotion tags, e.g. ["calm","tired"]
     created_at bigint unsigned DEFAULT (unix_timestamp()),
     updated_at bigint unsigned DEFAULT (unix_timestamp()),
-    -- Ensure a user can only have one note per date
-    UNIQUE KEY (user_id, note_date),
+    deleted_at bigint unsigned NOT NULL DEFAULT 0, -- Moved to the trash at (unix seconds), 0 while live
+    prompt_id INT NULL, -- Journaling prompt the note answers, see sql/prompt.sql
+    key_version INT NOT NULL DEFAULT 0, -- Master key content was encrypted with, 0 for plaintext
+    client_id VARCHAR(64) NULL, -- ID generated by the app when the note was created offline
+    version INT NOT NULL DEFAULT 1, -- Incremented on every change, clients send it back to detect conflicts
+    sync_seq BIGINT NOT NULL DEFAULT 0, -- Position in the user's change feed, see note_sync_state
+    -- Ensure a user can only have one live note per date; trashed notes keep their deletion time
+    UNIQUE KEY uk_notes_user_date_deleted (user_id, note_date, deleted_at),
+    UNIQUE KEY uk_notes_user_client (user_id, client_id),
+    INDEX idx_notes_user_sync (user_id, sync_seq),
+    INDEX idx_notes_key_version (key_version),
     -- Add index to improve query performance
     INDEX idx_notes_user_date (user_id, note_date)
 );
 
+
+-- Previous versions of notes, saved on every update
+CREATE TABLE note_revisions (
+    revision_id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    note_id INT NOT NULL,
+    user_id INT NOT NULL,
+    content MEDIUMTEXT NOT NULL, -- Copied from notes.content as stored, encrypted with the same key
+    key_version INT NOT NULL DEFAULT 0,
+    prompt_id INT NULL,
+    patient_mood TINYINT NULL,
+    caregiver_mood TINYINT NULL,
+    sleep_hours DECIMAL(4,1) NULL,
+    emotion_tags VARCHAR(500) NULL,
+    note_updated_at bigint unsigned NOT NULL, -- When this version was written
+    created_at bigint unsigned NOT NULL, -- When it was replaced
+    INDEX idx_note_revisions_note (note_id, revision_id),
+    INDEX idx_note_revisions_user (user_id),
+    INDEX idx_note_revisions_key_version (key_version)
+);
+
+
+-- Photos attached to notes; files live under uploads/notes and are removed when the note is purged
+CREATE TABLE note_attachments (
+    attachment_id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    note_id INT NOT NULL,
+    user_id INT NOT NULL,
+    file_path VARCHAR(255) NOT NULL, -- Relative to the uploads directory, e.g. notes/12-<uuid>.jpg
+    display_order INT NOT NULL DEFAULT 0,
+    created_at bigint unsigned NOT NULL,
+    INDEX idx_note_attachments_note (note_id, display_order),
+    INDEX idx_note_attachments_user (user_id)
+);
+
+
+-- Notes shared with another user or through a read-only link
+CREATE TABLE note_shares (
+    share_id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    owner_id INT NOT NULL,
+    recipient_id INT NULL, -- NULL for link shares and shares pending registration of the number
+    recipient_mobile VARCHAR(20) NOT NULL DEFAULT '', -- Empty for link shares
+    token_hash CHAR(64) NULL, -- SHA-256 of the link token, link shares only
+    label VARCHAR(50) NOT NULL DEFAULT '',
+    note_ids JSON NULL, -- Shared note IDs when no date range is given
+    start_date DATE NULL,
+    end_date DATE NULL,
+    expires_at bigint unsigned NOT NULL DEFAULT 0,
+    revoked_at bigint unsigned NOT NULL DEFAULT 0,
+    created_at bigint unsigned NOT NULL,
+    UNIQUE INDEX idx_note_shares_token (token_hash),
+    INDEX idx_note_shares_owner (owner_id),
+    INDEX idx_note_shares_recipient (recipient_id, owner_id),
+    INDEX idx_note_shares_recipient_mobile (recipient_mobile)
+);
+
+
+-- Latest AI reflection on a note
+CREATE TABLE note_reflections (
+    reflection_id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    note_id INT NOT NULL,
+    user_id INT NOT NULL,
+    content TEXT NOT NULL, -- Encrypted, see key_version
+    key_version INT NOT NULL DEFAULT 0,
+    provider VARCHAR(20) NOT NULL DEFAULT '', -- Empty for a crisis response
+    model VARCHAR(64) NOT NULL DEFAULT '',
+    crisis TINYINT(1) NOT NULL DEFAULT 0,
+    included_mood TINYINT(1) NOT NULL DEFAULT 0,
+    note_version INT NOT NULL DEFAULT 0,
+    created_at bigint unsigned NOT NULL,
+    UNIQUE INDEX idx_note_reflections_note (note_id),
+    INDEX idx_note_reflections_user (user_id),
+    INDEX idx_note_reflections_key_version (key_version)
+);
+
+
+-- Imports of journal entries from other apps, processed in the background
+CREATE TABLE note_import_jobs (
+    job_id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    status VARCHAR(20) NOT NULL, -- pending, running, completed or failed
+    file_name VARCHAR(255) NOT NULL, -- Name of the uploaded file
+    file_path VARCHAR(255) NOT NULL, -- Relative to the uploads directory, removed when the job ends
+    on_conflict VARCHAR(10) NOT NULL, -- skip or merge, for dates that already have a note
+    total INT NOT NULL DEFAULT 0,
+    processed INT NOT NULL DEFAULT 0,
+    created INT NOT NULL DEFAULT 0,
+    merged INT NOT NULL DEFAULT 0,
+    skipped INT NOT NULL DEFAULT 0,
+    failed INT NOT NULL DEFAULT 0,
+    errors JSON NULL, -- The first failed entries, with their file position and reason
+    message VARCHAR(500) NOT NULL DEFAULT '', -- Why the whole import failed
+    created_at bigint unsigned NOT NULL,
+    started_at bigint unsigned NOT NULL DEFAULT 0,
+    updated_at bigint unsigned NOT NULL, -- Last progress; running jobs without progress for a while are resumed
+    finished_at bigint unsigned NOT NULL DEFAULT 0,
+    INDEX idx_note_import_jobs_user (user_id, job_id),
+    INDEX idx_note_import_jobs_status (status, job_id)
+);
+
+
+-- Per-user change counter; every note change takes the next value and the
+-- sync API hands out the last value seen as the client's cursor
+CREATE TABLE note_sync_state (
+    user_id INT PRIMARY KEY,
+    seq BIGINT NOT NULL DEFAULT 0
+);
+
+
+-- Notes permanently deleted from the trash, so offline clients learn about them
+CREATE TABLE note_tombstones (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    note_id INT NOT NULL,
+    client_id VARCHAR(64) NULL,
+    sync_seq BIGINT NOT NULL,
+    deleted_at bigint unsigned NOT NULL,
+    INDEX idx_note_tombstones_user_sync (user_id, sync_seq)
+);
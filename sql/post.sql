@@ -6,11 +6,15 @@ CREATE TABLE `posts` (
   `view_count` int(11) NOT NULL DEFAULT 0 COMMENT 'Number of views',
   `like_count` int(11) NOT NULL DEFAULT 0 COMMENT 'Number of likes',
   `comment_count` int(11) NOT NULL DEFAULT 0 COMMENT 'Number of comments',
+  `anonymous` tinyint(1) NOT NULL DEFAULT 0 COMMENT 'Shown under a pseudonym, user_id is kept for moderation',
+  `pseudonym_seed` varchar(64) NOT NULL DEFAULT '' COMMENT 'Secret the pseudonyms of anonymous authors in the post are derived from',
+  `hidden` tinyint(1) NOT NULL DEFAULT 0 COMMENT 'Hidden from listings after reports or by a moderator',
   `created_at` bigint(20) NOT NULL COMMENT 'Creation timestamp in milliseconds since epoch',
   `updated_at` bigint(20) NOT NULL COMMENT 'Last update timestamp in milliseconds since epoch',
   PRIMARY KEY (`id`),
   KEY `idx_user_id` (`user_id`),
-  KEY `idx_created_at` (`created_at`)
+  KEY `idx_created_at_id` (`created_at`, `id`) COMMENT 'Feed order, see dao.PageCursor',
+  KEY `idx_user_created_at_id` (`user_id`, `created_at`, `id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
 
 -- Post Image table
@@ -34,13 +38,16 @@ CREATE TABLE `comments` (
   `like_count` int(11) NOT NULL DEFAULT 0 COMMENT 'Number of likes',
   `reply_count` int(11) NOT NULL DEFAULT 0 COMMENT 'Number of replies',
   `level` int(11) NOT NULL DEFAULT 0 COMMENT 'Nesting level (0 for top-level comments)',
+  `anonymous` tinyint(1) NOT NULL DEFAULT 0 COMMENT 'Shown under the author''s pseudonym in the post',
+  `hidden` tinyint(1) NOT NULL DEFAULT 0 COMMENT 'Hidden with its replies after reports or by a moderator',
   `created_at` bigint(20) NOT NULL COMMENT 'Creation timestamp in milliseconds since epoch',
   `updated_at` bigint(20) NOT NULL COMMENT 'Last update timestamp in milliseconds since epoch',
   PRIMARY KEY (`id`),
   KEY `idx_post_id` (`post_id`),
   KEY `idx_user_id` (`user_id`),
   KEY `idx_parent_id` (`parent_id`),
-  KEY `idx_created_at` (`created_at`)
+  KEY `idx_created_at` (`created_at`),
+  KEY `idx_post_level_created_at_id` (`post_id`, `level`, `created_at`, `id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
 
 -- Like table for posts
@@ -64,3 +71,46 @@ CREATE TABLE `comment_likes` (
   UNIQUE KEY `uk_comment_user` (`comment_id`,`user_id`),
   KEY `idx_user_id` (`user_id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Reports on posts and comments
+CREATE TABLE `reports` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `target_type` varchar(16) NOT NULL COMMENT 'post or comment',
+  `target_id` bigint(20) NOT NULL COMMENT 'Reported post or comment',
+  `target_user_id` bigint(20) NOT NULL COMMENT 'Author of the reported content',
+  `reporter_id` bigint(20) NOT NULL COMMENT 'User who filed the report',
+  `reason` varchar(32) NOT NULL COMMENT 'Reason code, see dao.ReportReason',
+  `detail` text NOT NULL COMMENT 'Free text from the reporter',
+  `content_snapshot` text NOT NULL COMMENT 'Content when it was reported',
+  `status` varchar(16) NOT NULL DEFAULT 'open' COMMENT 'open, claimed or resolved',
+  `claimed_by` bigint(20) NOT NULL DEFAULT 0 COMMENT 'Moderator working on the report',
+  `claimed_at` bigint(20) NOT NULL DEFAULT 0 COMMENT 'Claim timestamp in milliseconds since epoch',
+  `resolution` varchar(16) NOT NULL DEFAULT '' COMMENT 'Moderation action the report was resolved with',
+  `resolved_by` bigint(20) NOT NULL DEFAULT 0 COMMENT 'Moderator who resolved the report',
+  `resolved_at` bigint(20) NOT NULL DEFAULT 0 COMMENT 'Resolution timestamp in milliseconds since epoch',
+  `created_at` bigint(20) NOT NULL COMMENT 'Creation timestamp in milliseconds since epoch',
+  `updated_at` bigint(20) NOT NULL COMMENT 'Last update timestamp in milliseconds since epoch',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_target_reporter` (`target_type`, `target_id`, `reporter_id`),
+  KEY `idx_status_created_at` (`status`, `created_at`),
+  KEY `idx_reporter_id` (`reporter_id`),
+  KEY `idx_target_user_id` (`target_user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Moderation decisions, only ever inserted
+CREATE TABLE `moderation_decisions` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `report_id` bigint(20) NOT NULL COMMENT 'Report the decision resolved or that triggered it',
+  `target_type` varchar(16) NOT NULL COMMENT 'post or comment',
+  `target_id` bigint(20) NOT NULL COMMENT 'Moderated post or comment',
+  `target_user_id` bigint(20) NOT NULL COMMENT 'Author of the moderated content',
+  `moderator_id` bigint(20) NOT NULL DEFAULT 0 COMMENT 'Moderator who decided, 0 for the system',
+  `action` varchar(16) NOT NULL COMMENT 'dismiss, hide, delete, warn, ban or auto_hide',
+  `note` text NOT NULL COMMENT 'Moderator note, shown to the author with warnings and bans',
+  `ban_until` bigint(20) NOT NULL DEFAULT 0 COMMENT 'End of a ban in milliseconds since epoch',
+  `reports` int(11) NOT NULL DEFAULT 0 COMMENT 'Number of reports the decision resolved',
+  `created_at` bigint(20) NOT NULL COMMENT 'Creation timestamp in milliseconds since epoch',
+  PRIMARY KEY (`id`),
+  KEY `idx_target_user_id` (`target_user_id`),
+  KEY `idx_target` (`target_type`, `target_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,47 @@
+-- Journaling prompt library
+CREATE TABLE journal_prompts (
+    prompt_id INT AUTO_INCREMENT PRIMARY KEY,
+    category VARCHAR(32) NOT NULL, -- gratitude, coping, reflection, self_care or connection
+    locale VARCHAR(16) NOT NULL, -- BCP 47 tag, e.g. zh-CN or en
+    text VARCHAR(255) NOT NULL,
+    active TINYINT(1) NOT NULL DEFAULT 1, -- Retired prompts stay so old notes keep their reference
+    created_at bigint unsigned DEFAULT (unix_timestamp()),
+    INDEX idx_journal_prompts_locale (locale, active, category)
+);
+
+
+-- Note outlines users saved for reuse
+CREATE TABLE note_templates (
+    template_id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    name VARCHAR(50) NOT NULL,
+    content VARCHAR(1000) NOT NULL, -- Same limit as notes.content
+    prompt_id INT NULL,
+    created_at bigint unsigned NOT NULL,
+    updated_at bigint unsigned NOT NULL,
+    INDEX idx_note_templates_user (user_id)
+);
+
+
+-- Initial prompts
+INSERT INTO journal_prompts (category, locale, text) VALUES
+    ('gratitude', 'zh-CN', '今天有哪件小事还算顺利？'),
+    ('gratitude', 'zh-CN', '今天有谁或什么让你感到一点点温暖？'),
+    ('coping', 'zh-CN', '今天最难的时刻是什么？你是怎么撑过去的？'),
+    ('coping', 'zh-CN', '如果明天再遇到同样的困难，你想试试什么不同的做法？'),
+    ('reflection', 'zh-CN', '用三个词形容今天。'),
+    ('reflection', 'zh-CN', '今天 TA 的状态和昨天相比有什么变化？'),
+    ('self_care', 'zh-CN', '今天你为自己做了什么，哪怕只是喝了一杯水？'),
+    ('self_care', 'zh-CN', '你现在最需要的休息是什么样的？'),
+    ('connection', 'zh-CN', '今天你和谁说了话？那次交谈让你感觉如何？'),
+    ('connection', 'zh-CN', '有什么想对 TA 说、却还没说出口的话？'),
+    ('gratitude', 'en', 'What small thing went okay today?'),
+    ('gratitude', 'en', 'Who or what brought you a little warmth today?'),
+    ('coping', 'en', 'What was the hardest moment today, and how did you get through it?'),
+    ('coping', 'en', 'If the same difficulty comes up tomorrow, what would you like to try differently?'),
+    ('reflection', 'en', 'Describe today in three words.'),
+    ('reflection', 'en', 'How did your loved one seem today compared with yesterday?'),
+    ('self_care', 'en', 'What did you do for yourself today, even if it was just a glass of water?'),
+    ('self_care', 'en', 'What kind of rest do you need most right now?'),
+    ('connection', 'en', 'Who did you talk to today, and how did it feel?'),
+    ('connection', 'en', 'Is there something you want to say to your loved one but have not yet?');
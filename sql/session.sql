@@ -0,0 +1,17 @@
+-- Sessions: one row per issued login token
+CREATE TABLE `user_sessions` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `user_id` bigint(20) NOT NULL COMMENT 'User the token was issued to',
+  `token_id` varchar(64) NOT NULL COMMENT 'JWT jti claim',
+  `device_name` varchar(100) NOT NULL DEFAULT '' COMMENT 'Device name reported by the client',
+  `platform` varchar(50) NOT NULL DEFAULT '' COMMENT 'ios, android, web, ...',
+  `ip` varchar(64) NOT NULL DEFAULT '' COMMENT 'Last seen client IP',
+  `user_agent` varchar(255) NOT NULL DEFAULT '' COMMENT 'User-Agent at login',
+  `created_at` bigint(20) NOT NULL COMMENT 'Creation timestamp in milliseconds since epoch',
+  `last_seen_at` bigint(20) NOT NULL COMMENT 'Last request timestamp in milliseconds since epoch',
+  `expires_at` bigint(20) NOT NULL DEFAULT 0 COMMENT 'Token expiry in milliseconds since epoch',
+  `revoked_at` bigint(20) NOT NULL DEFAULT 0 COMMENT 'Sign-out timestamp in milliseconds, 0 while active',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_token_id` (`token_id`),
+  KEY `idx_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
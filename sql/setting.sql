@@ -2,14 +2,19 @@ CREATE TABLE user_profiles (
     id BIGINT PRIMARY KEY AUTO_INCREMENT,
     patient_name VARCHAR(100) NOT NULL COMMENT '患者昵称 (Patient nickname)',
     relationship_to_patient VARCHAR(50) NOT NULL COMMENT '与患者关系 (Relationship to patient)',
-    illness_cause VARCHAR(200) COMMENT '患病主要原因 (Main cause of illness)',
+    illness_cause VARCHAR(1024) COMMENT '患病主要原因 (Main cause of illness), encrypted; the API limits it to 200 characters',
     chat_background VARCHAR(255) COMMENT '聊天背景 (Chat background image path)',
     user_avatar VARCHAR(255) COMMENT '用户头像 (User avatar image path)',
     user_nickname VARCHAR(100) COMMENT '用户昵称 (User nickname)',
     mobile_number VARCHAR(20) NOT NULL COMMENT '绑定手机 (Bound mobile number)',
     password VARCHAR(255) NOT NULL COMMENT 'Hashed password',
+    role VARCHAR(20) NOT NULL DEFAULT 'user' COMMENT 'user, moderator or admin',
+    timezone VARCHAR(64) NOT NULL DEFAULT 'Asia/Shanghai' COMMENT 'IANA timezone used to decide which day "today" is',
+    community_banned_until BIGINT NOT NULL DEFAULT 0 COMMENT 'Posting and commenting are blocked until then, milliseconds since epoch',
     created_at BIGINT NOT NULL COMMENT 'Creation timestamp in milliseconds since epoch',
-    updated_at BIGINT NOT NULL COMMENT 'Last update timestamp in milliseconds since epoch'
+    updated_at BIGINT NOT NULL COMMENT 'Last update timestamp in milliseconds since epoch',
+    key_version INT NOT NULL DEFAULT 0 COMMENT 'Master key illness_cause was encrypted with, 0 for plaintext',
+    INDEX idx_user_profiles_key_version (key_version)
 );
 
 -- Index for faster queries by mobile number
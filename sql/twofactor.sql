@@ -0,0 +1,24 @@
+-- TOTP two-factor authentication settings, one row per enrolled user
+CREATE TABLE `user_two_factor` (
+  `user_id` bigint(20) NOT NULL COMMENT 'User the secret belongs to',
+  `secret` varchar(64) NOT NULL COMMENT 'Base32 TOTP secret',
+  `enabled` tinyint(1) NOT NULL DEFAULT 0 COMMENT '1 once enrollment is verified',
+  `enabled_at` bigint(20) NOT NULL DEFAULT 0 COMMENT 'Enrollment timestamp in milliseconds since epoch',
+  `failed_attempts` int(11) NOT NULL DEFAULT 0 COMMENT 'Wrong codes since the last accepted one',
+  `locked_until` bigint(20) NOT NULL DEFAULT 0 COMMENT 'Codes are refused until then, milliseconds since epoch',
+  `last_used_step` bigint(20) NOT NULL DEFAULT 0 COMMENT 'TOTP time step of the last accepted code, earlier steps are refused',
+  `created_at` bigint(20) NOT NULL COMMENT 'Creation timestamp in milliseconds since epoch',
+  `updated_at` bigint(20) NOT NULL COMMENT 'Last update timestamp in milliseconds since epoch',
+  PRIMARY KEY (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Single-use recovery codes, stored as bcrypt hashes
+CREATE TABLE `user_recovery_codes` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `user_id` bigint(20) NOT NULL COMMENT 'User the code belongs to',
+  `code_hash` varchar(255) NOT NULL COMMENT 'bcrypt hash of the recovery code',
+  `used_at` bigint(20) NOT NULL DEFAULT 0 COMMENT 'Use timestamp in milliseconds, 0 while unused',
+  `created_at` bigint(20) NOT NULL COMMENT 'Creation timestamp in milliseconds since epoch',
+  PRIMARY KEY (`id`),
+  KEY `idx_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;